@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/cache"
+	"github.com/mises-id/sns-apigateway/lib/dataloader"
+	gql "github.com/mises-id/sns-apigateway/lib/graphql"
+)
+
+// Schema is the schema of the graphql endpoint
+//
+//go:embed schema.graphql
+var Schema string
+
+var (
+	handlerMutex sync.Mutex
+	handler      *gql.Handler
+	// handlerLimits are the limits handler was built with
+	handlerLimits limits
+	// persistedQueries is shared by the handlers, so a reload keeps the persisted queries
+	persistedQueries     cache.Store
+	persistedQueriesOnce sync.Once
+)
+
+// limits are the settings of the graphql handler, read from the config of every request
+type limits struct {
+	maxDepth, maxComplexity, listSize, maxBatch int
+	persistedQueryTTL                           time.Duration
+	introspection                               bool
+}
+
+func limitsOf(cfg *env.Env) limits {
+	return limits{
+		maxDepth:          cfg.GraphQLMaxDepth,
+		maxComplexity:     cfg.GraphQLMaxComplexity,
+		listSize:          cfg.GraphQLListSize,
+		maxBatch:          cfg.GraphQLMaxBatch,
+		persistedQueryTTL: cfg.GraphQLPersistedQueryTTL,
+		introspection:     cfg.GraphQLIntrospection,
+	}
+}
+
+// Serve answers the graphql operations with the limits of the current config, the handler is
+// built again when they are reloaded
+func Serve(c echo.Context) error {
+	h, err := currentHandler(env.Current())
+	if err != nil {
+		return gql.WriteError(c, err)
+	}
+	return h.Serve(c)
+}
+
+func currentHandler(cfg *env.Env) (*gql.Handler, error) {
+	handlerMutex.Lock()
+	defer handlerMutex.Unlock()
+	if handler != nil && handlerLimits == limitsOf(cfg) {
+		return handler, nil
+	}
+	h, err := NewHandler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	handler, handlerLimits = h, limitsOf(cfg)
+	return handler, nil
+}
+
+// NewHandler parses the schema with the limits of cfg
+func NewHandler(cfg *env.Env) (*gql.Handler, error) {
+	persistedQueriesOnce.Do(func() {
+		persistedQueries = rest.NewCacheStore("graphql:")
+	})
+	return gql.New(Schema, &resolver{}, gql.Config{
+		MaxDepth:             cfg.GraphQLMaxDepth,
+		MaxComplexity:        cfg.GraphQLMaxComplexity,
+		ListSize:             cfg.GraphQLListSize,
+		MaxBatch:             cfg.GraphQLMaxBatch,
+		PersistedQueries:     persistedQueries,
+		PersistedQueryTTL:    cfg.GraphQLPersistedQueryTTL,
+		DisableIntrospection: !cfg.GraphQLIntrospection,
+		Context:              withLoaders,
+	})
+}
+
+// loaders batch the loads of the resolvers of a request
+type loaders struct {
+	users *dataloader.Loader[uint64, *v1.UserSummaryResp]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, c echo.Context) context.Context {
+	users := dataloader.New(ctx, func(ctx context.Context, uids []uint64) (map[uint64]*v1.UserSummaryResp, error) {
+		return v1.UserSummaries(c, uids)
+	}, dataloader.Config{MaxBatch: 100})
+	return context.WithValue(ctx, loadersKey{}, &loaders{users: users})
+}
+
+func loadersOf(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
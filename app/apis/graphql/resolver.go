@@ -0,0 +1,299 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/dataloader"
+	gql "github.com/mises-id/sns-apigateway/lib/graphql"
+	"github.com/mises-id/sns-apigateway/lib/validation"
+)
+
+// resolver resolves the fields of Query with the helpers of the rest handlers, the types of their
+// responses are wrapped to convert the fields which are not graphql scalars
+type resolver struct{}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	c := gql.EchoContext(ctx)
+	if v1.GetCurrentUID(c) == 0 {
+		return nil, nil
+	}
+	profile, err := v1.Profile(c)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	return &userResolver{*profile}, nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ UID gql.Uint64 }) (*userSummaryResolver, error) {
+	summary, err := loadersOf(ctx).users.Load(ctx, uint64(args.UID))
+	if codes.ErrNotFound.Equal(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newUserSummaryResolver(summary), nil
+}
+
+func (r *resolver) Users(ctx context.Context, args struct{ UIDs []gql.Uint64 }) ([]*userSummaryResolver, error) {
+	if len(args.UIDs) > validation.MaxPageSize {
+		return nil, codes.InvalidFields(codes.FieldError{
+			Field:   "uids",
+			Rule:    "max",
+			Message: fmt.Sprintf("uids must be at most %d items", validation.MaxPageSize),
+		})
+	}
+	uids := make([]uint64, len(args.UIDs))
+	for i, uid := range args.UIDs {
+		uids[i] = uint64(uid)
+	}
+	summaries, errs := loadersOf(ctx).users.LoadMany(ctx, uids)
+	users := []*userSummaryResolver{}
+	for i, summary := range summaries {
+		if codes.ErrNotFound.Equal(errs[i]) {
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		users = append(users, newUserSummaryResolver(summary))
+	}
+	return users, nil
+}
+
+func (r *resolver) Timeline(ctx context.Context, args struct {
+	LastID *string
+	Limit  int32
+}) (*statusPageResolver, error) {
+	c := gql.EchoContext(ctx)
+	if v1.GetCurrentUID(c) == 0 {
+		return nil, codes.ErrUnauthorized
+	}
+	params := &v1.ListUserStatusParams{}
+	params.Limit = int64(args.Limit)
+	if args.LastID != nil {
+		params.NextID = *args.LastID
+	}
+	if err := c.Validate(params); err != nil {
+		return nil, err
+	}
+	statuses, paginator, err := v1.TimelinePage(c, params)
+	if err != nil {
+		return nil, err
+	}
+	page := &statusPageResolver{pagination: &pageQuickResolver{}}
+	if paginator != nil {
+		page.pagination = &pageQuickResolver{Limit: int32(paginator.Limit), Total: int32(paginator.Total), LastID: paginator.NextId}
+	}
+	// the statuses embed their users, so the users of the request do not find them again
+	users := loadersOf(ctx).users
+	for _, status := range statuses {
+		for s := status; s != nil; s = s.ParentStatus {
+			primeUser(users, s)
+			primeUser(users, s.OriginStatus)
+		}
+		page.statuses = append(page.statuses, newStatusResolver(status))
+	}
+	return page, nil
+}
+
+func (r *resolver) News(ctx context.Context, args struct{ BeforeNewsID *string }) (*newsPageResolver, error) {
+	resp, err := v1.NewsPage(gql.EchoContext(ctx), &v1.ListNewsParams{BeforeNewsId: args.BeforeNewsID})
+	if err != nil {
+		return nil, err
+	}
+	page := &newsPageResolver{HaveMore: resp.HaveMore}
+	for _, news := range resp.NewsArray {
+		page.newsArray = append(page.newsArray, &newsResolver{*news})
+	}
+	return page, nil
+}
+
+func (r *resolver) Strategies(ctx context.Context, args struct{ BeforeStrategyID *string }) (*strategyPageResolver, error) {
+	resp, err := v1.StrategyPage(gql.EchoContext(ctx), &v1.ListStrategiesParams{BeforeStrategyId: args.BeforeStrategyID})
+	if err != nil {
+		return nil, err
+	}
+	page := &strategyPageResolver{HaveMore: resp.HaveMore}
+	for _, strategy := range resp.Strategies {
+		page.strategies = append(page.strategies, &strategyResolver{*strategy})
+	}
+	return page, nil
+}
+
+func (r *resolver) WebsiteCategories(ctx context.Context) ([]*websiteCategoryResolver, error) {
+	categories, err := v1.WebsiteCategories(gql.EchoContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return newWebsiteCategoryResolvers(categories), nil
+}
+
+func (r *resolver) MiningBonus(ctx context.Context) (*v1.BonusResponse, error) {
+	c := gql.EchoContext(ctx)
+	if v1.GetCurrentUID(c) == 0 {
+		return nil, codes.ErrUnauthorized
+	}
+	bonus, err := v1.Bonus(c)
+	if err != nil {
+		return nil, err
+	}
+	if bonus == nil {
+		bonus = &v1.BonusResponse{}
+	}
+	return bonus, nil
+}
+
+func primeUser(users *dataloader.Loader[uint64, *v1.UserSummaryResp], status *v1.StatusResp) {
+	if status != nil && status.User != nil {
+		users.Prime(status.User.UID, status.User)
+	}
+}
+
+type userResolver struct {
+	v1.UserFullResp
+}
+
+func (u *userResolver) UID() gql.Uint64        { return gql.Uint64(u.UserFullResp.UID) }
+func (u *userResolver) FollowingsCount() int32 { return int32(u.FollowingCount) }
+func (u *userResolver) FansCount() int32       { return int32(u.UserFullResp.FansCount) }
+func (u *userResolver) LikedCount() int32      { return int32(u.UserFullResp.LikedCount) }
+func (u *userResolver) NewFansCount() int32    { return int32(u.UserFullResp.NewFansCount) }
+
+type userSummaryResolver struct {
+	v1.UserSummaryResp
+}
+
+func newUserSummaryResolver(summary *v1.UserSummaryResp) *userSummaryResolver {
+	if summary == nil {
+		return nil
+	}
+	return &userSummaryResolver{*summary}
+}
+
+func (u *userSummaryResolver) UID() gql.Uint64 { return gql.Uint64(u.UserSummaryResp.UID) }
+
+type statusResolver struct {
+	v1.StatusResp
+}
+
+func newStatusResolver(status *v1.StatusResp) *statusResolver {
+	if status == nil {
+		return nil
+	}
+	return &statusResolver{*status}
+}
+
+// User is found by the loader of the request, which the statuses of the request primed
+func (s *statusResolver) User(ctx context.Context) (*userSummaryResolver, error) {
+	if s.StatusResp.User == nil {
+		return nil, nil
+	}
+	summary, err := loadersOf(ctx).users.Load(ctx, s.StatusResp.User.UID)
+	if codes.ErrNotFound.Equal(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newUserSummaryResolver(summary), nil
+}
+
+func (s *statusResolver) ParentStatus() *statusResolver {
+	return newStatusResolver(s.StatusResp.ParentStatus)
+}
+func (s *statusResolver) OriginStatus() *statusResolver {
+	return newStatusResolver(s.StatusResp.OriginStatus)
+}
+func (s *statusResolver) CommentsCount() int32 { return int32(s.StatusResp.CommentsCount) }
+func (s *statusResolver) LikesCount() int32    { return int32(s.StatusResp.LikesCount) }
+func (s *statusResolver) ForwardsCount() int32 { return int32(s.StatusResp.ForwardsCount) }
+func (s *statusResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: s.StatusResp.CreatedAt}
+}
+func (s *statusResolver) ThumbImages() []string { return nonNil(s.StatusResp.ThumbImages) }
+func (s *statusResolver) Images() []string      { return nonNil(s.StatusResp.Images) }
+func (s *statusResolver) HideTime() *graphqlgo.Time {
+	if s.StatusResp.HideTime.IsZero() {
+		return nil
+	}
+	return &graphqlgo.Time{Time: s.StatusResp.HideTime}
+}
+
+type pageQuickResolver struct {
+	Limit  int32
+	Total  int32
+	LastID string
+}
+
+type statusPageResolver struct {
+	statuses   []*statusResolver
+	pagination *pageQuickResolver
+}
+
+func (p *statusPageResolver) Statuses() []*statusResolver    { return nonNil(p.statuses) }
+func (p *statusPageResolver) Pagination() *pageQuickResolver { return p.pagination }
+
+type newsResolver struct {
+	v1.News
+}
+
+func (n *newsResolver) PublishedAt() graphqlgo.Time { return graphqlgo.Time{Time: n.News.PublishedAt} }
+func (n *newsResolver) Categories() []string        { return nonNil(n.News.Categories) }
+func (n *newsResolver) Medias() []v1.Media          { return nonNil(n.News.Medias) }
+func (n *newsResolver) Currencies() []v1.Currency   { return nonNil(n.News.Currencies) }
+
+type newsPageResolver struct {
+	newsArray []*newsResolver
+	HaveMore  bool
+}
+
+func (p *newsPageResolver) NewsArray() []*newsResolver { return nonNil(p.newsArray) }
+
+type strategyResolver struct {
+	v1.Strategy
+}
+
+func (s *strategyResolver) PublishedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: s.Strategy.PublishedAt}
+}
+func (s *strategyResolver) UpdatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: s.Strategy.UpdatedAt}
+}
+
+type strategyPageResolver struct {
+	strategies []*strategyResolver
+	HaveMore   bool
+}
+
+func (p *strategyPageResolver) Strategies() []*strategyResolver { return nonNil(p.strategies) }
+
+type websiteCategoryResolver struct {
+	v1.WebsiteCategoryResp
+}
+
+func newWebsiteCategoryResolvers(categories []*v1.WebsiteCategoryResp) []*websiteCategoryResolver {
+	resolvers := []*websiteCategoryResolver{}
+	for _, category := range categories {
+		if category != nil {
+			resolvers = append(resolvers, &websiteCategoryResolver{*category})
+		}
+	}
+	return resolvers
+}
+
+func (w *websiteCategoryResolver) ChildrenCategory() []*websiteCategoryResolver {
+	return newWebsiteCategoryResolvers(w.WebsiteCategoryResp.ChildrenCategory)
+}
+
+// nonNil answers an empty list rather than null for the non null lists
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
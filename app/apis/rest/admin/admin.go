@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/buildinfo"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/slowlog"
+	"github.com/sirupsen/logrus"
+)
+
+var started = time.Now()
+
+type RateLimitResetParams struct {
+	Key string `json:"key" query:"key"`
+}
+
+type LogLevelParams struct {
+	Level string `json:"level" query:"level"`
+}
+
+// Stats returns the build, uptime, goroutine and memory stats of the process
+func Stats(c echo.Context) error {
+	memStats := runtime.MemStats{}
+	runtime.ReadMemStats(&memStats)
+	return rest.BuildSuccessResp(c, echo.Map{
+		"build":      buildinfo.Get(),
+		"uptime":     time.Since(started).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+		"log_level":  logrus.GetLevel().String(),
+		"memory": echo.Map{
+			"alloc":        memStats.Alloc,
+			"sys":          memStats.Sys,
+			"heap_inuse":   memStats.HeapInuse,
+			"heap_objects": memStats.HeapObjects,
+			"num_gc":       memStats.NumGC,
+			"pause_total":  time.Duration(memStats.PauseTotalNs).String(),
+		},
+	})
+}
+
+// Goroutines dumps the stack of every goroutine as text
+func Goroutines(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return pprof.Lookup("goroutine").WriteTo(c.Response(), 2)
+}
+
+// Config returns the effective config with the secrets redacted
+func Config(c echo.Context) error {
+	return rest.BuildSuccessResp(c, env.Current().Redact())
+}
+
+// Pools returns the state of the service pools, the gateway has no circuit breakers to report
+func Pools(c echo.Context) error {
+	return rest.BuildSuccessResp(c, rest.SvrPoolStates())
+}
+
+// SlowRequests returns the latency percentiles of the routes and the last slow requests, newest first
+func SlowRequests(c echo.Context) error {
+	return rest.BuildSuccessResp(c, echo.Map{
+		"routes": slowlog.Default.Stats(),
+		"events": slowlog.Default.Events(),
+	})
+}
+
+// FlushStore empties the in memory store
+func FlushStore(c echo.Context) error {
+	count := rest.FlushInMemoryStore()
+	logging.FromContext(c).Warnf("admin: in memory store flushed, %d entries", count)
+	return rest.BuildSuccessResp(c, echo.Map{"flushed": count})
+}
+
+// FlushCache drops every cached response
+func FlushCache(c echo.Context) error {
+	if err := rest.FlushResponseCache(c); err != nil {
+		return err
+	}
+	logging.FromContext(c).Warn("admin: response cache flushed")
+	return rest.BuildSuccessResp(c, nil)
+}
+
+// ResetRateLimit gives the client identified by key, an ip or a wallet or eth address, full rate limit buckets
+func ResetRateLimit(c echo.Context) error {
+	params := &RateLimitResetParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	if params.Key == "" {
+		return codes.ErrInvalidArgument.New("key is required")
+	}
+	count := mw.ResetRateLimits(params.Key)
+	logging.FromContext(c).Warnf("admin: %d rate limit buckets of %s reset", count, params.Key)
+	return rest.BuildSuccessResp(c, echo.Map{"reset": count})
+}
+
+// SetLogLevel changes the log level until the next restart or config reload
+func SetLogLevel(c echo.Context) error {
+	params := &LogLevelParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	level, err := logrus.ParseLevel(params.Level)
+	if err != nil {
+		return codes.ErrInvalidArgument.Newf("invalid log level %s", params.Level)
+	}
+	logrus.SetLevel(level)
+	logging.FromContext(c).Warnf("admin: log level set to %s", level)
+	return rest.BuildSuccessResp(c, echo.Map{"log_level": level.String()})
+}
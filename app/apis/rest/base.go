@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	grpcpool "github.com/go-kit/kit/util/grpcpool"
@@ -18,6 +20,11 @@ import (
 	swapsvcgrpcclient "github.com/mises-id/mises-swapsvc/svc/client/grpc"
 	websitesvcpb "github.com/mises-id/mises-websitesvc/proto"
 	websitesvcgrpcclient "github.com/mises-id/mises-websitesvc/svc/client/grpc"
+	"github.com/mises-id/sns-apigateway/lib/fields"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
+	"github.com/mises-id/sns-apigateway/lib/slowlog"
+	"github.com/mises-id/sns-apigateway/lib/tracing"
+	"github.com/mises-id/sns-apigateway/lib/wire"
 	pb "github.com/mises-id/sns-socialsvc/proto"
 	grpcclient "github.com/mises-id/sns-socialsvc/svc/client/grpc"
 	storagepb "github.com/mises-id/sns-storagesvc/proto"
@@ -34,6 +41,7 @@ var (
 	mingsvcSvcPool  *grpcpool.Pool
 	newsFlowSvcPool *grpcpool.Pool
 	store           sync.Map
+	ready           atomic.Bool
 )
 
 type PoolCfg struct {
@@ -48,20 +56,20 @@ type PoolCfg struct {
 	IdleTimeout    time.Duration
 }
 type PageQuickParams struct {
-	Limit  int64  `json:"limit" query:"limit"`
+	Limit  int64  `json:"limit" query:"limit" validate:"page_size"`
 	Total  int64  `json:"total" query:"total"`
 	NextID string `json:"last_id" query:"last_id"`
 }
 type PageParams struct {
-	PageNum      int64 `json:"page_num" query:"page_num"`
-	PageSize     int64 `json:"page_size" query:"page_size"`
+	PageNum      int64 `json:"page_num" query:"page_num" validate:"gte=0"`
+	PageSize     int64 `json:"page_size" query:"page_size" validate:"page_size"`
 	TotalPage    int64 `json:"total_page"`
 	TotalRecords int64 `json:"total_records"`
 }
 
 // BuildSuccessResp return a success response with payload
 func Build403Resp(c echo.Context, data interface{}) error {
-	return c.JSON(http.StatusForbidden, echo.Map{
+	return wire.Render(c, http.StatusForbidden, echo.Map{
 		"code": http.StatusForbidden,
 		"data": data,
 	})
@@ -69,21 +77,33 @@ func Build403Resp(c echo.Context, data interface{}) error {
 
 // BuildSuccessResp return a success response with payload
 func BuildSuccessResp(c echo.Context, data interface{}) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 	})
 }
 
 func BuildSuccessRespWithRequestID(c echo.Context, requestID string, data interface{}) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code":       0,
 		"data":       data,
 		"request_id": requestID,
 	})
 }
 func BuildSuccessRespWithWebsitePageAndRequestID(c echo.Context, requestID string, data interface{}, pagination *websitesvcpb.Page) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code":       0,
 		"data":       data,
 		"request_id": requestID,
@@ -96,7 +116,11 @@ func BuildSuccessRespWithWebsitePageAndRequestID(c echo.Context, requestID strin
 	})
 }
 func BuildSuccessRespWithSwapPage(c echo.Context, requestID string, data interface{}, pagination *swapvcpb.Page) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code":       0,
 		"data":       data,
 		"request_id": requestID,
@@ -111,7 +135,11 @@ func BuildSuccessRespWithSwapPage(c echo.Context, requestID string, data interfa
 
 // BuildSuccessResp return a success response with payload
 func BuildSuccessRespWithPagination(c echo.Context, data interface{}, pagination *pb.PageQuick) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageQuickParams{
@@ -122,7 +150,11 @@ func BuildSuccessRespWithPagination(c echo.Context, data interface{}, pagination
 	})
 }
 func BuildSuccessRespWebsiteWithPagination(c echo.Context, data interface{}, pagination *websitesvcpb.PageQuick) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageQuickParams{
@@ -133,7 +165,11 @@ func BuildSuccessRespWebsiteWithPagination(c echo.Context, data interface{}, pag
 	})
 }
 func BuildSuccessRespWithWebsitePage(c echo.Context, data interface{}, pagination *websitesvcpb.Page) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageParams{
@@ -145,7 +181,11 @@ func BuildSuccessRespWithWebsitePage(c echo.Context, data interface{}, paginatio
 	})
 }
 func BuildSuccessRespAirdropWithPagination(c echo.Context, data interface{}, pagination *airdropsvcpb.PageQuick) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageQuickParams{
@@ -156,7 +196,11 @@ func BuildSuccessRespAirdropWithPagination(c echo.Context, data interface{}, pag
 	})
 }
 func BuildSuccessRespWithAirdropPage(c echo.Context, data interface{}, pagination *airdropsvcpb.Page) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageParams{
@@ -168,7 +212,11 @@ func BuildSuccessRespWithAirdropPage(c echo.Context, data interface{}, paginatio
 	})
 }
 func BuildSuccessRespWithPage(c echo.Context, data interface{}, pagination *pb.Page) error {
-	return c.JSON(http.StatusOK, echo.Map{
+	data, err := shapeData(c, data)
+	if err != nil {
+		return err
+	}
+	return wire.Render(c, http.StatusOK, echo.Map{
 		"code": 0,
 		"data": data,
 		"pagination": PageParams{
@@ -180,103 +228,151 @@ func BuildSuccessRespWithPage(c echo.Context, data interface{}, pagination *pb.P
 	})
 }
 
+// shapeData prunes data down to the fields and expand query params of the request
+func shapeData(c echo.Context, data interface{}) (interface{}, error) {
+	selector, err := fields.FromRequest(c)
+	if err != nil {
+		return nil, err
+	}
+	return selector.Apply(data)
+}
+
 // Probe for k8s liveness
 func Probe(c echo.Context) error {
 	return BuildSuccessResp(c, nil)
 }
 
+// SetReady switches the readiness probe, it is off until the server listens and while it drains
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
+// Ready for k8s readiness, a draining server is taken out of the service before it stops accepting
+func Ready(c echo.Context) error {
+	if !ready.Load() {
+		return wire.Render(c, http.StatusServiceUnavailable, echo.Map{
+			"code": http.StatusServiceUnavailable,
+			"data": nil,
+		})
+	}
+	return BuildSuccessResp(c, nil)
+}
+
+// serviceContext carries the trace and the deadline of the request to the backends, a backend
+// call is not canceled with the request since its result may be shared with other requests
+func serviceContext(c echo.Context) context.Context {
+	ctx := context.WithoutCancel(c.Request().Context())
+	deadline, ok := c.Request().Context().Deadline()
+	if !ok {
+		return ctx
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	// the deadline releases the context, the call may outlive the request
+	_ = cancel
+	return ctx
+}
+
+// poolConn takes a connection of the backend pool, recording how long it waited for it
+func poolConn(c echo.Context, backend string, pool *grpcpool.Pool) (*grpcpool.ClientConn, error) {
+	start := time.Now()
+	conn, err := pool.Get(context.Background())
+	metrics.ObservePoolWait(backend, time.Since(start))
+	slowlog.Observe(c.Request().Context(), "pool:"+backend, start)
+	return conn, err
+}
+
 // build a service client, we are currently not using service discover
-func GrpcSocialService() (pb.SocialServer, context.Context, error) {
-	conn, err := socialSvcPool.Get(context.Background())
+func GrpcSocialService(c echo.Context) (pb.SocialServer, context.Context, error) {
+	conn, err := poolConn(c, "socialsvc", socialSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := grpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
 
-func GrpcStorageService() (storagepb.StoragesvcServer, context.Context, error) {
-	conn, err := storageSvcPool.Get(context.Background())
+func GrpcStorageService(c echo.Context) (storagepb.StoragesvcServer, context.Context, error) {
+	conn, err := poolConn(c, "storagesvc", storageSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := storagesvcgrpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
-func GrpcWebsiteService() (websitesvcpb.WebsitesvcServer, context.Context, error) {
-	conn, err := websiteSvcPool.Get(context.Background())
+func GrpcWebsiteService(c echo.Context) (websitesvcpb.WebsitesvcServer, context.Context, error) {
+	conn, err := poolConn(c, "websitesvc", websiteSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := websitesvcgrpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
-func GrpcSwapService() (swapvcpb.SwapsvcServer, context.Context, error) {
-	conn, err := swapSvcPool.Get(context.Background())
+func GrpcSwapService(c echo.Context) (swapvcpb.SwapsvcServer, context.Context, error) {
+	conn, err := poolConn(c, "swapsvc", swapSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := swapsvcgrpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
 
-func GrpcMiningService() (miningsvcpb.MiningsvcServer, context.Context, error) {
-	conn, err := mingsvcSvcPool.Get(context.Background())
+func GrpcMiningService(c echo.Context) (miningsvcpb.MiningsvcServer, context.Context, error) {
+	conn, err := poolConn(c, "miningsvc", mingsvcSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := miningsvcgrpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
 
-func GrpcAirdropService() (airdropsvcpb.AirdropsvcServer, context.Context, error) {
-	conn, err := airdropSvcPool.Get(context.Background())
+func GrpcAirdropService(c echo.Context) (airdropsvcpb.AirdropsvcServer, context.Context, error) {
+	conn, err := poolConn(c, "airdropsvc", airdropSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	svcclient, err := airdropsvcgrpcclient.New(conn.ClientConn)
 	return svcclient, ctx, err
 }
 
-func GrpcNewsFlowService() (newsflowpb.ApiserverClient, context.Context, error) {
-	conn, err := newsFlowSvcPool.Get(context.Background())
+func GrpcNewsFlowService(c echo.Context) (newsflowpb.ApiserverClient, context.Context, error) {
+	conn, err := poolConn(c, "news-flow", newsFlowSvcPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create news-flow grpcclient: %q", err)
 	}
 	defer conn.Close()
 
 	// Create a context with the header key and value
-	ctx := context.WithValue(context.Background(), "key", "value")
+	ctx := context.WithValue(serviceContext(c), "key", "value")
 
 	client := newsflowpb.NewApiserverClient(conn.ClientConn)
 	return client, ctx, nil
@@ -286,38 +382,85 @@ func InMemoryStore() *sync.Map {
 	return &store
 }
 
+// PoolState is the usage of a service pool
+type PoolState struct {
+	Name      string `json:"name"`
+	Capacity  int    `json:"capacity"`
+	Available int    `json:"available"`
+	Closed    bool   `json:"closed"`
+}
+
+func svrPools() map[string]*grpcpool.Pool {
+	return map[string]*grpcpool.Pool{
+		"socialsvc":  socialSvcPool,
+		"storagesvc": storageSvcPool,
+		"websitesvc": websiteSvcPool,
+		"airdropsvc": airdropSvcPool,
+		"swapsvc":    swapSvcPool,
+		"miningsvc":  mingsvcSvcPool,
+		"news-flow":  newsFlowSvcPool,
+	}
+}
+
+// SvrPoolStates returns the state of every service pool, sorted by name
+func SvrPoolStates() []PoolState {
+	states := []PoolState{}
+	for name, pool := range svrPools() {
+		states = append(states, PoolState{Name: name, Capacity: pool.Capacity(), Available: pool.Available(), Closed: pool.IsClosed()})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// CloseSvrPool closes the connections of every service pool
+func CloseSvrPool() {
+	for _, pool := range svrPools() {
+		if pool != nil {
+			pool.Close()
+		}
+	}
+}
+
+// FlushInMemoryStore empties the store of InMemoryStore, returning the number of entries dropped
+func FlushInMemoryStore() int {
+	count := 0
+	store.Range(func(key, _ interface{}) bool {
+		store.Delete(key)
+		count++
+		return true
+	})
+	return count
+}
+
+// dialBackend connects to the backend at uri, tracing and measuring its calls
+func dialBackend(backend, uri string) grpcpool.FactoryWithContext {
+	return func(ctx context.Context) (*grpc.ClientConn, error) {
+		return grpc.DialContext(ctx, uri, grpc.WithInsecure(), grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(backend),
+			metrics.UnaryClientInterceptor(backend),
+			slowlog.UnaryClientInterceptor(backend),
+		))
+	}
+}
+
 func ResetSvrPool(cfg PoolCfg) {
 	var err error
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	socialSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.SocialSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	socialSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("socialsvc", cfg.SocialSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	storageSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.StorageSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	storageSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("storagesvc", cfg.StorageSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	websiteSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.WebsiteSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	websiteSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("websitesvc", cfg.WebsiteSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	airdropSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.AirdropSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	airdropSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("airdropsvc", cfg.AirdropSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	swapSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.SwapSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	swapSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("swapsvc", cfg.SwapSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	mingsvcSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.MiningSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	mingsvcSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("miningsvc", cfg.MiningSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
-	newsFlowSvcPool, err = grpcpool.NewWithContext(ctx, func(ctx context.Context) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, cfg.NewsFlowSvcURI, grpc.WithInsecure())
-	}, 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
+	newsFlowSvcPool, err = grpcpool.NewWithContext(ctx, dialBackend("news-flow", cfg.NewsFlowSvcURI), 0, cfg.Capacity, cfg.IdleTimeout*time.Second)
 
 	if err != nil {
 		panic(err)
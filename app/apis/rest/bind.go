@@ -0,0 +1,6 @@
+package rest
+
+import "github.com/mises-id/sns-apigateway/lib/fields"
+
+// SharedQueryParams are read by the response helpers rather than bound to the handler params
+var SharedQueryParams = []string{fields.FieldsParam, fields.ExpandParam, ExportFormatParam, ExportCursorParam}
@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/cache"
+	"github.com/mises-id/sns-apigateway/lib/cache/rediscache"
+	"github.com/mises-id/sns-apigateway/lib/fields"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	responseCacheOnce sync.Once
+	responseCache     *cache.Loader
+	// query params that do not change the payload returned by the backend
+	cacheIgnoredParams = map[string]bool{
+		"request_id":       true,
+		fields.FieldsParam: true,
+		fields.ExpandParam: true,
+	}
+)
+
+// CachePolicy describes how the backend payload of a route is cached
+type CachePolicy struct {
+	TTL time.Duration
+	// Personalized payloads depend on the current user, so the requests with credentials bypass the cache
+	Personalized bool
+}
+
+// Cached returns the payload of load from the response cache, concurrent misses
+// of the same request are collapsed into a single load.
+// A "Cache-Control: no-cache" request header skips the cached value.
+func Cached[T any](c echo.Context, policy CachePolicy, load func() (T, error)) (T, error) {
+	return cached(c, policy, func(scope string) string { return cacheKey(c, scope) }, load)
+}
+
+// CachedRoute caches the payload of load under the key of a GET of the route path with query,
+// so the callers besides its rest handler, like the graphql resolvers, share its cached payloads
+func CachedRoute[T any](c echo.Context, policy CachePolicy, path string, query url.Values, load func() (T, error)) (T, error) {
+	return cached(c, policy, func(scope string) string {
+		return routeCacheKey(http.MethodGet, path, nil, nil, query, scope)
+	}, load)
+}
+
+func cached[T any](c echo.Context, policy CachePolicy, key func(scope string) string, load func() (T, error)) (T, error) {
+	var result T
+	scope, ok := cache.Scope(c.Request(), policy.Personalized)
+	if !ok {
+		return load()
+	}
+	refresh := strings.Contains(c.Request().Header.Get("Cache-Control"), "no-cache")
+	value, err := responseCacheLoader().Load(c.Request().Context(), key(scope), policy.TTL, refresh, func() ([]byte, error) {
+		data, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(data)
+	})
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(value, &result)
+	return result, err
+}
+
+// FlushResponseCache drops every cached response
+func FlushResponseCache(c echo.Context) error {
+	return responseCacheLoader().Store().Flush(c.Request().Context())
+}
+
+// cacheKey is built from the route, its params, the normalized query and the auth scope
+func cacheKey(c echo.Context, scope string) string {
+	return routeCacheKey(c.Request().Method, c.Path(), c.ParamNames(), c.ParamValues(), c.QueryParams(), scope)
+}
+
+func routeCacheKey(method, path string, paramNames, paramValues []string, params url.Values, scope string) string {
+	builder := strings.Builder{}
+	builder.WriteString(method)
+	builder.WriteString(" ")
+	builder.WriteString(path)
+	for i, name := range paramNames {
+		builder.WriteString("|" + name + "=" + paramValues[i])
+	}
+	query := url.Values{}
+	for key, values := range params {
+		if cacheIgnoredParams[key] {
+			continue
+		}
+		sorted := append([]string{}, values...)
+		sort.Strings(sorted)
+		query[key] = sorted
+	}
+	// url.Values.Encode sorts by key
+	builder.WriteString("?" + query.Encode())
+	builder.WriteString("#" + scope)
+	return builder.String()
+}
+
+// NewCacheStore is a store of the configured cache provider whose keys start with prefix,
+// in memory when redis is unavailable
+func NewCacheStore(prefix string) cache.Store {
+	if env.Envs.CacheProvider == "redis" {
+		store, err := rediscache.New(env.Envs.RedisURI, "sns-apigateway:"+prefix)
+		if err == nil {
+			return store
+		}
+		logrus.Errorf("redis cache store %s unavailable, fallback to memory: %v", prefix, err)
+	}
+	return cache.NewMemoryStore(env.Envs.CacheSize)
+}
+
+// responseCacheLoader builds the response cache on its first use, once the config file and
+// the flags are applied
+func responseCacheLoader() *cache.Loader {
+	responseCacheOnce.Do(func() {
+		responseCache = cache.NewLoader(NewCacheStore("cache:"))
+	})
+	return responseCache
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/export"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+)
+
+const (
+	ExportFormatParam = "format"
+	ExportCursorParam = "cursor"
+	// ExportPageSize is the page size used to walk the backend
+	ExportPageSize = 50
+	// exportMaxPages bounds a single export request, the export is marked truncated and
+	// clients resume from the last cursor
+	exportMaxPages = 1000
+)
+
+// ExportPageFunc fetches the page that starts at cursor and returns the cursor of the
+// next page, an empty next cursor ends the export
+type ExportPageFunc[T any] func(ctx context.Context, cursor string) (items []T, next string, err error)
+
+// Export streams every page returned by fetch as NDJSON or CSV, starting from the cursor query param.
+// Each page is flushed to the client before the next one is fetched, so a slow client holds
+// the backend paging back, and a disconnected client cancels the export.
+func Export[T any](c echo.Context, name string, fetch ExportPageFunc[T]) error {
+	ctx := c.Request().Context()
+	format := exportFormat(c)
+	cursor := c.QueryParam(ExportCursorParam)
+
+	// the first page is fetched before the headers are sent, so its errors get a regular error response
+	items, next, err := fetch(ctx, cursor)
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	writer := export.NewWriter(format, res)
+	res.Header().Set(echo.HeaderContentType, writer.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, "attachment; filename=\""+name+"."+format+"\"")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(http.StatusOK)
+
+	for page := 1; ; page++ {
+		for _, item := range items {
+			if err = writer.WriteItem(item); err != nil {
+				return nil
+			}
+		}
+		if next != "" {
+			if err = writer.WriteCursor(next); err != nil {
+				return nil
+			}
+		}
+		if err = writer.Flush(); err != nil {
+			return nil
+		}
+		res.Flush()
+		if next == "" || next == cursor {
+			return nil
+		}
+		if page >= exportMaxPages {
+			logging.FromContext(c).Warnf("export %s truncated at cursor %s", name, next)
+			if writer.WriteTruncated() == nil && writer.Flush() == nil {
+				res.Flush()
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			logging.FromContext(c).Debugf("export %s canceled at cursor %s", name, next)
+			return nil
+		default:
+		}
+		cursor = next
+		if items, next, err = fetch(ctx, cursor); err != nil {
+			// the status line is already sent, report the error in the stream instead
+			logging.FromContext(c).Errorf("export %s failed at cursor %s: %v", name, cursor, err)
+			code, ok := err.(codes.Code)
+			if !ok {
+				code = codes.ErrInternal
+			}
+			writer.WriteError(code)
+			writer.Flush()
+			res.Flush()
+			return nil
+		}
+	}
+}
+
+func exportFormat(c echo.Context) string {
+	if format := strings.ToLower(c.QueryParam(ExportFormatParam)); format == export.FormatCSV || format == export.FormatNDJSON {
+		return format
+	}
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), export.MIMETextCSV) {
+		return export.FormatCSV
+	}
+	return export.FormatNDJSON
+}
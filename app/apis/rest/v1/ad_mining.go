@@ -4,8 +4,8 @@ import (
 	"github.com/labstack/echo/v4"
 	miningsvc "github.com/mises-id/mises-miningsvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
-	"github.com/sirupsen/logrus"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
 )
 
 type AdMiningCallbackResponse struct {
@@ -36,10 +36,10 @@ func AdMiningLog(c echo.Context) (err error) {
 
 	params := &AdMiningLogRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 	ethAddress := GetCurrentEthAddress(c)
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -48,14 +48,15 @@ func AdMiningLog(c echo.Context) (err error) {
 		AdType:  params.AdType,
 		Address: ethAddress,
 		UserAgent: &miningsvc.UserAgent{
-			Ua:       user_agent.ua,
-			Ipaddr:   user_agent.ipaddr,
-			Os:       user_agent.os,
-			Browser:  user_agent.browser,
-			Platform: user_agent.platform,
-			DeviceId: user_agent.device_id,
+			Ua:       user_agent.UA,
+			Ipaddr:   user_agent.IPAddr,
+			Os:       user_agent.OS,
+			Browser:  user_agent.Browser,
+			Platform: user_agent.Platform,
+			DeviceId: user_agent.DeviceID,
 		},
 	})
+	metrics.CountMiningAction("ad_mining_log", metrics.Outcome(err))
 	if err != nil {
 		return err
 	}
@@ -66,7 +67,7 @@ func AdMiningLog(c echo.Context) (err error) {
 func MyAdMining(c echo.Context) (err error) {
 
 	ethAddress := GetCurrentEthAddress(c)
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -98,9 +99,9 @@ func EstimateAdBonus(c echo.Context) error {
 
 	params := &EstimateAdBonusRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -133,14 +134,16 @@ func buildEstimateAdBonusResponse(in *miningsvc.EstimateAdBonusResponse) *Estima
 func ADMobSSV(c echo.Context) error {
 
 	urlStr := c.Request().URL.String()
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
+		metrics.CountAdCallback("admob", metrics.OutcomeFailed)
 		return rest.BuildSuccessResp(c, buildADMobSSVResponseOnError(err))
 	}
 	resp, err := grpcsvc.AdMiningCallback(ctx, &miningsvc.AdMiningCallbackRequest{
 		AdType:      "admob",
 		CallbackUrl: urlStr,
 	})
+	metrics.CountAdCallback("admob", metrics.Outcome(err))
 	if err != nil {
 		return rest.BuildSuccessResp(c, buildADMobSSVResponseOnError(err))
 	}
@@ -152,17 +155,19 @@ func ADMobSSV(c echo.Context) error {
 func MintegralCallback(c echo.Context) error {
 
 	urlStr := c.Request().URL.String()
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
-		logrus.Error("MintegralCallback:grpc:", err)
+		metrics.CountAdCallback("mintegral", metrics.OutcomeFailed)
+		logging.FromContext(c).Error("MintegralCallback:grpc:", err)
 		return rest.Build403Resp(c, "internal error")
 	}
 	_, err = grpcsvc.AdMiningCallback(ctx, &miningsvc.AdMiningCallbackRequest{
 		AdType:      "mintegral",
 		CallbackUrl: urlStr,
 	})
+	metrics.CountAdCallback("mintegral", metrics.Outcome(err))
 	if err != nil {
-		logrus.Error("MintegralCallback:response:", err)
+		logging.FromContext(c).Error("MintegralCallback:response:", err)
 		return rest.Build403Resp(c, "process error")
 	}
 
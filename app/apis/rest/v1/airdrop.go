@@ -44,7 +44,7 @@ type (
 
 func TwitterAuthUrl(c echo.Context) error {
 	uid := GetCurrentUID(c)
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
@@ -52,12 +52,12 @@ func TwitterAuthUrl(c echo.Context) error {
 	svcresp, err := grpcsvc.GetTwitterAuthUrl(ctx, &pb.GetTwitterAuthUrlRequest{
 		CurrentUid: uid,
 		UserAgent: &pb.UserAgent{
-			Ua:       user_agent.ua,
-			Ipaddr:   user_agent.ipaddr,
-			Os:       user_agent.os,
-			Browser:  user_agent.browser,
-			Platform: user_agent.platform,
-			DeviceId: user_agent.device_id,
+			Ua:       user_agent.UA,
+			Ipaddr:   user_agent.IPAddr,
+			Os:       user_agent.OS,
+			Browser:  user_agent.Browser,
+			Platform: user_agent.Platform,
+			DeviceId: user_agent.DeviceID,
 		},
 	})
 	if err != nil {
@@ -73,7 +73,7 @@ func TwitterCallback(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
@@ -85,12 +85,12 @@ func TwitterCallback(c echo.Context) error {
 		OauthVerifier: params.OauthVerifier,
 		State:         params.State,
 		UserAgent: &pb.UserAgent{
-			Ua:       user_agent.ua,
-			Ipaddr:   user_agent.ipaddr,
-			Os:       user_agent.os,
-			Browser:  user_agent.browser,
-			Platform: user_agent.platform,
-			DeviceId: user_agent.device_id,
+			Ua:       user_agent.UA,
+			Ipaddr:   user_agent.IPAddr,
+			Os:       user_agent.OS,
+			Browser:  user_agent.Browser,
+			Platform: user_agent.Platform,
+			DeviceId: user_agent.DeviceID,
 		},
 	})
 	if err != nil {
@@ -102,7 +102,7 @@ func TwitterCallback(c echo.Context) error {
 
 func AirdropInfo(c echo.Context) error {
 	uid := GetCurrentUID(c)
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
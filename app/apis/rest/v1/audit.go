@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/audit"
+)
+
+// auditAction records the outcome of a security sensitive action of the current user
+func auditAction(c echo.Context, action, target string, err error) {
+	audit.Log(c, action, currentActor(c), target, err)
+}
+
+// currentActor is the uid of the session, or the mises id or eth address of the signed request
+func currentActor(c echo.Context) string {
+	if uid := GetCurrentUID(c); uid > 0 {
+		return "uid:" + strconv.FormatUint(uid, 10)
+	}
+	if misesid := GetCurrentMisesID(c); misesid != "" {
+		return misesid
+	}
+	return GetCurrentEthAddress(c)
+}
+
+// signInActor is the mises id the sign in auth string is signed by
+func signInActor(auth string) string {
+	values, err := url.ParseQuery(auth)
+	if err != nil {
+		return ""
+	}
+	return values.Get("mises_id")
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/batch"
+	"github.com/mises-id/sns-apigateway/lib/binding"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+type BatchParams struct {
+	Requests []batch.Request `json:"requests" validate:"required,min=1,dive"`
+}
+
+// Batch returns the handler serving the sub requests of a batch, the ones other than GET and
+// HEAD only on the routes of mutations, never on the streaming routes
+func Batch(mutations, streaming map[string]bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		params := &BatchParams{}
+		if err := c.Bind(params); err != nil {
+			return err
+		}
+		cfg := env.Current()
+		if len(params.Requests) > cfg.BatchMaxRequests {
+			return codes.InvalidFields(codes.FieldError{
+				Field:   "requests",
+				Rule:    "max",
+				In:      binding.InBody,
+				Message: fmt.Sprintf("requests must be at most %d items", cfg.BatchMaxRequests),
+			})
+		}
+		return rest.BuildSuccessResp(c, batch.Run(c, params.Requests, batch.Config{
+			Prefix:           "/api/v1/",
+			Concurrency:      cfg.BatchConcurrency,
+			Mutations:        mutations,
+			Streaming:        streaming,
+			MaxResponseBytes: cfg.BatchMaxResponseBytes,
+		}))
+	}
+}
@@ -1,11 +1,11 @@
 package v1
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
@@ -42,9 +42,9 @@ func BuildBlacklistRespSlice(infos []*pb.Blacklist) []*BlackResp {
 func ListBlacklist(c echo.Context) error {
 	params := &ListBlacklistParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -65,9 +65,9 @@ func ListBlacklist(c echo.Context) error {
 func CreateBlacklist(c echo.Context) error {
 	params := &BlacklistParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -75,6 +75,7 @@ func CreateBlacklist(c echo.Context) error {
 		Uid:       GetCurrentUID(c),
 		TargetUid: params.UID,
 	})
+	auditAction(c, "blacklist.create", "uid:"+strconv.FormatUint(params.UID, 10), err)
 	if err != nil {
 		return err
 	}
@@ -86,7 +87,7 @@ func DeleteBlacklist(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -94,6 +95,7 @@ func DeleteBlacklist(c echo.Context) error {
 		Uid:       GetCurrentUID(c),
 		TargetUid: uid,
 	})
+	auditAction(c, "blacklist.delete", "uid:"+strconv.FormatUint(uid, 10), err)
 	if err != nil {
 		return err
 	}
@@ -1,10 +1,11 @@
 package v1
 
 import (
+    "strings"
     "github.com/labstack/echo/v4"
     swapsvc "github.com/mises-id/mises-swapsvc/proto"
     "github.com/mises-id/sns-apigateway/app/apis/rest"
-    "github.com/mises-id/sns-apigateway/lib/codes"
+    "github.com/mises-id/sns-apigateway/lib/metrics"
     "time"
 )
 
@@ -60,7 +61,7 @@ func buildBridgeGetCurrenciesSuccessResp(c echo.Context, data []*swapsvc.BridgeC
 }
 
 func BridgeGetCurrencies(c echo.Context) (err error) {
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -75,9 +76,9 @@ func BridgeGetCurrencies(c echo.Context) (err error) {
 func BridgeGetPairsParams(c echo.Context) (err error) {
     params := &swapsvc.BridgeGetPairsParamsRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -91,9 +92,9 @@ func BridgeGetPairsParams(c echo.Context) (err error) {
 func BridgeGetExchangeAmount(c echo.Context) (err error) {
     params := &swapsvc.BridgeGetExchangeAmountRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -107,14 +108,16 @@ func BridgeGetExchangeAmount(c echo.Context) (err error) {
 func BridgeCreateTransaction(c echo.Context) (err error) {
     params := &swapsvc.BridgeCreateTransactionRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
     params.EthAddress = GetCurrentEthAddress(c)
     ret, err := grpcsvc.BridgeCreateTransaction(ctx, params)
+    metrics.CountBridgeTransaction("float", bridgePair(params.From, params.To), metrics.Outcome(err))
+    auditAction(c, "bridge.create_transaction", bridgePair(params.From, params.To), err)
     if err != nil {
         return err
     }
@@ -124,9 +127,9 @@ func BridgeCreateTransaction(c echo.Context) (err error) {
 func BridgeGetTransactionInfo(c echo.Context) (err error) {
     params := &swapsvc.BridgeGetTransactionInfoRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -140,9 +143,9 @@ func BridgeGetTransactionInfo(c echo.Context) (err error) {
 func BridgeGetTransactionStatus(c echo.Context) (err error) {
     params := &swapsvc.BridgeGetTransactionStatusRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -156,9 +159,9 @@ func BridgeGetTransactionStatus(c echo.Context) (err error) {
 func BridgeValidateAddress(c echo.Context) (err error) {
     params := &swapsvc.BridgeValidateAddressRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -172,9 +175,9 @@ func BridgeValidateAddress(c echo.Context) (err error) {
 func BridgeGetFixRateForAmount(c echo.Context) (err error) {
     params := &swapsvc.BridgeGetFixRateForAmountRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -188,14 +191,16 @@ func BridgeGetFixRateForAmount(c echo.Context) (err error) {
 func BridgeCreateFixTransaction(c echo.Context) (err error) {
     params := &swapsvc.BridgeCreateFixTransactionRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
     params.EthAddress = GetCurrentEthAddress(c)
     ret, err := grpcsvc.BridgeCreateFixTransaction(ctx, params)
+    metrics.CountBridgeTransaction("fixed", bridgePair(params.From, params.To), metrics.Outcome(err))
+    auditAction(c, "bridge.create_fix_transaction", bridgePair(params.From, params.To), err)
     if err != nil {
         return err
     }
@@ -228,9 +233,9 @@ func bridgeHistoryFormatTime(unixTimestamp int64) string {
 func BridgeHistoryList(c echo.Context) (err error) {
     params := &swapsvc.BridgeHistoryListRequest{}
     if err := c.Bind(params); err != nil {
-        return codes.ErrInvalidArgument.New("invalid query params")
+        return err
     }
-    grpcsvc, ctx, err := rest.GrpcSwapService()
+    grpcsvc, ctx, err := rest.GrpcSwapService(c)
     if err != nil {
         return err
     }
@@ -241,3 +246,11 @@ func BridgeHistoryList(c echo.Context) (err error) {
     }
     return buildBridgeHistoryListSuccessResp(c, ret.Data)
 }
+
+// bridgePair is the "from/to" currency pair of a create transaction request
+func bridgePair(from, to string) string {
+    if from == "" || to == "" {
+        return ""
+    }
+    return strings.ToLower(from + "/" + to)
+}
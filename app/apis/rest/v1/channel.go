@@ -5,7 +5,6 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 
 	pb "github.com/mises-id/mises-airdropsvc/proto"
 )
@@ -13,10 +12,10 @@ import (
 type (
 	PageChannelUserInput struct {
 		rest.PageParams
-		Misesid string `json:"misesid" query:"misesid"`
+		Misesid string `json:"misesid" query:"misesid" validate:"omitempty,misesid"`
 	}
 	GetChannelUserInput struct {
-		Misesid string `json:"misesid" query:"misesid"`
+		Misesid string `json:"misesid" query:"misesid" validate:"omitempty,misesid"`
 	}
 
 	GetChannelUserOutput struct {
@@ -39,7 +38,7 @@ type (
 		CreatedAt    time.Time        `json:"created_at"`
 	}
 	ChannelUrlRequest struct {
-		Misesid string `json:"misesid" query:"misesid"`
+		Misesid string `json:"misesid" query:"misesid" validate:"omitempty,misesid"`
 		Type    string `json:"type" query:"type"`
 		Medium  string `json:"medium" query:"medium"`
 	}
@@ -90,10 +89,10 @@ func PageChannelUser(c echo.Context) error {
 
 	params := &PageChannelUserInput{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
@@ -113,7 +112,7 @@ func PageChannelUser(c echo.Context) error {
 
 func GetChannelUser(c echo.Context) error {
 	misesid := c.Param("misesid")
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
@@ -130,7 +129,7 @@ func ChannelInfo(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcAirdropService()
+	grpcsvc, ctx, err := rest.GrpcAirdropService(c)
 	if err != nil {
 		return err
 	}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
@@ -14,7 +13,7 @@ type CreateCommentParams struct {
 	CommentableID string `json:"status_id"`
 	NftAssetID    string `json:"nft_asset_id"`
 	ParentID      string `json:"parent_id"`
-	Content       string `json:"content"`
+	Content       string `json:"content" validate:"required,max=2000"`
 }
 
 type ListCommentParams struct {
@@ -75,7 +74,7 @@ func BuildCommentRespSlice(in []*pb.Comment) []*CommentResp {
 }
 func GetComment(c echo.Context) error {
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -93,10 +92,10 @@ func GetComment(c echo.Context) error {
 func ListComment(c echo.Context) error {
 	params := &ListCommentParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -120,9 +119,9 @@ func ListComment(c echo.Context) error {
 func CreateComment(c echo.Context) error {
 	params := &CreateCommentParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid comment params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -140,7 +139,7 @@ func CreateComment(c echo.Context) error {
 }
 
 func DeleteComment(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -155,7 +154,7 @@ func DeleteComment(c echo.Context) error {
 }
 
 func LikeComment(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -171,7 +170,7 @@ func LikeComment(c echo.Context) error {
 }
 
 func UnlikeComment(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,12 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	"github.com/mises-id/sns-apigateway/app/middleware"
+)
+
+// ListFlags returns every feature flag evaluated for the current user and device
+func ListFlags(c echo.Context) error {
+	return rest.BuildSuccessResp(c, middleware.RequestFlags(c).Evaluate(middleware.FlagSubject(c)))
+}
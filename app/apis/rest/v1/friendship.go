@@ -5,7 +5,6 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
@@ -71,7 +70,7 @@ func BuildRecommendUserRespSlice(infos []*pb.UserInfo) []*UserSummaryResp {
 }
 
 func LatestFollowing(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -98,7 +97,7 @@ func ListFriendship(c echo.Context) error {
 		params.RelationType = "fan"
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -121,10 +120,10 @@ func ListFriendship(c echo.Context) error {
 func Follow(c echo.Context) error {
 	params := &FollowParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -142,10 +141,10 @@ func Follow(c echo.Context) error {
 func Unfollow(c echo.Context) error {
 	params := &FollowParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -4,7 +4,7 @@ import (
 	"github.com/labstack/echo/v4"
 	miningsvc "github.com/mises-id/mises-miningsvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
 )
 
 type MBAirdropUser struct {
@@ -29,9 +29,9 @@ func ClaimMBAirdrop(c echo.Context) (err error) {
 	misesid := GetCurrentMisesID(c)
 	params := &MBAirdropClaimParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -43,6 +43,8 @@ func ClaimMBAirdrop(c echo.Context) (err error) {
 		Sig:            params.Sig,
 		TxHash:         params.TxHash,
 	})
+	metrics.CountAirdropClaim(metrics.Outcome(err))
+	auditAction(c, "mb_airdrop.claim", params.ReceiveAddress, err)
 	if err != nil {
 		return
 	}
@@ -52,7 +54,7 @@ func ClaimMBAirdrop(c echo.Context) (err error) {
 
 func FindMBAirdropUser(c echo.Context) error {
 	misesid := c.Param("misesid")
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -1,11 +1,11 @@
 package v1
 
 import (
+	"context"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
 
@@ -228,7 +228,7 @@ func BuildMessageRespSlice(ins []*pb.Message) []*MessageResp {
 	return resp
 }
 func MessageSummary(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -245,9 +245,9 @@ func MessageSummary(c echo.Context) error {
 func ListMessage(c echo.Context) error {
 	params := &ListMessageParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -266,12 +266,36 @@ func ListMessage(c echo.Context) error {
 	return rest.BuildSuccessRespWithPagination(c, BuildMessageRespSlice(svcresp.Messages), svcresp.Paginator)
 }
 
+// ExportMessage streams every message of the current user
+func ExportMessage(c echo.Context) error {
+	grpcsvc, _, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return err
+	}
+	currentUID := GetCurrentUID(c)
+	state := c.QueryParam("state")
+	return rest.Export(c, "messages", func(ctx context.Context, cursor string) ([]*MessageResp, string, error) {
+		svcresp, err := grpcsvc.ListMessage(ctx, &pb.ListMessageRequest{
+			State:      state,
+			CurrentUid: currentUID,
+			Paginator: &pb.PageQuick{
+				NextId: cursor,
+				Limit:  rest.ExportPageSize,
+			},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return BuildMessageRespSlice(svcresp.Messages), svcresp.GetPaginator().GetNextId(), nil
+	})
+}
+
 func ReadMessage(c echo.Context) error {
 	params := &ReadMessageParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"strconv"
+
 	"github.com/labstack/echo/v4"
 	miningsvc "github.com/mises-id/mises-miningsvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
 )
 
 type AdMiningConfig struct {
@@ -41,7 +43,7 @@ func GeMiningConfig(c echo.Context) error {
 
 	ethAddress := GetCurrentEthAddress(c)
 
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -86,20 +88,31 @@ func buildMiningConfigResponse(in *miningsvc.GetMiningConfigResponse) (resp *Min
 
 func GetBonus(c echo.Context) error {
 
+	bonus, err := Bonus(c)
+	if err != nil {
+		return err
+	}
+
+	return rest.BuildSuccessResp(c, bonus)
+}
+
+// Bonus is the mining bonus of the current user
+func Bonus(c echo.Context) (*BonusResponse, error) {
+
 	ethAddress := GetCurrentEthAddress(c)
 
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	resp, err := grpcsvc.GetBonus(ctx, &miningsvc.GetBonusRequest{
 		EthAddress: ethAddress,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return rest.BuildSuccessResp(c, buildBonusResponse(resp))
+	return buildBonusResponse(resp), nil
 }
 
 func RedeemBonus(c echo.Context) error {
@@ -107,10 +120,10 @@ func RedeemBonus(c echo.Context) error {
 	ethAddress := GetCurrentEthAddress(c)
 	params := &RedeemBonusRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcMiningService()
+	grpcsvc, ctx, err := rest.GrpcMiningService(c)
 	if err != nil {
 		return err
 	}
@@ -119,6 +132,8 @@ func RedeemBonus(c echo.Context) error {
 		ReceiveAddress: ethAddress,
 		Bonus:          params.Bonus,
 	})
+	metrics.CountMiningAction("redeem_bonus", metrics.Outcome(err))
+	auditAction(c, "mining.redeem_bonus", strconv.FormatFloat(params.Bonus, 'f', -1, 64), err)
 	if err != nil {
 		return err
 	}
@@ -85,7 +85,7 @@ func ChainInfo(c echo.Context) error {
 		store.Store("LastBlockInfo", info)
 	}
 
-	nodes := strings.Split(env.Envs.MisesNodes, ",")
+	nodes := strings.Split(env.Current().MisesNodes, ",")
 
 	return rest.BuildSuccessResp(c, &ChainInfoResp{
 		BlockHeight: info.height,
@@ -1,14 +1,17 @@
 package v1
 
 import (
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	pb "github.com/mises-id/mises-news-flow/pkg/proto/apiserver/v1"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 )
 
+var newsCachePolicy = rest.CachePolicy{TTL: time.Minute}
+
 type ListNewsParams struct {
 	BeforeNewsId *string `json:"before_news_id" query:"before_news_id"`
 }
@@ -16,25 +19,40 @@ type ListNewsParams struct {
 func ListNews(c echo.Context) error {
 	params := &ListNewsParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcNewsFlowService()
+	news, err := NewsPage(c, params)
 	if err != nil {
 		return err
 	}
 
-	resp, err := grpcsvc.FindNewsInPageBefore(
-		ctx,
-		&pb.FindNewsInPageBeforeRequest{
-			NewsId: params.BeforeNewsId,
-		},
-	)
-	if err != nil {
-		return err
+	return rest.BuildSuccessResp(c, news)
+}
+
+// NewsPage is the page of news before params.BeforeNewsId, cached under the key of ListNews
+func NewsPage(c echo.Context, params *ListNewsParams) (*ListNewsResponse, error) {
+	query := url.Values{}
+	if params.BeforeNewsId != nil {
+		query.Set("before_news_id", *params.BeforeNewsId)
 	}
+	return rest.CachedRoute(c, newsCachePolicy, "/api/v1/news", query, func() (*ListNewsResponse, error) {
+		grpcsvc, ctx, err := rest.GrpcNewsFlowService(c)
+		if err != nil {
+			return nil, err
+		}
 
-	return rest.BuildSuccessResp(c, NewListNewsResponseFromPB(resp))
+		resp, err := grpcsvc.FindNewsInPageBefore(
+			ctx,
+			&pb.FindNewsInPageBeforeRequest{
+				NewsId: params.BeforeNewsId,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return NewListNewsResponseFromPB(resp), nil
+	})
 }
 
 type GetNewsParams struct {
@@ -44,7 +62,7 @@ type GetNewsParams struct {
 func GetNews(c echo.Context) error {
 	newsId := c.Param("id")
 
-	grpcsvc, ctx, err := rest.GrpcNewsFlowService()
+	grpcsvc, ctx, err := rest.GrpcNewsFlowService(c)
 	if err != nil {
 		return err
 	}
@@ -59,7 +77,11 @@ func GetNews(c echo.Context) error {
 		return err
 	}
 
-	return rest.BuildSuccessResp(c, NewGetNewsByIdRespFromPB(resp))
+	news := NewGetNewsByIdRespFromPB(resp)
+	if news != nil && !news.PublishedAt.IsZero() {
+		c.Response().Header().Set(echo.HeaderLastModified, news.PublishedAt.UTC().Format(http.TimeFormat))
+	}
+	return rest.BuildSuccessResp(c, news)
 }
 
 type ListNewsResponse struct {
@@ -183,25 +205,40 @@ type ListStrategiesParams struct {
 func ListStrategies(c echo.Context) error {
 	params := &ListStrategiesParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcNewsFlowService()
+	strategies, err := StrategyPage(c, params)
 	if err != nil {
 		return err
 	}
 
-	resp, err := grpcsvc.FindStrategiesInPageBefore(
-		ctx,
-		&pb.FindStrategiesInPageBeforeRequest{
-			StrategyId: params.BeforeStrategyId,
-		},
-	)
-	if err != nil {
-		return err
+	return rest.BuildSuccessResp(c, strategies)
+}
+
+// StrategyPage is the page of strategies before params.BeforeStrategyId, cached under the key of ListStrategies
+func StrategyPage(c echo.Context, params *ListStrategiesParams) (*ListStrategiesResponse, error) {
+	query := url.Values{}
+	if params.BeforeStrategyId != nil {
+		query.Set("before_strategy_id", *params.BeforeStrategyId)
 	}
+	return rest.CachedRoute(c, newsCachePolicy, "/api/v1/strategies", query, func() (*ListStrategiesResponse, error) {
+		grpcsvc, ctx, err := rest.GrpcNewsFlowService(c)
+		if err != nil {
+			return nil, err
+		}
 
-	return rest.BuildSuccessResp(c, NewListStrategiesResponseFromPB(resp))
+		resp, err := grpcsvc.FindStrategiesInPageBefore(
+			ctx,
+			&pb.FindStrategiesInPageBeforeRequest{
+				StrategyId: params.BeforeStrategyId,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return NewListStrategiesResponseFromPB(resp), nil
+	})
 }
 
 type Strategy struct {
@@ -3,7 +3,6 @@ package v1
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
 
@@ -107,10 +106,10 @@ type (
 func ListNftAssetLike(c echo.Context) error {
 	params := &LikeRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -154,9 +153,9 @@ func BuildLikeResp(like *pb.Like) *LikeResp {
 func PageNftEvent(c echo.Context) error {
 	params := &PageNftEventParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -234,7 +233,7 @@ func BuildNftAccountResp(in *pb.NftAccount) *NftAccount {
 }
 func GetNftAsset(c echo.Context) error {
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -316,7 +315,7 @@ func BuildNftCollectionResp(in *pb.NftCollection) *NftCollection {
 }
 
 func LikeNftAsset(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -332,7 +331,7 @@ func LikeNftAsset(c echo.Context) error {
 }
 
 func UnlikeNftAsset(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -42,7 +42,7 @@ func GetOpenseaAsset(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -64,7 +64,7 @@ func GetOpenseaAssetContract(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -83,7 +83,7 @@ func ListOpenseaAsset(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
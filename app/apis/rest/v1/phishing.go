@@ -4,7 +4,6 @@ import (
 	"github.com/labstack/echo/v4"
 	pb "github.com/mises-id/mises-websitesvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 )
 
 type (
@@ -37,9 +36,9 @@ type (
 func PhishingCheck(c echo.Context) error {
 	params := &PhishingSiteParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -74,9 +73,9 @@ func BuildPhishingSiteResp(data *pb.PhishingCheckResponse) *PhishingSiteResp {
 func VerifyContract(c echo.Context) error {
 	params := &VerifyContractParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
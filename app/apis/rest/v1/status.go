@@ -1,13 +1,13 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 	pb "github.com/mises-id/sns-socialsvc/proto"
 )
 
@@ -37,9 +37,9 @@ type CreateStatusParams struct {
 	FromType     string        `json:"from_type"`
 	StatusType   string        `json:"status_type"`
 	ParentID     string        `json:"parent_id"`
-	Content      string        `json:"content"`
+	Content      string        `json:"content" validate:"max=4000"`
 	LinkMeta     *LinkMeta     `json:"link_meta"`
-	Images       []string      `json:"images"`
+	Images       []string      `json:"images" validate:"max=9"`
 	IsPrivate    bool          `json:"is_private"`
 	ShowDuration time.Duration `json:"show_duration"`
 }
@@ -130,7 +130,7 @@ func BuildStatusRespSlice(infos []*pb.StatusInfo) []*StatusResp {
 }
 func GetStatus(c echo.Context) error {
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -154,10 +154,10 @@ func ListUserStatus(c echo.Context) error {
 
 	params := &ListUserStatusParams{}
 	if err = c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -177,15 +177,43 @@ func ListUserStatus(c echo.Context) error {
 	return rest.BuildSuccessRespWithPagination(c, BuildStatusRespSlice(svcresp.Statuses), svcresp.Paginator)
 }
 
+// ExportUserStatus streams every status of a user
+func ExportUserStatus(c echo.Context) error {
+	uid, err := GetUIDParam(c)
+	if err != nil {
+		return err
+	}
+	grpcsvc, _, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return err
+	}
+	currentUID := GetCurrentUID(c)
+	return rest.Export(c, "statuses", func(ctx context.Context, cursor string) ([]*StatusResp, string, error) {
+		svcresp, err := grpcsvc.ListStatus(ctx, &pb.ListStatusRequest{
+			CurrentUid: currentUID,
+			TargetUid:  uid,
+			FromTypes:  []string{"post", "forward"},
+			Paginator: &pb.PageQuick{
+				NextId: cursor,
+				Limit:  rest.ExportPageSize,
+			},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return BuildStatusRespSlice(svcresp.Statuses), svcresp.GetPaginator().GetNextId(), nil
+	})
+}
+
 // list status
 func ListStatus(c echo.Context) error {
 
 	params := &ListStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -205,13 +233,23 @@ func ListStatus(c echo.Context) error {
 func Timeline(c echo.Context) error {
 	params := &ListUserStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	statuses, paginator, err := TimelinePage(c, params)
 	if err != nil {
 		return err
 	}
+
+	return rest.BuildSuccessRespWithPagination(c, statuses, paginator)
+}
+
+// TimelinePage is a page of the timeline of the current user
+func TimelinePage(c echo.Context, params *ListUserStatusParams) ([]*StatusResp, *pb.PageQuick, error) {
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return nil, nil, err
+	}
 	svcresp, err := grpcsvc.ListUserTimeline(ctx, &pb.ListStatusRequest{
 		CurrentUid: GetCurrentUID(c),
 		Paginator: &pb.PageQuick{
@@ -220,20 +258,20 @@ func Timeline(c echo.Context) error {
 		},
 	})
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	return rest.BuildSuccessRespWithPagination(c, BuildStatusRespSlice(svcresp.Statuses), svcresp.Paginator)
+	return BuildStatusRespSlice(svcresp.Statuses), svcresp.Paginator, nil
 }
 
 func RecommendStatus(c echo.Context) error {
 
 	params := &RecommendStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -275,10 +313,10 @@ func RecommendStatus(c echo.Context) error {
 func RecentStatus(c echo.Context) error {
 	params := &RecentStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -301,9 +339,9 @@ func RecentStatus(c echo.Context) error {
 func UpdateStatus(c echo.Context) error {
 	params := &CreateStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid status params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -322,7 +360,7 @@ func UpdateStatus(c echo.Context) error {
 func CreateStatus(c echo.Context) error {
 	params := &CreateStatusParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid status params")
+		return err
 	}
 	fromType := "post"
 	if len(params.ParentID) > 0 {
@@ -341,7 +379,7 @@ func CreateStatus(c echo.Context) error {
 			return err
 		}
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -365,7 +403,7 @@ func CreateStatus(c echo.Context) error {
 }
 
 func DeleteStatus(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -381,7 +419,7 @@ func DeleteStatus(c echo.Context) error {
 }
 
 func LikeStatus(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -397,7 +435,7 @@ func LikeStatus(c echo.Context) error {
 }
 
 func UnlikeStatus(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
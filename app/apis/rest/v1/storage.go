@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"fmt"
 	"io"
 	"math/rand"
 	"os"
@@ -13,6 +12,7 @@ import (
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
 	"github.com/mises-id/sns-apigateway/config/env"
 	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/logging"
 	storagepb "github.com/mises-id/sns-storagesvc/proto"
 )
 
@@ -28,7 +28,7 @@ type (
 func UploadFile(c echo.Context) error {
 	params := &UploadInput{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid upload params")
+		return err
 	}
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -44,14 +44,14 @@ func UploadFile(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcStorageService()
+	grpcsvc, ctx, err := rest.GrpcStorageService(c)
 	if err != nil {
 		return err
 	}
 	key := path.Join(time.Now().Format("2006/01/02/"), fileName(file.Filename))
 	svcresp, err := grpcsvc.FUpload(ctx, &storagepb.FUploadRequest{File: localFile, Key: key, Bucket: ""})
 	if err != nil {
-		fmt.Println(err.Error())
+		logging.FromContext(c).Errorf("upload %s: %v", key, err)
 		return err
 	}
 	return rest.BuildSuccessResp(c, svcresp)
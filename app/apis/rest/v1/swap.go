@@ -1,12 +1,15 @@
 package v1
 
 import (
+	"context"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	pb "github.com/mises-id/mises-swapsvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
 	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
 )
 
 type (
@@ -14,7 +17,7 @@ type (
 	//----------------------------------------------------------------
 	SwapPublicRequest struct {
 		RequestID string `json:"request_id" query:"request_id"`
-		ChainID   uint64 `json:"chain_id" query:"chain_id"`
+		ChainID   uint64 `json:"chain_id" query:"chain_id" validate:"omitempty,chain_id"`
 	}
 	SwapOrderRequest struct {
 		FromAddress string `json:"from_address" query:"from_address"`
@@ -27,26 +30,26 @@ type (
 	}
 	SwapApproveRequest struct {
 		SwapPublicRequest
-		TokenAddress      string `json:"token_address" query:"token_address"`
-		WalletAddress     string `json:"wallet_address" query:"wallet_address"`
-		AggregatorAddress string `json:"aggregator_address" query:"aggregator_address"`
-		Amount            string `json:"amount" query:"amount"`
+		TokenAddress      string `json:"token_address" query:"token_address" validate:"omitempty,evm_address"`
+		WalletAddress     string `json:"wallet_address" query:"wallet_address" validate:"omitempty,evm_address"`
+		AggregatorAddress string `json:"aggregator_address" query:"aggregator_address" validate:"omitempty,evm_address"`
+		Amount            string `json:"amount" query:"amount" validate:"omitempty,decimal"`
 	}
 	SwapTradeRequest struct {
 		SwapPublicRequest
-		Amount            string  `json:"amount" query:"amount"`
-		FromTokenAddress  string  `json:"from_token_address" query:"from_token_address"`
-		ToTokenAddress    string  `json:"to_token_address" query:"to_token_address"`
-		FromAddress       string  `json:"from_address" query:"from_address"`
-		DestReceiver      string  `json:"dest_receiver" query:"dest_receiver"`
-		Slippage          float32 `json:"slippage" query:"slippage"`
-		AggregatorAddress string  `json:"aggregator_address" query:"aggregator_address"`
+		Amount            string  `json:"amount" query:"amount" validate:"required,decimal"`
+		FromTokenAddress  string  `json:"from_token_address" query:"from_token_address" validate:"required,evm_address"`
+		ToTokenAddress    string  `json:"to_token_address" query:"to_token_address" validate:"required,evm_address"`
+		FromAddress       string  `json:"from_address" query:"from_address" validate:"required,evm_address"`
+		DestReceiver      string  `json:"dest_receiver" query:"dest_receiver" validate:"omitempty,evm_address"`
+		Slippage          float32 `json:"slippage" query:"slippage" validate:"gte=0,lte=50"`
+		AggregatorAddress string  `json:"aggregator_address" query:"aggregator_address" validate:"omitempty,evm_address"`
 	}
 	SwapQuoteRequest struct {
 		SwapPublicRequest
-		Amount           string `json:"amount" query:"amount"`
-		FromTokenAddress string `json:"from_token_address" query:"from_token_address"`
-		ToTokenAddress   string `json:"to_token_address" query:"to_token_address"`
+		Amount           string `json:"amount" query:"amount" validate:"required,decimal"`
+		FromTokenAddress string `json:"from_token_address" query:"from_token_address" validate:"required,evm_address"`
+		ToTokenAddress   string `json:"to_token_address" query:"to_token_address" validate:"required,evm_address"`
 	}
 	//response
 	//----------------------------------------------------------------
@@ -144,14 +147,19 @@ type (
 		AllQuote  []*SwapQuoteInfo `json:"all_quote"`
 	}
 	WalletsAndTokensRequest struct {
-		ChainID uint64
-		Wallets []string
-		Tokens  []string
+		ChainID uint64   `validate:"omitempty,chain_id"`
+		Wallets []string `validate:"max=100,dive,evm_address"`
+		Tokens  []string `validate:"max=100,dive,evm_address"`
 	}
 
 	WalletsAndTokensResponse *map[string]map[string]string
 )
 
+var (
+	swapTokenCachePolicy = rest.CachePolicy{TTL: 5 * time.Minute}
+	swapQuoteCachePolicy = rest.CachePolicy{TTL: 10 * time.Second}
+)
+
 func GetChainIDParam(c echo.Context) (uint64, error) {
 	chain_idParam := c.Param("chain_id")
 	chain_id, err := strconv.ParseUint(chain_idParam, 10, 64)
@@ -164,9 +172,9 @@ func GetChainIDParam(c echo.Context) (uint64, error) {
 func PageSwapOrder(c echo.Context) error {
 	params := &SwapOrderRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -185,12 +193,50 @@ func PageSwapOrder(c echo.Context) error {
 	return rest.BuildSuccessRespWithSwapPage(c, params.RequestID, BuildSwapOrderSliceResp(svcresp.Data), svcresp.Paginator)
 }
 
+// ExportSwapOrder streams every swap order of an address, the cursor is the next page number
+func ExportSwapOrder(c echo.Context) error {
+	params := &SwapOrderRequest{}
+	if err := c.Bind(params); err != nil {
+		return err
+	}
+	grpcsvc, _, err := rest.GrpcSwapService(c)
+	if err != nil {
+		return err
+	}
+	fromAddress := c.Param("from_address")
+	return rest.Export(c, "swap_orders", func(ctx context.Context, cursor string) ([]*SwapOrderResponse, string, error) {
+		pageNum := uint64(1)
+		if cursor != "" {
+			var err error
+			if pageNum, err = strconv.ParseUint(cursor, 10, 64); err != nil || pageNum == 0 {
+				return nil, "", codes.ErrInvalidArgument.Newf("invalid cursor %s", cursor)
+			}
+		}
+		svcresp, err := grpcsvc.SwapOrderPage(ctx, &pb.SwapOrderPageRequest{
+			ChainID:     params.ChainID,
+			FromAddress: fromAddress,
+			Paginator: &pb.Page{
+				PageNum:  pageNum,
+				PageSize: rest.ExportPageSize,
+			},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		next := ""
+		if svcresp.Paginator != nil && pageNum < svcresp.Paginator.TotalPage {
+			next = strconv.FormatUint(pageNum+1, 10)
+		}
+		return BuildSwapOrderSliceResp(svcresp.Data), next, nil
+	})
+}
+
 func FindSwapOrder(c echo.Context) error {
 	params := &SwapOrderRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -210,28 +256,34 @@ func FindSwapOrder(c echo.Context) error {
 func ListTokens(c echo.Context) error {
 	params := &TokenRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
-	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
-	if err != nil {
 		return err
 	}
-	svcresp, err := grpcsvc.ListSwapToken(ctx, &pb.ListSwapTokenRequest{
-		ChainID: params.ChainID,
+	tokens, err := rest.Cached(c, swapTokenCachePolicy, func() ([]*Token, error) {
+		grpcsvc, ctx, err := rest.GrpcSwapService(c)
+		if err != nil {
+			return nil, err
+		}
+		svcresp, err := grpcsvc.ListSwapToken(ctx, &pb.ListSwapTokenRequest{
+			ChainID: params.ChainID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return BuildSwapTokenSlice(svcresp.Data), nil
 	})
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessRespWithRequestID(c, params.RequestID, BuildSwapTokenSlice(svcresp.Data))
+	return rest.BuildSuccessRespWithRequestID(c, params.RequestID, tokens)
 }
 
 // approve allowance
 func GetSwapApproveAllowance(c echo.Context) error {
 	params := &SwapApproveRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -251,9 +303,9 @@ func GetSwapApproveAllowance(c echo.Context) error {
 func ApproveSwapTransaction(c echo.Context) error {
 	params := &SwapApproveRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -270,7 +322,7 @@ func ApproveSwapTransaction(c echo.Context) error {
 }
 
 func SwapHealth(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -284,9 +336,9 @@ func SwapHealth(c echo.Context) error {
 func SwapTrade(c echo.Context) error {
 	params := &SwapTradeRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
@@ -303,29 +355,66 @@ func SwapTrade(c echo.Context) error {
 		Misesid:           ethAddress,
 	})
 	if err != nil {
+		metrics.CountSwapTrade(params.ChainID, metrics.OutcomeFailed)
 		return err
 	}
+	outcome := metrics.OutcomeOK
+	if svcresp.Data != nil {
+		outcome = metrics.ItemOutcome(svcresp.Data.Error)
+	}
+	metrics.CountSwapTrade(params.ChainID, outcome)
 	return rest.BuildSuccessRespWithRequestID(c, params.RequestID, buildSwapTradeInfo(svcresp.Data))
 }
 func SwapQuote(c echo.Context) error {
 	params := &SwapQuoteRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
-	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
-	if err != nil {
 		return err
 	}
-	svcresp, err := grpcsvc.SwapQuote(ctx, &pb.SwapQuoteRequest{
-		ChainID:          params.ChainID,
-		FromTokenAddress: params.FromTokenAddress,
-		ToTokenAddress:   params.ToTokenAddress,
-		Amount:           params.Amount,
+	quote, err := rest.Cached(c, swapQuoteCachePolicy, func() (*SwapQuoteResponse, error) {
+		grpcsvc, ctx, err := rest.GrpcSwapService(c)
+		if err != nil {
+			return nil, err
+		}
+		svcresp, err := grpcsvc.SwapQuote(ctx, &pb.SwapQuoteRequest{
+			ChainID:          params.ChainID,
+			FromTokenAddress: params.FromTokenAddress,
+			ToTokenAddress:   params.ToTokenAddress,
+			Amount:           params.Amount,
+		})
+		countSwapQuote(params.ChainID, svcresp, err)
+		if err != nil {
+			return nil, err
+		}
+		return buildSwapQuoteResponse(svcresp), nil
 	})
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessRespWithRequestID(c, params.RequestID, buildSwapQuoteResponse(svcresp))
+	return rest.BuildSuccessRespWithRequestID(c, params.RequestID, quote)
+}
+
+// countSwapQuote records the outcome of every aggregator, cache hits are not counted
+func countSwapQuote(chainID uint64, data *pb.SwapQuoteResponse, err error) {
+	if err != nil || data == nil {
+		metrics.CountSwapQuote(chainID, "", metrics.OutcomeFailed)
+		return
+	}
+	answered := 0
+	for _, v := range data.AllQuote {
+		if v == nil {
+			continue
+		}
+		aggregator := ""
+		if v.Aggregator != nil {
+			aggregator = v.Aggregator.Name
+		}
+		outcome := metrics.ItemOutcome(v.Error)
+		if outcome == metrics.OutcomeOK {
+			answered++
+		}
+		metrics.CountSwapQuote(chainID, aggregator, outcome)
+	}
+	metrics.ObserveSwapQuoteAggregators(chainID, answered)
 }
 
 func buildSwapQuoteResponse(data *pb.SwapQuoteResponse) *SwapQuoteResponse {
@@ -531,9 +620,9 @@ func NewToken(data *pb.Token) *Token {
 func WalletsAndTokens(c echo.Context) error {
 	params := &WalletsAndTokensRequest{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSwapService()
+	grpcsvc, ctx, err := rest.GrpcSwapService(c)
 	if err != nil {
 		return err
 	}
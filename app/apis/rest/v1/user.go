@@ -3,13 +3,17 @@ package v1
 import (
 	"context"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
 	"github.com/mises-id/sns-apigateway/app/middleware"
+	"github.com/mises-id/sns-apigateway/lib/audit"
 	"github.com/mises-id/sns-apigateway/lib/codes"
-	"github.com/mssola/user_agent"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/validation"
 
 	airdroppb "github.com/mises-id/mises-airdropsvc/proto"
 	pb "github.com/mises-id/sns-socialsvc/proto"
@@ -87,14 +91,6 @@ type (
 	ReceiveAirdropParams struct {
 		Tweet string `json:"tweet"`
 	}
-	UserAgent struct {
-		ua        string
-		ipaddr    string
-		os        string
-		browser   string
-		platform  string
-		device_id string
-	}
 )
 
 func GetCurrentUID(c echo.Context) uint64 {
@@ -130,12 +126,12 @@ func GetUIDParam(c echo.Context) (uint64, error) {
 func SignIn(c echo.Context) error {
 	params := &SignInParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 	if params.UserAuthz == nil {
 		return codes.ErrInvalidArgument.New("invalid auth params")
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -144,14 +140,15 @@ func SignIn(c echo.Context) error {
 		Auth:     params.UserAuthz.Auth,
 		Referrer: params.Referrer,
 		UserAgent: &pb.UserAgent{
-			Ua:       user_agent.ua,
-			Ipaddr:   user_agent.ipaddr,
-			Os:       user_agent.os,
-			Browser:  user_agent.browser,
-			Platform: user_agent.platform,
-			DeviceId: user_agent.device_id,
+			Ua:       user_agent.UA,
+			Ipaddr:   user_agent.IPAddr,
+			Os:       user_agent.OS,
+			Browser:  user_agent.Browser,
+			Platform: user_agent.Platform,
+			DeviceId: user_agent.DeviceID,
 		},
 	})
+	audit.Log(c, "user.sign_in", signInActor(params.UserAuthz.Auth), "", err)
 	if err != nil {
 		return err
 	}
@@ -161,18 +158,8 @@ func SignIn(c echo.Context) error {
 	})
 }
 
-func userAgent(c echo.Context) *UserAgent {
-	res := &UserAgent{}
-	uastr := c.Request().UserAgent()
-	ua := user_agent.New(uastr)
-	res.ua = uastr
-	res.ipaddr = c.RealIP()
-	browserName, browserVersion := ua.Browser()
-	res.browser = browserName + " " + browserVersion
-	res.os = ua.OS()
-	res.platform = ua.Platform()
-	res.device_id = c.Request().Header.Get("mises-device-id")
-	return res
+func userAgent(c echo.Context) *middleware.UserAgent {
+	return middleware.RequestUserAgent(c)
 }
 
 func ReceiveAirdrop(c echo.Context) error {
@@ -180,7 +167,7 @@ func ReceiveAirdrop(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -200,7 +187,7 @@ func Complaint(c echo.Context) error {
 		return err
 	}
 	uid := GetCurrentUID(c)
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -210,6 +197,7 @@ func Complaint(c echo.Context) error {
 		TargetId:   params.TargetID,
 		Reason:     params.Reason,
 	})
+	auditAction(c, "complaint.create", params.TargetType+":"+params.TargetID, err)
 	if err != nil {
 		return err
 	}
@@ -218,7 +206,7 @@ func Complaint(c echo.Context) error {
 
 func ShareTweetUrl(c echo.Context) error {
 	uid := GetCurrentUID(c)
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -234,19 +222,80 @@ func ShareTweetUrl(c echo.Context) error {
 }
 
 func MyProfile(c echo.Context) error {
-	uid := GetCurrentUID(c)
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	profile, err := Profile(c)
 	if err != nil {
 		return err
 	}
+	return rest.BuildSuccessResp(c, profile)
+}
+
+// Profile is the full profile of the current user
+func Profile(c echo.Context) (*UserFullResp, error) {
+	uid := GetCurrentUID(c)
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return nil, err
+	}
 	svcresp, err := grpcsvc.FindUser(ctx, &pb.FindUserRequest{
 		Uid:        uid,
 		CurrentUid: uid,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return rest.BuildSuccessResp(c, BuildUserFullResp(svcresp.User, false))
+	return BuildUserFullResp(svcresp.User, false), nil
+}
+
+// userSummaryConcurrency bounds the users found at once for UserSummaries
+const userSummaryConcurrency = 8
+
+// UserSummaries finds the summaries of uids as seen by the current user, the social service
+// finds one user at a time so they are found concurrently
+func UserSummaries(c echo.Context, uids []uint64) (map[uint64]*UserSummaryResp, error) {
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return nil, err
+	}
+	currentUID := GetCurrentUID(c)
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		firstErr  error
+		summaries = make(map[uint64]*UserSummaryResp, len(uids))
+		slots     = make(chan struct{}, userSummaryConcurrency)
+	)
+	for _, uid := range uids {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(uid uint64) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			svcresp, err := grpcsvc.FindUser(ctx, &pb.FindUserRequest{
+				Uid:        uid,
+				CurrentUid: currentUID,
+			})
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				// an unknown user is left out, the other errors fail the lookup
+				if code, _ := mw.ErrorCode(err); !code.Equal(codes.ErrNotFound) && firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if summary := BuildUserSummaryResp(svcresp.User); summary != nil {
+				summary.IsFollowed = svcresp.IsFollowed
+				summaries[uid] = summary
+			}
+		}(uid)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return summaries, nil
 }
 
 func FindUser(c echo.Context) error {
@@ -255,7 +304,7 @@ func FindUser(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -273,7 +322,7 @@ func FindMisesUser(c echo.Context) error {
 	currentUID := GetCurrentUID(c)
 	misesidParam := c.Param("misesid")
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -290,9 +339,9 @@ func FindMisesUser(c echo.Context) error {
 type UserProfileParams struct {
 	Gender  string `json:"gender"`
 	Mobile  string `json:"mobile"`
-	Eamil   string `json:"email"`
-	Address string `json:"address"`
-	Intro   string `json:"intro"`
+	Eamil   string `json:"email" validate:"omitempty,email"`
+	Address string `json:"address" validate:"max=200"`
+	Intro   string `json:"intro" validate:"max=500"`
 }
 type UpdateUserConfigParams struct {
 	NftState bool `json:"nft_state"`
@@ -302,7 +351,7 @@ type UserConfig struct {
 	NftState bool `json:"nft_state"`
 }
 type UserNameParams struct {
-	Username string `json:"username"`
+	Username string `json:"username" validate:"required,max=64"`
 }
 
 type UserAvatarParams struct {
@@ -311,18 +360,35 @@ type UserAvatarParams struct {
 }
 
 type UserUpdateParams struct {
-	By       string             `json:"by"`
+	By       string             `json:"by" validate:"required,oneof=profile avatar username"`
 	Profile  *UserProfileParams `json:"profile"`
 	Username *UserNameParams    `json:"username"`
 	Avatar   *UserAvatarParams  `json:"avatar"`
 }
 
+func init() {
+	validation.Default.RegisterStructValidation(validateUserUpdate, UserUpdateParams{})
+}
+
+// validateUserUpdate requires the params of the chosen update, UpdateUser reads only those
+func validateUserUpdate(sl validator.StructLevel) {
+	params := sl.Current().Interface().(UserUpdateParams)
+	switch {
+	case params.By == "profile" && params.Profile == nil:
+		sl.ReportError(params.Profile, "profile", "Profile", "required_if", "by is profile")
+	case params.By == "avatar" && params.Avatar == nil:
+		sl.ReportError(params.Avatar, "avatar", "Avatar", "required_if", "by is avatar")
+	case params.By == "username" && params.Username == nil:
+		sl.ReportError(params.Username, "username", "Username", "required_if", "by is username")
+	}
+}
+
 func UpdateUserConfig(c echo.Context) error {
 	params := &UpdateUserConfigParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -337,7 +403,7 @@ func UpdateUserConfig(c echo.Context) error {
 }
 
 func GetUserConfig(c echo.Context) error {
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -367,7 +433,7 @@ func BuildUserConfig(config *pb.UserConfig) *UserConfig {
 func UpdateUser(c echo.Context) error {
 	params := &UserUpdateParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument
+		return err
 	}
 
 	uid := c.Get("CurrentUser").(*middleware.UserSession).UID
@@ -375,7 +441,7 @@ func UpdateUser(c echo.Context) error {
 	var ctx context.Context
 	var err error
 	var serverresp *pb.UpdateUserResponse
-	if grpcsvc, ctx, err = rest.GrpcSocialService(); err != nil {
+	if grpcsvc, ctx, err = rest.GrpcSocialService(c); err != nil {
 		return err
 	}
 	switch params.By {
@@ -391,6 +457,9 @@ func UpdateUser(c echo.Context) error {
 			Intro:   params.Profile.Intro,
 		})
 	case "avatar":
+		if params.Avatar != nil && params.Avatar.NftAssetId != "" && !middleware.FlagEnabled(c, middleware.FlagNftAvatar) {
+			return codes.ErrForbidden
+		}
 		serverresp, err = grpcsvc.UpdateUserAvatar(ctx, &pb.UpdateUserAvatarRequest{
 			Uid:            uid,
 			AttachmentPath: params.Avatar.AttachmentPath,
@@ -401,6 +470,7 @@ func UpdateUser(c echo.Context) error {
 			Uid:      uid,
 			Username: params.Username.Username,
 		})
+		auditAction(c, "user.username_change", params.Username.Username, err)
 	}
 	if err != nil {
 		return err
@@ -543,7 +613,7 @@ func PageUserNftAsset(c echo.Context) error {
 	}
 	params := &PageNftAssetParams{}
 	if err = c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 	params.UID = uid
 
@@ -551,7 +621,7 @@ func PageUserNftAsset(c echo.Context) error {
 }
 func PageNftAsset(c echo.Context, params *PageNftAssetParams) error {
 
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -574,7 +644,7 @@ func PageNftAsset(c echo.Context, params *PageNftAssetParams) error {
 func MyNftAsset(c echo.Context) error {
 	params := &PageNftAssetParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 	params.UID = GetCurrentUID(c)
 	return PageNftAsset(c, params)
@@ -583,13 +653,13 @@ func MyNftAsset(c echo.Context) error {
 func ListUserLike(c echo.Context) error {
 	params := &ListUserLikeParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.Newf("invalid query params")
+		return err
 	}
 	uid, err := GetUIDParam(c)
 	if err != nil {
 		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcSocialService()
+	grpcsvc, ctx, err := rest.GrpcSocialService(c)
 	if err != nil {
 		return err
 	}
@@ -608,3 +678,30 @@ func ListUserLike(c echo.Context) error {
 
 	return rest.BuildSuccessRespWithPagination(c, BuildUserLikeResplice(svcresp.Statuses), svcresp.Paginator)
 }
+
+// ExportUserLike streams every status liked by a user
+func ExportUserLike(c echo.Context) error {
+	uid, err := GetUIDParam(c)
+	if err != nil {
+		return err
+	}
+	grpcsvc, _, err := rest.GrpcSocialService(c)
+	if err != nil {
+		return err
+	}
+	currentUID := GetCurrentUID(c)
+	return rest.Export(c, "likes", func(ctx context.Context, cursor string) ([]*UserLikeResp, string, error) {
+		svcresp, err := grpcsvc.ListLikeStatus(ctx, &pb.ListLikeRequest{
+			Uid:        uid,
+			CurrentUid: currentUID,
+			Paginator: &pb.PageQuick{
+				NextId: cursor,
+				Limit:  rest.ExportPageSize,
+			},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return BuildUserLikeResplice(svcresp.Statuses), svcresp.GetPaginator().GetNextId(), nil
+	})
+}
@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"os"
 	"path"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	pb "github.com/mises-id/mises-websitesvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 )
 
 type (
@@ -32,6 +32,11 @@ type (
 		Subcategory       *WebsiteCategoryResp `json:"subcategory"`
 	}
 
+	websitePage struct {
+		Data      []*WebsiteResp `json:"data"`
+		Paginator *pb.Page       `json:"paginator"`
+	}
+
 	WebsiteSearchParams struct {
 		Keywords string `json:"keywords" query:"keywords"`
 	}
@@ -51,37 +56,45 @@ type (
 	}
 )
 
+var websiteCachePolicy = rest.CachePolicy{TTL: 5 * time.Minute}
+
 func PageWebsite(c echo.Context) error {
 	params := &WebsiteParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
-	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
-	if err != nil {
 		return err
 	}
-	svcresp, err := grpcsvc.WebsitePage(ctx, &pb.WebsitePageRequest{
-		Type:              "web3",
-		Keywords:          params.Keywords,
-		WebsiteCategoryId: params.WebSiteCategoryID,
-		SubcategoryId:     params.SubcategoryID,
-		Paginator: &pb.Page{
-			PageNum:  uint64(params.PageParams.PageNum),
-			PageSize: uint64(params.PageParams.PageSize),
-		},
+	page, err := rest.Cached(c, websiteCachePolicy, func() (*websitePage, error) {
+		grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
+		if err != nil {
+			return nil, err
+		}
+		svcresp, err := grpcsvc.WebsitePage(ctx, &pb.WebsitePageRequest{
+			Type:              "web3",
+			Keywords:          params.Keywords,
+			WebsiteCategoryId: params.WebSiteCategoryID,
+			SubcategoryId:     params.SubcategoryID,
+			Paginator: &pb.Page{
+				PageNum:  uint64(params.PageParams.PageNum),
+				PageSize: uint64(params.PageParams.PageSize),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &websitePage{Data: BuildWebsiteSliceResp(svcresp.Data), Paginator: svcresp.Paginator}, nil
 	})
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessRespWithWebsitePage(c, BuildWebsiteSliceResp(svcresp.Data), svcresp.Paginator)
+	return rest.BuildSuccessRespWithWebsitePage(c, page.Data, page.Paginator)
 }
 
 func SearchWebsite(c echo.Context) error {
 	params := &WebsiteSearchParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -98,9 +111,9 @@ func SearchWebsite(c echo.Context) error {
 func WebsiteInternalSearch(c echo.Context) error {
 	params := &WebsiteInternalSearchParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -118,9 +131,9 @@ func WebsiteInternalSearch(c echo.Context) error {
 func PageExtensions(c echo.Context) error {
 	params := &WebsiteParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -143,9 +156,9 @@ func PageExtensions(c echo.Context) error {
 func CreateRecommendJson(c echo.Context) error {
 	params := &WebsiteParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -4,7 +4,6 @@ import (
 	"github.com/labstack/echo/v4"
 	pb "github.com/mises-id/mises-websitesvc/proto"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
-	"github.com/mises-id/sns-apigateway/lib/codes"
 )
 
 type (
@@ -24,31 +23,41 @@ func ListWebsiteCategory(c echo.Context) error {
 
 	params := &ListWebsiteCategoryParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	categories, err := WebsiteCategories(c)
 	if err != nil {
 		return err
 	}
 
+	return rest.BuildSuccessResp(c, categories)
+}
+
+// WebsiteCategories are the categories of the web3 websites
+func WebsiteCategories(c echo.Context) ([]*WebsiteCategoryResp, error) {
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
+	if err != nil {
+		return nil, err
+	}
+
 	svcresp, err := grpcsvc.WebsiteCategoryList(ctx, &pb.WebsiteCategoryListRequest{
 		Type: "web3",
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return rest.BuildSuccessResp(c, BuildWebsiteCategorySliceResp(svcresp.Data))
+	return BuildWebsiteCategorySliceResp(svcresp.Data), nil
 }
 func ListExtensionsCategory(c echo.Context) error {
 
 	params := &ListWebsiteCategoryParams{}
 	if err := c.Bind(params); err != nil {
-		return codes.ErrInvalidArgument.New("invalid query params")
+		return err
 	}
 
-	grpcsvc, ctx, err := rest.GrpcWebsiteService()
+	grpcsvc, ctx, err := rest.GrpcWebsiteService(c)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/lib/connect"
+)
+
+type UserRequest struct {
+	UID uint64 `json:"uid" param:"uid"`
+}
+
+type StatusRequest struct {
+	ID string `json:"id" param:"id"`
+}
+
+type StatusPage struct {
+	Data       []*v1.StatusResp     `json:"data"`
+	Pagination rest.PageQuickParams `json:"pagination"`
+}
+
+// Gateway is the curated api of the web clients over gRPC-Web and Connect, each method is
+// served by its rest route
+var Gateway = connect.Service{
+	Name: "mises.gateway.v1.Gateway",
+	Methods: []connect.Method{
+		{Name: "GetMe", Route: "GET /api/v1/user/me", Request: connect.Empty{}, Response: v1.UserFullResp{}},
+		{Name: "GetUser", Route: "GET /api/v1/user/:uid", Request: UserRequest{}, Response: v1.UserRestrictedResp{}},
+		{Name: "Follow", Route: "POST /api/v1/user/follow", Request: v1.FollowParams{}, Response: connect.Empty{}},
+		{Name: "Unfollow", Route: "DELETE /api/v1/user/follow", Request: v1.FollowParams{}, Response: connect.Empty{}},
+		{Name: "ListTimeline", Route: "GET /api/v1/timeline/me", Request: v1.ListUserStatusParams{}, Response: StatusPage{}, Page: true},
+		{Name: "GetStatus", Route: "GET /api/v1/status/:id", Request: StatusRequest{}, Response: v1.StatusResp{}},
+		{Name: "LikeStatus", Route: "POST /api/v1/status/:id/like", Request: StatusRequest{}, Response: connect.Empty{}},
+		{Name: "UnlikeStatus", Route: "DELETE /api/v1/status/:id/like", Request: StatusRequest{}, Response: connect.Empty{}},
+		{Name: "ListNews", Route: "GET /api/v1/news", Request: v1.ListNewsParams{}, Response: v1.ListNewsResponse{}},
+		{Name: "ListStrategies", Route: "GET /api/v1/strategies", Request: v1.ListStrategiesParams{}, Response: v1.ListStrategiesResponse{}},
+		{Name: "GetMiningConfig", Route: "GET /api/v1/mining/config", Request: connect.Empty{}, Response: v1.MiningConfigResponse{}},
+		{Name: "GetBonus", Route: "GET /api/v1/mining/bonus", Request: connect.Empty{}, Response: v1.BonusResponse{}},
+	},
+}
+
+// Serve serves the methods of Gateway at /mises.gateway.v1.Gateway/:method
+var Serve = connect.Handler(Gateway)
@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+)
+
+// responseTypes are the data payloads described in the descriptor
+var responseTypes = []interface{}{
+	rest.PageParams{},
+	rest.PageQuickParams{},
+	[]*v1.StatusResp{},
+	[]*v1.CommentResp{},
+	[]*v1.MessageResp{},
+	v1.MessageSummaryResp{},
+	v1.UserFullResp{},
+	v1.UserRestrictedResp{},
+	[]*v1.UserSummaryResp{},
+	[]*v1.NftAssetResp{},
+	[]*v1.SwapOrderResponse{},
+	[]*v1.Token{},
+	v1.SwapQuoteResponse{},
+	v1.SwapTradeInfo{},
+	v1.News{},
+	v1.ListNewsResponse{},
+	v1.ListStrategiesResponse{},
+	[]*v1.WebsiteResp{},
+	v1.MiningConfigResponse{},
+	v1.BonusResponse{},
+}
+
+// WriteProto writes proto/gateway/v1/gateway.proto, the descriptor of the application/x-protobuf
+// responses and of Gateway
+func WriteProto(w io.Writer) error {
+	types := make([]reflect.Type, 0, len(responseTypes))
+	for _, v := range responseTypes {
+		types = append(types, reflect.TypeOf(v))
+	}
+	return wire.WriteServiceProto(w, []wire.Service{Gateway.Descriptor()}, types...)
+}
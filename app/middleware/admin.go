@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+// AdminAuthMiddleware lets through the requests bearing the ADMIN_TOKEN
+var AdminAuthMiddleware = func(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		expected := env.Current().AdminToken
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return codes.ErrUnauthorized
+		}
+		return next(c)
+	}
+}
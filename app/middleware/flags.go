@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/filewatch"
+	"github.com/mises-id/sns-apigateway/lib/flags"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FlagBridgeFixRate = "bridge_fix_rate"
+	FlagNftAvatar     = "nft_avatar"
+	FlagAdMining      = "ad_mining"
+)
+
+// FeatureFlags serves the flags file, the gated features stay on until the file says otherwise
+var FeatureFlags = flags.NewStore(flags.Set{
+	FlagBridgeFixRate: {Description: "bridge fixed rate exchanges", Enabled: true, Percentage: 100},
+	FlagNftAvatar:     {Description: "nft assets as user avatar", Enabled: true, Percentage: 100},
+	FlagAdMining:      {Description: "ad mining bonus", Enabled: true, Percentage: 100},
+})
+
+// SetupFeatureFlags loads the flags file and reloads it on change until ctx is done
+func SetupFeatureFlags(ctx context.Context, path string) error {
+	if path == "" {
+		return nil
+	}
+	set, err := flags.Load(path)
+	if err != nil {
+		return err
+	}
+	FeatureFlags.Replace(set)
+	return filewatch.Watch(ctx, path, func() {
+		set, err := flags.Load(path)
+		if err != nil {
+			logrus.Errorf("feature flags reload rejected: %v", err)
+			return
+		}
+		FeatureFlags.Replace(set)
+		logrus.Info("feature flags reloaded")
+	})
+}
+
+// FlagSubject is the current user and device of the request
+func FlagSubject(c echo.Context) flags.Subject {
+	ua := RequestUserAgent(c)
+	subject := flags.Subject{
+		DeviceID: ua.DeviceID,
+		Platform: ua.Platform,
+	}
+	if user, ok := c.Get("CurrentUser").(*UserSession); ok && user != nil {
+		subject.UID = user.UID
+		subject.MisesID = user.Misesid
+	}
+	return subject
+}
+
+// RequestFlags returns the flag set of the request, taken once so a reload does not change flags mid request
+func RequestFlags(c echo.Context) flags.Set {
+	if set, ok := c.Get("FeatureFlags").(flags.Set); ok {
+		return set
+	}
+	set := FeatureFlags.Current()
+	c.Set("FeatureFlags", set)
+	return set
+}
+
+// FlagEnabled evaluates the flag name for the request
+func FlagEnabled(c echo.Context, name string) bool {
+	return RequestFlags(c).Enabled(name, FlagSubject(c))
+}
+
+// RequireFlag hides the route while the flag name is off for the request,
+// it runs after SetCurrentUserMiddleware so user rollouts apply
+func RequireFlag(name string) echo.MiddlewareFunc {
+	return mw.Describe("flag "+name, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !FlagEnabled(c, name) {
+				return codes.ErrNotFound
+			}
+			return next(c)
+		}
+	})
+}
@@ -11,7 +11,6 @@ import (
 )
 
 var (
-	secret           = env.Envs.JWTSecret
 	validAuthMethods = []string{
 		"Bearer",
 	}
@@ -26,7 +25,8 @@ type UserSession struct {
 
 func Auth(ctx context.Context, authToken string) (*UserSession, error) {
 	claim, err := jwt.Parse(authToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+		// read at call time, the command line may load another config after the package init
+		return []byte(env.Envs.JWTSecret), nil
 	})
 	if err != nil {
 		if err.Error() == "Token is expired" {
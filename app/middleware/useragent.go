@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mssola/user_agent"
+)
+
+// UserAgent is the client of a request as the services record it
+type UserAgent struct {
+	UA       string
+	IPAddr   string
+	OS       string
+	Browser  string
+	Platform string
+	DeviceID string
+}
+
+// RequestUserAgent parses the user agent and the device of the request
+func RequestUserAgent(c echo.Context) *UserAgent {
+	res := &UserAgent{}
+	uastr := c.Request().UserAgent()
+	ua := user_agent.New(uastr)
+	res.UA = uastr
+	res.IPAddr = c.RealIP()
+	browserName, browserVersion := ua.Browser()
+	res.Browser = browserName + " " + browserVersion
+	res.OS = ua.OS()
+	res.Platform = ua.Platform()
+	res.DeviceID = c.Request().Header.Get("mises-device-id")
+	return res
+}
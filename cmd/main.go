@@ -1,30 +1,129 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
+	"fmt"
 	"os"
-	"time"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/mises-id/sns-apigateway/cmd/rest"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/config/route"
+	"github.com/mises-id/sns-apigateway/lib/audit"
+	"github.com/mises-id/sns-apigateway/lib/buildinfo"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
+	"github.com/mises-id/sns-apigateway/lib/sdkgen"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
 )
 
-func main() {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
-	defer cancel()
+var configFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "config, c",
+		Usage:  "config file, .yaml, .yml or .toml",
+		EnvVar: env.ConfigFileEnv,
+	},
+	cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "override a setting by its env name, e.g. --set PORT=8081",
+	},
+}
 
+var jsonFlag = cli.BoolFlag{
+	Name:  "json",
+	Usage: "print json",
+}
+
+func main() {
 	app := cli.NewApp()
-	app.Action = func(c *cli.Context) error {
-		return rest.Start(ctx)
-	}
+	app.Name = "sns-apigateway"
+	app.Usage = "mises api gateway"
+	app.Version = buildinfo.Get().String()
+	app.Flags = configFlags
+	// serve when no command is given
+	app.Action = serve
 	app.Commands = cli.Commands{
 		{
-			Name:  "",
-			Usage: "./mises",
-			Flags: []cli.Flag{},
+			Name:   "serve",
+			Usage:  "start the api server",
+			Flags:  configFlags,
+			Action: serve,
+		},
+		{
+			Name:   "routes",
+			Usage:  "print the route table with the middleware chain and rate policy of every route",
+			Flags:  append([]cli.Flag{jsonFlag}, configFlags...),
+			Action: printRoutes,
+		},
+		{
+			Name:   "openapi",
+			Usage:  "print the openapi document of the routes, fails when it does not match the routes",
+			Flags:  configFlags,
+			Action: printOpenAPI,
+		},
+		{
+			Name:  "sdk",
+			Usage: "generate the typescript and go clients of the api, --check fails when they are out of date",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{Name: "out", Value: "sdk", Usage: "dir of the clients"},
+				cli.BoolFlag{Name: "check", Usage: "compare the clients of --out with the api instead of writing them"},
+			}, configFlags...),
+			Action: generateSDK,
+		},
+		{
+			Name:  "config",
+			Usage: "config tools",
+			Subcommands: cli.Commands{
+				{
+					Name:   "print",
+					Usage:  "print the effective config with secrets redacted",
+					Flags:  append([]cli.Flag{jsonFlag}, configFlags...),
+					Action: printConfig,
+				},
+				{
+					Name:   "check",
+					Usage:  "validate the config and report every error",
+					Flags:  configFlags,
+					Action: checkConfig,
+				},
+			},
+		},
+		{
+			Name:  "audit",
+			Usage: "audit log tools",
+			Subcommands: cli.Commands{
+				{
+					Name:      "verify",
+					Usage:     "verify the hash chain of audit files, oldest first, e.g. audit.log.2 audit.log.1 audit.log",
+					ArgsUsage: "FILE...",
+					Action:    verifyAudit,
+				},
+			},
+		},
+		{
+			Name:  "dashboard",
+			Usage: "print the grafana dashboard of the backend metrics, see dashboards/grpc_client.json",
 			Action: func(c *cli.Context) error {
-				return rest.Start(ctx)
+				out, err := metrics.Dashboard()
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+				return nil
+			},
+		},
+		{
+			Name:  "version",
+			Usage: "print the build info",
+			Flags: []cli.Flag{jsonFlag},
+			Action: func(c *cli.Context) error {
+				if c.Bool("json") {
+					return printJSON(buildinfo.Get())
+				}
+				fmt.Println(buildinfo.Get())
+				return nil
 			},
 		},
 	}
@@ -32,3 +131,165 @@ func main() {
 		logrus.Fatal(err)
 	}
 }
+
+func loadOptions(c *cli.Context) (env.LoadOptions, error) {
+	opts := env.LoadOptions{
+		File:      c.GlobalString("config"),
+		Overrides: map[string]string{},
+	}
+	if file := c.String("config"); file != "" {
+		opts.File = file
+	}
+	for _, set := range append(c.GlobalStringSlice("set"), c.StringSlice("set")...) {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return opts, fmt.Errorf("invalid --set %s, expected KEY=VALUE", set)
+		}
+		opts.Overrides[key] = value
+	}
+	return opts, nil
+}
+
+func setupConfig(c *cli.Context) error {
+	opts, err := loadOptions(c)
+	if err != nil {
+		return err
+	}
+	return env.Setup(opts)
+}
+
+func serve(c *cli.Context) error {
+	if err := setupConfig(c); err != nil {
+		return err
+	}
+	return rest.Start()
+}
+
+func printRoutes(c *cli.Context) error {
+	if err := setupConfig(c); err != nil {
+		return err
+	}
+	rest.NewServer()
+	routes := route.Routes()
+	if c.Bool("json") {
+		return printJSON(map[string]interface{}{"global_middleware": rest.GlobalMiddleware(), "routes": routes})
+	}
+	fmt.Printf("global middleware: %s\n\n", strings.Join(rest.GlobalMiddleware(), " > "))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tHANDLER\tRATE POLICY\tMIDDLEWARE")
+	for _, r := range routes {
+		ratePolicy := strings.Join(r.RatePolicy, "; ")
+		if ratePolicy == "" {
+			ratePolicy = "-"
+		}
+		middleware := strings.Join(r.Middleware, " > ")
+		if middleware == "" {
+			middleware = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, r.Handler, ratePolicy, middleware)
+	}
+	return w.Flush()
+}
+
+func printOpenAPI(c *cli.Context) error {
+	if err := setupConfig(c); err != nil {
+		return err
+	}
+	rest.NewServer()
+	if err := route.CheckOpenAPI(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	return printJSON(route.OpenAPI())
+}
+
+func generateSDK(c *cli.Context) error {
+	if err := setupConfig(c); err != nil {
+		return err
+	}
+	rest.NewServer()
+	if err := route.CheckOpenAPI(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	files, err := sdkgen.Generate(route.OpenAPI())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if c.Bool("check") {
+		if err := sdkgen.Check(c.String("out"), files); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return nil
+	}
+	return sdkgen.Write(c.String("out"), files)
+}
+
+func printConfig(c *cli.Context) error {
+	opts, err := loadOptions(c)
+	if err != nil {
+		return err
+	}
+	cfg, err := env.Load(opts)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if c.Bool("json") {
+		return printJSON(cfg)
+	}
+	// printed as a config file, keys are the lower cased env names
+	values := map[string]interface{}{}
+	for key, value := range cfg.Redact() {
+		values[strings.ToLower(key)] = value
+	}
+	out, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func verifyAudit(c *cli.Context) error {
+	if !c.Args().Present() {
+		return cli.NewExitError("no audit file given", 1)
+	}
+	verifier := &audit.Verifier{}
+	for _, name := range c.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		err = verifier.Verify(name, file)
+		file.Close()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+	result := verifier.Result()
+	if result.Records == 0 {
+		fmt.Println("no audit records")
+		return nil
+	}
+	fmt.Printf("%d records verified, seq %d to %d\n", result.Records, result.First.Seq, result.Last.Seq)
+	if !result.Anchored {
+		fmt.Printf("the chain starts at seq %d, the older files are missing\n", result.First.Seq)
+	}
+	return nil
+}
+
+func checkConfig(c *cli.Context) error {
+	opts, err := loadOptions(c)
+	if err != nil {
+		return err
+	}
+	if _, err = env.Load(opts); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Println("config ok")
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
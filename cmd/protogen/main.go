@@ -0,0 +1,27 @@
+// protogen writes proto/gateway/v1/gateway.proto, the descriptor of the
+// application/x-protobuf responses and of the gRPC-Web and Connect service of the gateway.
+//
+//	go run ./cmd/protogen
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/mises-id/sns-apigateway/app/apis/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	out := flag.String("out", "proto/gateway/v1/gateway.proto", "descriptor file to write")
+	flag.Parse()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer file.Close()
+	if err = rpc.WriteProto(file); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -7,14 +7,28 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/labstack/echo-contrib/prometheus"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	appmw "github.com/mises-id/sns-apigateway/app/middleware"
 	"github.com/mises-id/sns-apigateway/config/env"
 	"github.com/mises-id/sns-apigateway/config/route"
+	"github.com/mises-id/sns-apigateway/lib/audit"
+	"github.com/mises-id/sns-apigateway/lib/binding"
+	"github.com/mises-id/sns-apigateway/lib/buildinfo"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/slowlog"
+	"github.com/mises-id/sns-apigateway/lib/tracing"
+	"github.com/mises-id/sns-apigateway/lib/validation"
+	"github.com/sirupsen/logrus"
 )
 
 func urlSkipper(c echo.Context) bool {
@@ -24,23 +38,102 @@ func urlSkipper(c echo.Context) bool {
 	return false
 }
 
-func Start(ctx context.Context) error {
+// NewServer returns the gateway server with its middleware and routes
+func NewServer() *echo.Echo {
 	e := echo.New()
+	e.Binder = slowlog.Binder{Binder: binding.Binder{Binder: e.Binder}}
+	e.Validator = validation.Default
 
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Skipper: func(c echo.Context) bool { return c.Path() == "/" },
-		Format: `{"timestamp":"${time_rfc3339}","serviceContext":{"service":"mises-sns"},"message":"${remote_ip} ${status} ${method} ${uri}",` +
-			`"severity":"INFO","context":{"request_id":"${id}","remote_ip":"${remote_ip}","host":"${host}","method":"${method}","uri":"${uri}",` +
-			`"user_agent":"${user_agent}","status":"${status}","error":"${error}","latency_human":"${latency_human}","device_id":"${header:mises-device-id}","user_wallet_address":"${header:User-Wallet-Address}"}}` + "\n",
-	}))
+	globalMutex.Lock()
+	global = nil
+	globalMutex.Unlock()
+	use(e,
+		mw.Describe("request_id", middleware.RequestID()),
+		mw.Describe("logger", logging.Middleware(func(c echo.Context) bool { return c.Path() == "/" })),
+		mw.Describe("recover", middleware.Recover()),
+		mw.Describe("cors", middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOriginFunc: allowOrigin,
+			AllowMethods:    []string{http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch},
+			AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "mises-device-id", "User-Wallet-Address",
+				"Connect-Protocol-Version", "Connect-Timeout-Ms", "Grpc-Timeout", "X-Grpc-Web", "X-User-Agent"},
+			ExposeHeaders: []string{"Grpc-Status", "Grpc-Message", "Mises-Code"},
+		})),
+		mw.Describe("body_limit", mw.BodyLimit(env.Envs.MaxBodyBytes, route.OwnBodyLimit)),
+		mw.Describe("in_flight", inFlight.Middleware()),
+		mw.Describe("tracing", tracing.Middleware()),
+		mw.Describe("slow_requests", slowlog.Default.Middleware()),
+	)
+	route.SetRoutes(e)
+	return e
+}
+
+// NewAdminServer returns the admin server, its routes require the ADMIN_TOKEN
+func NewAdminServer() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: strings.Split(env.Envs.AllowOrigins, ","),
-		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "mises-device-id", "User-Wallet-Address"},
-	}))
-	route.SetRoutes(e)
+	route.SetAdminRoutes(e)
+	return e
+}
+
+// inFlight counts the requests of the gateway server for the shutdown
+var inFlight mw.InFlight
+
+var (
+	globalMutex sync.Mutex
+	// global is the middleware of the last gateway server, in call order
+	global []echo.MiddlewareFunc
+)
+
+// use adds m to the middleware e runs before every route, e is the last gateway server
+func use(e *echo.Echo, m ...echo.MiddlewareFunc) {
+	globalMutex.Lock()
+	global = append(global, m...)
+	globalMutex.Unlock()
+	e.Use(m...)
+}
+
+// GlobalMiddleware names the middleware the gateway server runs before every route, in call order
+func GlobalMiddleware() []string {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	names := []string{}
+	for _, m := range global {
+		names = append(names, mw.Description(m))
+	}
+	return names
+}
+
+func Start() error {
+	logging.Setup(env.Envs.LogFormat, "mises-sns")
+	applyLogSettings(env.Current())
+	env.OnReload(applyLogSettings)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if err := env.Watch(watchCtx); err != nil {
+		return err
+	}
+	if err := appmw.SetupFeatureFlags(watchCtx, env.Envs.FlagsFile); err != nil {
+		return err
+	}
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    env.Envs.TracingExporter,
+		Endpoint:    env.Envs.TracingEndpoint,
+		Insecure:    env.Envs.TracingInsecure,
+		SampleRatio: env.Envs.TracingSampleRatio,
+		ServiceName: "sns-apigateway",
+		Version:     buildinfo.Get().Version,
+	})
+	if err != nil {
+		return err
+	}
+	auditLogger, err := openAudit(env.Envs)
+	if err != nil {
+		return err
+	}
+	audit.SetDefault(auditLogger)
+	e := NewServer()
 	/* p := prometheus.NewPrometheus("echo", urlSkipper)
 	p.Use(e) */
 	// Create Prometheus server and Middleware
@@ -49,18 +142,170 @@ func Start(ctx context.Context) error {
 	prom := prometheus.NewPrometheus("echo", nil)
 
 	// Scrape metrics from Main Server
-	e.Use(prom.HandlerFunc)
+	use(e, mw.Describe("prometheus", prom.HandlerFunc))
 	// Setup metrics endpoint at another server
 	prom.SetMetricsPath(echoPrometheus)
 
-	go func() { echoPrometheus.Logger.Fatal(echoPrometheus.Start(":8360")) }()
+	serve, err := listen(watchCtx, e, env.Envs)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := echoPrometheus.Start(fmt.Sprintf(":%d", env.Envs.MetricsPort)); err != nil && err != http.ErrServerClosed {
+			logrus.Fatal(err)
+		}
+	}()
 	go func() {
-		if err := e.Start(fmt.Sprintf(":%d", env.Envs.Port)); err != nil {
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
 	}()
-	quit := make(chan os.Signal)
+	sides := map[string]*echo.Echo{"metrics": echoPrometheus}
+	if env.Envs.AdminPort != 0 {
+		sides["admin"] = NewAdminServer()
+		go func() {
+			if err := sides["admin"].Start(fmt.Sprintf(":%d", env.Envs.AdminPort)); err != nil && err != http.ErrServerClosed {
+				logrus.Fatal(err)
+			}
+		}()
+	}
+	rest.SetReady(true)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-	return e.Shutdown(ctx)
+	sig := <-quit
+	stopWatch()
+	err = shutdown(sig, e, sides)
+	// flush the spans of the last requests
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if tracingErr := stopTracing(ctx); tracingErr != nil {
+		logrus.Errorf("shutdown: tracing: %v", tracingErr)
+	}
+	if auditLogger != nil {
+		audit.SetDefault(nil)
+		if auditErr := auditLogger.Close(); auditErr != nil {
+			logrus.Errorf("shutdown: audit: %v", auditErr)
+		}
+	}
+	return err
+}
+
+// openAudit opens the audit sink of cfg, continuing the chain of the audit file
+func openAudit(cfg *env.Env) (*audit.Logger, error) {
+	switch cfg.AuditSink {
+	case "file":
+		sink, err := audit.NewFileSink(cfg.Path(cfg.AuditFile), cfg.AuditMaxBytes, cfg.AuditMaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		last, err := sink.Last()
+		if err != nil {
+			sink.Close()
+			return nil, err
+		}
+		return audit.New(sink, last), nil
+	case "stdout":
+		return audit.New(audit.WriterSink{Writer: os.Stdout}, nil), nil
+	}
+	return nil, nil
+}
+
+// sideShutdownTimeout is how long the metrics and admin servers wait for their requests
+const sideShutdownTimeout = 5 * time.Second
+
+// shutdown stops the servers in order: unready, drain, stop accepting, wait for the requests
+// in flight, then close the service pools and the side servers, metrics and admin
+func shutdown(sig os.Signal, e *echo.Echo, sides map[string]*echo.Echo) error {
+	cfg := env.Current()
+	started := time.Now()
+	logrus.Infof("shutdown: received %s, %d requests in flight", sig, inFlight.Count())
+
+	rest.SetReady(false)
+	phase := time.Now()
+	time.Sleep(cfg.ShutdownDrain)
+	logrus.Infof("shutdown: unready, drained for %s, %d requests in flight", time.Since(phase), inFlight.Count())
+
+	// the timeout starts once the drain is over, so a long drain cannot eat it
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	phase = time.Now()
+	err := e.Shutdown(ctx)
+	left := inFlight.Wait(ctx)
+	if err != nil || left > 0 {
+		logrus.Errorf("shutdown: %d requests still in flight after %s: %v", left, time.Since(phase), err)
+	} else {
+		logrus.Infof("shutdown: listener closed, requests done in %s", time.Since(phase))
+	}
+
+	phase = time.Now()
+	rest.CloseSvrPool()
+	logrus.Infof("shutdown: service pools closed in %s", time.Since(phase))
+
+	for name, side := range sides {
+		phase = time.Now()
+		// the gateway may have used up ctx, a side server gets its own time to close
+		sideCtx, cancelSide := context.WithTimeout(context.Background(), sideShutdownTimeout)
+		sideErr := side.Shutdown(sideCtx)
+		cancelSide()
+		if sideErr != nil {
+			logrus.Errorf("shutdown: %s server: %v", name, sideErr)
+			if err == nil {
+				err = sideErr
+			}
+			continue
+		}
+		logrus.Infof("shutdown: %s server closed in %s", name, time.Since(phase))
+	}
+	logrus.Infof("shutdown: done in %s", time.Since(started))
+	return err
+}
+
+// allowOrigin checks origin against the live ALLOW_ORIGINS, so it can be reloaded
+func allowOrigin(origin string) (bool, error) {
+	for _, pattern := range originPatterns(env.Current().AllowOrigins) {
+		if pattern.MatchString(origin) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var (
+	originsMutex  sync.Mutex
+	origins       string
+	originRegexps []*regexp.Regexp
+)
+
+// originPatterns compiles the allowed origins like echo does, * and ? are wildcards,
+// e.g. https://*.mises.site, they are compiled again when the setting changes
+func originPatterns(allowOrigins string) []*regexp.Regexp {
+	originsMutex.Lock()
+	defer originsMutex.Unlock()
+	if allowOrigins == origins && originRegexps != nil {
+		return originRegexps
+	}
+	patterns := []*regexp.Regexp{}
+	for _, allowed := range strings.Split(allowOrigins, ",") {
+		pattern := regexp.QuoteMeta(strings.TrimSpace(allowed))
+		pattern = strings.ReplaceAll(pattern, "\\*", ".*")
+		pattern = strings.ReplaceAll(pattern, "\\?", ".")
+		patterns = append(patterns, regexp.MustCompile("(?i)^"+pattern+"$"))
+	}
+	origins, originRegexps = allowOrigins, patterns
+	return patterns
+}
+
+func applyLogSettings(cfg *env.Env) {
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logrus.Errorf("invalid log level %s", cfg.LogLevel)
+		return
+	}
+	logrus.SetLevel(level)
+	logging.SetAccessSampleRatio(cfg.LogAccessSampleRatio)
+	slowlog.Default.SetConfig(slowlog.Config{
+		Threshold:   cfg.SlowRequestThreshold,
+		P95Multiple: cfg.SlowRequestP95Multiple,
+		MinLatency:  cfg.SlowRequestMinLatency,
+	})
 }
@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/certreload"
+	"golang.org/x/net/http2"
+)
+
+// configureServer applies the server limits of cfg to the http and https servers of e
+func configureServer(e *echo.Echo, cfg *env.Env) {
+	for _, s := range []*http.Server{e.Server, e.TLSServer} {
+		s.ReadTimeout = cfg.ReadTimeout
+		s.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+		s.WriteTimeout = cfg.WriteTimeout
+		s.IdleTimeout = cfg.IdleTimeout
+		s.MaxHeaderBytes = cfg.MaxHeaderBytes
+	}
+}
+
+// listen opens the listener of cfg, the unix socket or the port, and returns the func serving e on it.
+// The tls key pair is watched until ctx is done.
+func listen(ctx context.Context, e *echo.Echo, cfg *env.Env) (func() error, error) {
+	configureServer(e, cfg)
+	address := fmt.Sprintf(":%d", cfg.Port)
+	var (
+		listener net.Listener
+		err      error
+	)
+	if cfg.UnixSocket != "" {
+		address = cfg.Path(cfg.UnixSocket)
+		// a socket left by a killed process would make the listen fail
+		if err = os.Remove(address); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		listener, err = net.Listen("unix", address)
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cfg.TLSCertFile != "":
+		reloader, err := certreload.New(cfg.Path(cfg.TLSCertFile), cfg.Path(cfg.TLSKeyFile))
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		if err = reloader.Watch(ctx); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		e.TLSServer.TLSConfig = reloader.Config()
+		e.TLSListener = tls.NewListener(listener, e.TLSServer.TLSConfig)
+		return func() error { return e.StartServer(e.TLSServer) }, nil
+	case cfg.H2C:
+		e.Listener = listener
+		return func() error {
+			return e.StartH2CServer(address, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+		}, nil
+	default:
+		e.Listener = listener
+		return func() error { return e.StartServer(e.Server) }, nil
+	}
+}
@@ -4,53 +4,120 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 	"time"
-
-	"github.com/caarlos0/env"
-	"github.com/joho/godotenv"
 )
 
 var Envs *Env
 
+// Env is the gateway configuration, every field is loaded from its envDefault, then from the
+// config file key (the lower cased env name), then from the env variable, then from the command line.
+// Fields tagged reload can be changed in the config file without a restart, fields tagged secret are
+// redacted when printed and can be read from a file named by <NAME>_FILE or from the secret provider.
 type Env struct {
-	Port            int           `env:"PORT" envDefault:"8080"`
-	AppEnv          string        `env:"APP_ENV" envDefault:"development"`
-	LogLevel        string        `env:"LOG_LEVEL" envDefault:"INFO"`
-	AssetHost       string        `env:"ASSET_HOST" envDefault:"http://localhost/"`
+	Port            int           `env:"PORT" envDefault:"8080" validate:"min=1,max=65535"`
+	AppEnv          string        `env:"APP_ENV" envDefault:"development" validate:"required"`
+	LogLevel        string        `env:"LOG_LEVEL" envDefault:"INFO" validate:"loglevel" reload:"true"`
+	AssetHost       string        `env:"ASSET_HOST" envDefault:"http://localhost/" validate:"url"`
 	StorageProvider string        `env:"STORAGE_PROVIDER" envDefault:"local"`
-	JWTSecret       string        `env:"JWT_SECRET" envDefault:"jwt secret"`
-	TokenDuration   time.Duration `env:"TOKEN_DURATION" envDefault:"24h"`
-	AllowOrigins    string        `env:"ALLOW_ORIGINS" envDefault:"*"`
+	JWTSecret       string        `env:"JWT_SECRET" envDefault:"jwt secret" validate:"required" secret:"key"`
+	TokenDuration   time.Duration `env:"TOKEN_DURATION" envDefault:"24h" validate:"gt=0"`
+	AllowOrigins    string        `env:"ALLOW_ORIGINS" envDefault:"*" validate:"required" reload:"true"`
 	LocalFilePath   string        `env:"LocalFilePath" envDefault:"/tmp/sns-apigateway/"`
-	MisesNodes      string        `env:"MISES_NODES" envDefault:"https://e1.mises.site:443,https://e2.mises.site:443,https://w1.mises.site:443,https://w2.mises.site:443"`
-	RootPath        string
+	MisesNodes      string        `env:"MISES_NODES" envDefault:"https://e1.mises.site:443,https://e2.mises.site:443,https://w1.mises.site:443,https://w2.mises.site:443" validate:"urls" reload:"true"`
+	CacheProvider   string        `env:"CACHE_PROVIDER" envDefault:"memory" validate:"oneof=memory redis"`
+	CacheSize       int           `env:"CACHE_SIZE" envDefault:"10000" validate:"min=1"`
+	RedisURI        string        `env:"REDIS_URI" envDefault:"redis://localhost:6379/0" validate:"omitempty,url" secret:"true"`
+	// requests per second and client of the rate limited public routes
+	RateLimitUser   float64 `env:"RATE_LIMIT_USER" envDefault:"4" validate:"gt=0" reload:"true"`
+	RateLimitExport float64 `env:"RATE_LIMIT_EXPORT" envDefault:"1" validate:"gt=0" reload:"true"`
+	// FlagsFile is the yaml file of feature flags, it is watched for changes
+	FlagsFile string `env:"FLAGS_FILE"`
+	// SecretProvider resolves the secret:<ref> values, dir reads <SecretDir>/<ref>
+	SecretProvider string `env:"SECRET_PROVIDER" envDefault:"dir"`
+	SecretDir      string `env:"SECRET_DIR" envDefault:"/run/secrets"`
+	// ShutdownDrain is how long a stopping server stays up unready, so load balancers stop sending it
+	// requests, before it closes its listener and waits up to ShutdownTimeout for the requests in flight
+	ShutdownDrain   time.Duration `env:"SHUTDOWN_DRAIN" envDefault:"5s" validate:"min=0"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"60s" validate:"gt=0"`
+	// http server limits, a zero timeout disables it, the write timeout bounds the streamed exports
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT" envDefault:"30s" validate:"min=0"`
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"10s" validate:"min=0"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" envDefault:"5m" validate:"min=0"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT" envDefault:"120s" validate:"min=0"`
+	MaxHeaderBytes    int           `env:"MAX_HEADER_BYTES" envDefault:"1048576" validate:"min=4096"`
+	// TLSCertFile and TLSKeyFile terminate tls with http/2, the files are watched for renewals
+	TLSCertFile string `env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+	// H2C serves cleartext http/2 to internal callers, it cannot be combined with tls
+	H2C bool `env:"H2C" envDefault:"false"`
+	// UnixSocket is a socket path to listen on instead of PORT
+	UnixSocket  string `env:"UNIX_SOCKET"`
+	MetricsPort int    `env:"METRICS_PORT" envDefault:"8360" validate:"min=1,max=65535"`
+	// AdminPort serves pprof, runtime stats and operational actions to callers bearing AdminToken, 0 disables it
+	AdminPort  int    `env:"ADMIN_PORT" envDefault:"0" validate:"min=0,max=65535"`
+	AdminToken string `env:"ADMIN_TOKEN" secret:"key"`
+	// TracingExporter sends the spans to the otlp collector at TracingEndpoint, to stdout, or nowhere
+	TracingExporter    string  `env:"TRACING_EXPORTER" envDefault:"none" validate:"oneof=none stdout otlp"`
+	TracingEndpoint    string  `env:"TRACING_ENDPOINT"`
+	TracingInsecure    bool    `env:"TRACING_INSECURE" envDefault:"false"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1" validate:"min=0,max=1"`
+	// LogFormat is json or text, LogAccessSampleRatio is the share of the 2xx access lines written,
+	// the other statuses are always logged
+	LogFormat            string  `env:"LOG_FORMAT" envDefault:"json" validate:"oneof=json text"`
+	LogAccessSampleRatio float64 `env:"LOG_ACCESS_SAMPLE_RATIO" envDefault:"1" validate:"min=0,max=1" reload:"true"`
+	// a request slower than SlowRequestThreshold, or SlowRequestP95Multiple times the p95 of its route
+	// and at least SlowRequestMinLatency, is logged with its breakdown, zero disables a rule
+	SlowRequestThreshold   time.Duration `env:"SLOW_REQUEST_THRESHOLD" envDefault:"2s" validate:"min=0" reload:"true"`
+	SlowRequestP95Multiple float64       `env:"SLOW_REQUEST_P95_MULTIPLE" envDefault:"3" validate:"min=0" reload:"true"`
+	SlowRequestMinLatency  time.Duration `env:"SLOW_REQUEST_MIN_LATENCY" envDefault:"100ms" validate:"min=0" reload:"true"`
+	// AuditSink is where the hash chained audit records go, none until it is set, AuditFile is
+	// rotated at AuditMaxBytes
+	AuditSink       string `env:"AUDIT_SINK" envDefault:"none" validate:"oneof=none file stdout"`
+	AuditFile       string `env:"AUDIT_FILE" envDefault:"audit/audit.log"`
+	AuditMaxBytes   int64  `env:"AUDIT_MAX_BYTES" envDefault:"104857600" validate:"min=0"`
+	AuditMaxBackups int    `env:"AUDIT_MAX_BACKUPS" envDefault:"10" validate:"min=0"`
+	// RootPath is the directory relative paths are resolved from, the working directory by default
+	RootPath string `env:"ROOT_PATH"`
+	// MaxBodyBytes caps the request bodies of the routes without their own body limit, 0 disables it
+	MaxBodyBytes int64 `env:"MAX_BODY_BYTES" envDefault:"1048576" validate:"min=0"`
+	// the graphql endpoint rejects the batches of more than GraphQLMaxBatch operations and the queries
+	// nested deeper than GraphQLMaxDepth or costing more than GraphQLMaxComplexity, a zero disables the
+	// limit, a list field without a limit argument costs GraphQLListSize times its items
+	GraphQLMaxDepth      int  `env:"GRAPHQL_MAX_DEPTH" envDefault:"8" validate:"min=0" reload:"true"`
+	GraphQLMaxComplexity int  `env:"GRAPHQL_MAX_COMPLEXITY" envDefault:"5000" validate:"min=0" reload:"true"`
+	GraphQLListSize      int  `env:"GRAPHQL_LIST_SIZE" envDefault:"20" validate:"min=1" reload:"true"`
+	GraphQLMaxBatch      int  `env:"GRAPHQL_MAX_BATCH" envDefault:"10" validate:"min=1" reload:"true"`
+	GraphQLIntrospection bool `env:"GRAPHQL_INTROSPECTION" envDefault:"true" reload:"true"`
+	// GraphQLPersistedQueryTTL is how long a persisted query is kept after its last registration
+	GraphQLPersistedQueryTTL time.Duration `env:"GRAPHQL_PERSISTED_QUERY_TTL" envDefault:"24h" validate:"gt=0" reload:"true"`
+	// /api/v1/batch takes at most BatchMaxRequests sub requests, serves BatchConcurrency of them at once
+	// and keeps at most BatchMaxResponseBytes of the body of each
+	BatchMaxRequests      int `env:"BATCH_MAX_REQUESTS" envDefault:"20" validate:"min=1"`
+	BatchConcurrency      int `env:"BATCH_CONCURRENCY" envDefault:"4" validate:"min=1"`
+	BatchMaxResponseBytes int `env:"BATCH_MAX_RESPONSE_BYTES" envDefault:"1048576" validate:"min=1"`
 }
 
 func init() {
 	fmt.Println("apigateway env initializing...")
-	_, b, _, _ := runtime.Caller(0)
-	appEnv := os.Getenv("APP_ENV")
-	projectRootPath := filepath.Dir(b) + "/../../"
-	envPath := projectRootPath + ".env"
-	appEnvPath := envPath + "." + appEnv
-	localEnvPath := appEnvPath + ".local"
-	_ = godotenv.Load(filtePath(localEnvPath, appEnvPath, envPath)...)
-	Envs = &Env{}
-	err := env.Parse(Envs)
+	loadDotenv()
+	// the command line loads the config again with its flags and refuses to serve an invalid one,
+	// until then the packages initialize with what could be loaded
+	cfg, err := Load(LoadOptions{File: os.Getenv(ConfigFileEnv)})
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+	}
+	if cfg == nil {
+		cfg, _ = Load(LoadOptions{})
 	}
-	Envs.RootPath = projectRootPath
+	Envs = cfg
+	current.Store(cfg)
 	fmt.Println("apigateway env loaded...")
 }
 
-func filtePath(paths ...string) []string {
-	result := make([]string, 0)
-	for _, path := range paths {
-		if _, err := os.Stat(path); err == nil {
-			result = append(result, path)
-		}
+// Path resolves p from RootPath unless it is absolute
+func (e *Env) Path(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
 	}
-	return result
+	return filepath.Join(e.RootPath, p)
 }
@@ -0,0 +1,242 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the config file when no --config flag is given
+const ConfigFileEnv = "CONFIG_FILE"
+
+// LoadOptions are the layers above the defaults and the env variables
+type LoadOptions struct {
+	// File is a .yaml, .yml or .toml config file, optional
+	File string
+	// Overrides come from the command line and are keyed by env name, e.g. PORT=8081
+	Overrides map[string]string
+}
+
+var (
+	optionsMutex sync.Mutex
+	options      LoadOptions
+)
+
+// Setup loads and validates the config, makes it the current one and remembers opts for reloads
+func Setup(opts LoadOptions) error {
+	cfg, err := Load(opts)
+	if err != nil {
+		return err
+	}
+	optionsMutex.Lock()
+	options = opts
+	optionsMutex.Unlock()
+	Envs = cfg
+	current.Store(cfg)
+	return nil
+}
+
+// Load builds the config from the defaults, opts.File, the env variables and opts.Overrides,
+// in this order, and returns every validation error at once along with the config as far as it was loaded
+func Load(opts LoadOptions) (*Env, error) {
+	cfg := &Env{}
+	errs := Errors{}
+	fileValues := map[string]string{}
+	if opts.File != "" {
+		values, err := readFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = values
+	}
+	known, invalid := map[string]bool{}, map[string]bool{}
+	t := reflect.TypeOf(cfg).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		known[strings.ToLower(key)], known[strings.ToLower(key+FileSuffix)] = true, true
+		value, source := field.Tag.Get("envDefault"), "default"
+		layers := []struct {
+			source string
+			lookup func(key string) (string, bool)
+		}{
+			{opts.File, func(key string) (string, bool) {
+				value, found := fileValues[strings.ToLower(key)]
+				return value, found
+			}},
+			{"env", os.LookupEnv},
+			{"flag", func(key string) (string, bool) {
+				value, found := opts.Overrides[key]
+				return value, found
+			}},
+		}
+		for _, layer := range layers {
+			layerValue, found := layer.lookup(key)
+			path, fromFile := layer.lookup(key + FileSuffix)
+			if found && fromFile {
+				errs = append(errs, fmt.Sprintf("%s: set only one of %s and %s in %s", key, key, key+FileSuffix, layer.source))
+				invalid[key] = true
+				continue
+			}
+			if fromFile {
+				secret, err := readSecretFile(path)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %s", key+FileSuffix, err))
+					invalid[key] = true
+					continue
+				}
+				layerValue, found = secret, true
+			}
+			if found {
+				value, source = layerValue, layer.source
+			}
+		}
+		if value == "" || invalid[key] {
+			continue
+		}
+		if err := setField(reflect.ValueOf(cfg).Elem().Field(i), value); err != nil {
+			if isSecret(field) {
+				value = Redacted
+			}
+			errs = append(errs, fmt.Sprintf("%s: invalid value %q from %s: %s", key, value, source, err))
+			invalid[key] = true
+		}
+	}
+	for key := range fileValues {
+		if !known[key] {
+			errs = append(errs, fmt.Sprintf("%s: unknown key in %s", key, opts.File))
+		}
+	}
+	for key := range opts.Overrides {
+		if !known[strings.ToLower(key)] {
+			errs = append(errs, fmt.Sprintf("%s: unknown setting", key))
+		}
+	}
+	if cfg.RootPath == "" {
+		cfg.RootPath, _ = os.Getwd()
+	}
+	errs = append(errs, resolveSecrets(cfg)...)
+	errs = append(errs, validate(cfg, invalid)...)
+	errs = append(errs, weakSecretErrors(cfg)...)
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return cfg, errs
+	}
+	return cfg, nil
+}
+
+// Errors aggregates every config error found while loading
+type Errors []string
+
+func (errs Errors) Error() string {
+	return "invalid config:\n  " + strings.Join(errs, "\n  ")
+}
+
+// loadDotenv loads the .env files of the working directory into the env variables,
+// variables already set win over the files
+func loadDotenv() {
+	appEnv := os.Getenv("APP_ENV")
+	paths := []string{".env." + appEnv + ".local", ".env." + appEnv, ".env"}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := godotenv.Load(path); err != nil {
+			fmt.Printf("failed to load %s: %s\n", path, err)
+			continue
+		}
+		fmt.Printf("loaded %s\n", path)
+	}
+}
+
+// readFile flattens a config file into lower cased keys and string values,
+// lists are joined with commas
+func readFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &values)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(raw)).Decode(&values)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		result[strings.ToLower(key)] = formatValue(value)
+	}
+	return result, nil
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatValue(item)
+		}
+		return strings.Join(items, ",")
+	case time.Duration:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
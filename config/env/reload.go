@@ -0,0 +1,87 @@
+package env
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mises-id/sns-apigateway/lib/filewatch"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	current         atomic.Pointer[Env]
+	listenersMutex  sync.Mutex
+	reloadListeners []func(*Env)
+)
+
+// Current returns the live config, read it instead of Envs for the fields tagged reload
+func Current() *Env {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return Envs
+}
+
+// OnReload registers fn to be called with the new config after every applied reload
+func OnReload(fn func(*Env)) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	reloadListeners = append(reloadListeners, fn)
+}
+
+// Reload loads the config again and applies the changed fields tagged reload,
+// changes of the other fields are reported and wait for a restart
+func Reload() error {
+	optionsMutex.Lock()
+	opts := options
+	optionsMutex.Unlock()
+	loaded, err := Load(opts)
+	if err != nil {
+		return err
+	}
+	old := Current()
+	next := *old
+	changed := false
+	oldValue, loadedValue, nextValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(loaded).Elem(), reflect.ValueOf(&next).Elem()
+	for i := 0; i < nextValue.NumField(); i++ {
+		field := nextValue.Type().Field(i)
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), loadedValue.Field(i).Interface()) {
+			continue
+		}
+		if field.Tag.Get("reload") != "true" {
+			logrus.Warnf("config %s changed, restart to apply it", field.Tag.Get("env"))
+			continue
+		}
+		nextValue.Field(i).Set(loadedValue.Field(i))
+		changed = true
+		logrus.Infof("config %s reloaded", field.Tag.Get("env"))
+	}
+	if !changed {
+		return nil
+	}
+	current.Store(&next)
+	listenersMutex.Lock()
+	listeners := append([]func(*Env){}, reloadListeners...)
+	listenersMutex.Unlock()
+	for _, fn := range listeners {
+		fn(&next)
+	}
+	return nil
+}
+
+// Watch reloads the config whenever the config file changes, until ctx is done
+func Watch(ctx context.Context) error {
+	optionsMutex.Lock()
+	file := options.File
+	optionsMutex.Unlock()
+	if file == "" {
+		return nil
+	}
+	return filewatch.Watch(ctx, file, func() {
+		if err := Reload(); err != nil {
+			logrus.Errorf("config reload rejected: %v", err)
+		}
+	})
+}
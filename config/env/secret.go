@@ -0,0 +1,171 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// FileSuffix names the env variable or config key holding the path of a file with the value, e.g. JWT_SECRET_FILE
+	FileSuffix = "_FILE"
+	// SecretRefPrefix marks a value resolved by the secret provider, e.g. JWT_SECRET=secret:apigateway/jwt
+	SecretRefPrefix = "secret:"
+	// Redacted replaces secret values wherever the config is printed
+	Redacted = "[REDACTED]"
+
+	minSecretLength = 32
+)
+
+// SecretProvider resolves secret references, providers register themselves with RegisterSecretProvider
+// and are selected with SECRET_PROVIDER
+type SecretProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretProviderFunc builds a provider from the loaded config
+type SecretProviderFunc func(cfg *Env) (SecretProvider, error)
+
+var (
+	secretProvidersMutex sync.Mutex
+	secretProviders      = map[string]SecretProviderFunc{
+		"dir": func(cfg *Env) (SecretProvider, error) {
+			return dirSecretProvider(cfg.SecretDir), nil
+		},
+	}
+	weakSecrets = map[string]bool{
+		"jwt secret": true,
+		"secret":     true,
+		"changeme":   true,
+		"password":   true,
+	}
+)
+
+// RegisterSecretProvider makes a provider available under name
+func RegisterSecretProvider(name string, fn SecretProviderFunc) {
+	secretProvidersMutex.Lock()
+	defer secretProvidersMutex.Unlock()
+	secretProviders[name] = fn
+}
+
+// dirSecretProvider reads each secret from the file named by its ref, as mounted by docker or kubernetes secrets
+type dirSecretProvider string
+
+func (dir dirSecretProvider) Secret(ctx context.Context, ref string) (string, error) {
+	path := filepath.Join(string(dir), filepath.Clean("/"+ref))
+	return readSecretFile(path)
+}
+
+func readSecretFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
+
+// isSecret reports whether the field is redacted, key secrets are also checked for strength
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") != ""
+}
+
+// resolveSecrets replaces the secret references of cfg with their value from the configured provider
+func resolveSecrets(cfg *Env) Errors {
+	v := reflect.ValueOf(cfg).Elem()
+	var provider SecretProvider
+	errs := Errors{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if !isSecret(field) || value.Kind() != reflect.String || !strings.HasPrefix(value.String(), SecretRefPrefix) {
+			continue
+		}
+		key := field.Tag.Get("env")
+		if provider == nil {
+			secretProvidersMutex.Lock()
+			fn, ok := secretProviders[cfg.SecretProvider]
+			secretProvidersMutex.Unlock()
+			if !ok {
+				return append(errs, fmt.Sprintf("%s: unknown SECRET_PROVIDER %q", key, cfg.SecretProvider))
+			}
+			var err error
+			if provider, err = fn(cfg); err != nil {
+				return append(errs, fmt.Sprintf("SECRET_PROVIDER: %s", err))
+			}
+		}
+		secret, err := provider.Secret(context.Background(), strings.TrimPrefix(value.String(), SecretRefPrefix))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: resolve secret: %s", key, err))
+			continue
+		}
+		value.SetString(secret)
+	}
+	return errs
+}
+
+// weakSecretErrors refuses default, short and well known key secrets in production
+func weakSecretErrors(cfg *Env) Errors {
+	if cfg.AppEnv != "production" {
+		return nil
+	}
+	errs := Errors{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("secret") != "key" {
+			continue
+		}
+		secret := v.Field(i).String()
+		key := field.Tag.Get("env")
+		switch {
+		case secret == "" && field.Tag.Get("envDefault") == "":
+			// an optional secret left unset, the feature needing it is off
+		case secret == field.Tag.Get("envDefault"):
+			errs = append(errs, fmt.Sprintf("%s: the default secret is not allowed in production", key))
+		case weakSecrets[strings.ToLower(secret)] || len(secret) < minSecretLength:
+			errs = append(errs, fmt.Sprintf("%s: weak secret, use at least %d random characters in production", key, minSecretLength))
+		}
+	}
+	return errs
+}
+
+// Redact returns the config keyed by env name with the secret values replaced
+func (e Env) Redact() map[string]interface{} {
+	result := map[string]interface{}{}
+	v := reflect.ValueOf(e)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if isSecret(field) && !v.Field(i).IsZero() {
+			value = Redacted
+		} else if d, ok := value.(fmt.Stringer); ok {
+			value = d.String()
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// MarshalJSON keeps secrets out of config dumps
+func (e Env) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Redact())
+}
+
+// String keeps secrets out of logs
+func (e Env) String() string {
+	return fmt.Sprint(e.Redact())
+}
+
+// GoString keeps secrets out of %#v
+func (e Env) GoString() string {
+	return e.String()
+}
@@ -0,0 +1,99 @@
+package env
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	configValidator = newValidator()
+	// secretKeys are the env names of the fields tagged secret
+	secretKeys = map[string]bool{}
+)
+
+func init() {
+	t := reflect.TypeOf(Env{})
+	for i := 0; i < t.NumField(); i++ {
+		if isSecret(t.Field(i)) {
+			secretKeys[t.Field(i).Tag.Get("env")] = true
+		}
+	}
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+	_ = v.RegisterValidation("loglevel", func(fl validator.FieldLevel) bool {
+		_, err := logrus.ParseLevel(fl.Field().String())
+		return err == nil
+	})
+	// urls is a comma separated list of absolute urls
+	_ = v.RegisterValidation("urls", func(fl validator.FieldLevel) bool {
+		for _, raw := range strings.Split(fl.Field().String(), ",") {
+			u, err := url.Parse(strings.TrimSpace(raw))
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return false
+			}
+		}
+		return true
+	})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		cfg := sl.Current().Interface().(Env)
+		if cfg.CacheProvider == "redis" && cfg.RedisURI == "" {
+			sl.ReportError(cfg.RedisURI, "REDIS_URI", "RedisURI", "required_with_redis", "")
+		}
+		if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+			sl.ReportError(cfg.TLSKeyFile, "TLS_KEY_FILE", "TLSKeyFile", "required_with_tls_cert_file", "")
+		}
+		if cfg.H2C && cfg.TLSCertFile != "" {
+			sl.ReportError(cfg.H2C, "H2C", "H2C", "excluded_with_tls", "")
+		}
+		if cfg.UnixSocket == "" && cfg.MetricsPort == cfg.Port {
+			sl.ReportError(cfg.MetricsPort, "METRICS_PORT", "MetricsPort", "ne_port", "")
+		}
+		if cfg.AdminPort != 0 {
+			if cfg.AdminToken == "" {
+				sl.ReportError(cfg.AdminToken, "ADMIN_TOKEN", "AdminToken", "required_with_admin_port", "")
+			}
+			if cfg.AdminPort == cfg.MetricsPort || (cfg.UnixSocket == "" && cfg.AdminPort == cfg.Port) {
+				sl.ReportError(cfg.AdminPort, "ADMIN_PORT", "AdminPort", "ne_port", "")
+			}
+		}
+	}, Env{})
+	return v
+}
+
+// validate returns one message per invalid field, skipping the fields that already failed to parse
+func validate(cfg *Env, skip map[string]bool) Errors {
+	err := configValidator.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return Errors{err.Error()}
+	}
+	errs := make(Errors, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		if skip[fieldError.Field()] {
+			continue
+		}
+		rule := fieldError.Tag()
+		if fieldError.Param() != "" {
+			rule += "=" + fieldError.Param()
+		}
+		value := fieldError.Value()
+		if secretKeys[fieldError.Field()] {
+			value = Redacted
+		}
+		errs = append(errs, fmt.Sprintf("%s: value %v does not satisfy %s", fieldError.Field(), value, rule))
+	}
+	return errs
+}
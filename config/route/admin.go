@@ -0,0 +1,33 @@
+package route
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/app/apis/rest/admin"
+	appmw "github.com/mises-id/sns-apigateway/app/middleware"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+)
+
+// SetAdminRoutes sets the routes of the admin server, every route requires the admin token
+func SetAdminRoutes(e *echo.Echo) {
+	e.Use(mw.ErrorResponseMiddleware, appmw.AdminAuthMiddleware)
+	e.GET("/admin/stats", admin.Stats)
+	e.GET("/admin/goroutines", admin.Goroutines)
+	e.GET("/admin/config", admin.Config)
+	e.GET("/admin/pools", admin.Pools)
+	e.GET("/admin/slow_requests", admin.SlowRequests)
+	e.POST("/admin/store/flush", admin.FlushStore)
+	e.POST("/admin/cache/flush", admin.FlushCache)
+	e.POST("/admin/rate_limit/reset", admin.ResetRateLimit)
+	e.PUT("/admin/log_level", admin.SetLogLevel)
+
+	// pprof, the index serves the named profiles, e.g. /debug/pprof/heap
+	e.GET("/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	e.GET("/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	e.GET("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	e.POST("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	e.GET("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	e.GET("/debug/pprof/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+}
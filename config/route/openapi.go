@@ -0,0 +1,292 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	swapsvc "github.com/mises-id/mises-swapsvc/proto"
+	"github.com/mises-id/sns-apigateway/app/apis/rest"
+	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/lib/batch"
+	"github.com/mises-id/sns-apigateway/lib/buildinfo"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	gql "github.com/mises-id/sns-apigateway/lib/graphql"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+)
+
+const (
+	// OpenAPIPath serves the openapi document of the routes, /api/v1/docs its documentation page
+	OpenAPIPath = "/api/v1/openapi.json"
+	docsTitle   = "Mises API gateway"
+)
+
+// pathParams types the path params which are not strings
+type pathParams struct {
+	UID uint64 `param:"uid"`
+}
+
+// anyValue documents the payloads relayed from a backend as is
+var anyValue = json.RawMessage{}
+
+func object(properties map[string]*openapi.Schema) *openapi.Schema {
+	return &openapi.Schema{Type: "object", Properties: properties}
+}
+
+var (
+	stringSchema  = &openapi.Schema{Type: "string"}
+	integerSchema = &openapi.Schema{Type: "integer"}
+	urlSchema     = object(map[string]*openapi.Schema{"url": stringSchema})
+)
+
+// operations documents the handler of every route, keyed by the handler name of the route table.
+// The route, the auth and the rate limits are read from the route table, an operation only
+// gives the params its handler binds and the data it responds.
+var operations = map[string]openapi.Endpoint{
+	"rest.Probe":    {Summary: "Liveness probe"},
+	"rest.Ready":    {Summary: "Readiness probe, unavailable while the server starts or drains"},
+	"v1.SwapHealth": {Summary: "Health of the swap backend"},
+
+	"openapi.SpecHandler.func1": {OperationID: "GetOpenAPI", Summary: "This OpenAPI document", Tags: []string{"docs"}, ContentType: "application/json"},
+	"openapi.DocsHandler.func1": {OperationID: "GetDocs", Summary: "Documentation page of the OpenAPI document", Tags: []string{"docs"}, ContentType: "text/html"},
+	"graphql.Serve": {OperationID: "GraphQL", Summary: "GraphQL operations on the data of the home screen, a json array batches them",
+		Tags: []string{"graphql"}, Request: gql.Request{}, ContentType: "application/json"},
+	"v1.Batch.func1": {OperationID: "Batch", Summary: "Serves sub requests in one round trip, each answered with its status and body",
+		Tags: []string{"batch"}, Request: v1.BatchParams{}, Response: []batch.Response{}},
+	"connect.Handler.func1": {OperationID: "GatewayRPC", Summary: "Unary methods of the mises.gateway.v1.Gateway service over gRPC-Web and Connect, see proto/gateway/v1/gateway.proto",
+		Tags: []string{"rpc"}, ContentType: "application/proto"},
+
+	// user
+	"v1.SignIn": {Request: v1.SignInParams{}, Response: object(map[string]*openapi.Schema{
+		"token": stringSchema, "is_created": {Type: "boolean"},
+	}), Errors: []codes.Code{codes.ErrAuthorizeFailed}},
+	"v1.FindUser":         {Response: v1.UserRestrictedResp{}},
+	"v1.FindMisesUser":    {Summary: "Find user by mises id", Response: v1.UserRestrictedResp{}},
+	"v1.MyProfile":        {Response: v1.UserFullResp{}},
+	"v1.UpdateUser":       {Request: v1.UserUpdateParams{}, Response: v1.UserFullResp{}, Errors: []codes.Code{codes.ErrUsernameExisted}},
+	"v1.GetUserConfig":    {Response: v1.UserConfig{}},
+	"v1.UpdateUserConfig": {Request: v1.UpdateUserConfigParams{}, Response: v1.UserConfig{}},
+	"v1.Complaint":        {Request: v1.ComplaintParams{}},
+	"v1.UploadFile":       {Request: v1.UploadInput{}, Files: []string{"file"}, Response: anyValue},
+	"v1.ShareTweetUrl":    {Summary: "Share tweet url", Response: urlSchema},
+	"v1.TwitterAuthUrl":   {Summary: "Twitter auth url", Response: urlSchema},
+	"v1.TwitterCallback":  {Request: v1.TwitterCallbackParam{}, Redirect: true},
+	"v1.ListUserLike":     {Request: v1.ListUserLikeParams{}, Response: []*v1.UserLikeResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.ExportUserLike":   {Summary: "Export the statuses liked by a user", Export: v1.UserLikeResp{}},
+	"v1.RecommendUser":    {Response: []*v1.FollowingResp{}},
+	"v1.ListFriendship":   {Request: v1.ListFriendshipParams{}, Response: []*v1.FriendshipResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.Follow":           {Request: v1.FollowParams{}},
+	"v1.Unfollow":         {Request: v1.FollowParams{}},
+	"v1.LatestFollowing":  {Response: []*v1.FollowingResp{}},
+	"v1.ListBlacklist":    {Request: v1.ListBlacklistParams{}, Response: []*v1.BlackResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.CreateBlacklist":  {Request: v1.BlacklistParams{}},
+	"v1.DeleteBlacklist":  {},
+
+	// channel and airdrop
+	"v1.GetChannelUser":  {Response: v1.GetChannelUserOutput{}},
+	"v1.PageChannelUser": {Request: v1.PageChannelUserInput{}, Response: []*v1.PageChannelUserOutput{}, Pagination: rest.PageParams{}},
+	"v1.ChannelInfo": {Request: v1.ChannelUrlRequest{}, Response: object(map[string]*openapi.Schema{
+		"url": stringSchema, "total_channel_user": integerSchema, "airdrop_amount": {},
+		"medium_url": stringSchema, "ios_link": stringSchema, "ios_medium_link": stringSchema,
+	})},
+	"v1.AirdropInfo":    {Response: v1.AirdropInfoResp{}},
+	"v1.ReceiveAirdrop": {Request: v1.ReceiveAirdropParams{}},
+
+	// opensea, the assets are relayed as the json string opensea returned
+	"v1.GetOpenseaAsset":         {Request: v1.OpenseaSingleAssetInput{}, ContentType: "text/plain"},
+	"v1.GetOpenseaAssetContract": {Request: v1.OpenseaSingleAssetInput{}, ContentType: "text/plain"},
+	"v1.ListOpenseaAsset":        {Request: v1.ListOpenseaAssetInput{}, ContentType: "text/plain"},
+
+	// website
+	"v1.ListWebsiteCategory":    {Request: v1.ListWebsiteCategoryParams{}, Response: []*v1.WebsiteCategoryResp{}},
+	"v1.PageWebsite":            {Request: v1.WebsiteParams{}, Response: []*v1.WebsiteResp{}, Pagination: rest.PageParams{}},
+	"v1.SearchWebsite":          {Request: v1.WebsiteSearchParams{}, Response: []*v1.WebsiteResp{}},
+	"v1.WebsiteInternalSearch":  {Request: v1.WebsiteInternalSearchParams{}, Response: []*v1.WebsiteInternalSearchResp{}},
+	"v1.ListExtensionsCategory": {Request: v1.ListWebsiteCategoryParams{}, Response: []*v1.WebsiteCategoryResp{}},
+	"v1.PageExtensions":         {Request: v1.WebsiteParams{}, Response: []*v1.WebsiteResp{}, Pagination: rest.PageParams{}},
+	"v1.PhishingCheck":          {Request: v1.PhishingSiteParams{}, Response: v1.PhishingSiteResp{}},
+	"v1.VerifyContract":         {Request: v1.VerifyContractParams{}, Response: v1.VerifyContractResp{}},
+
+	// news
+	"v1.ListNews":       {Request: v1.ListNewsParams{}, Response: v1.ListNewsResponse{}},
+	"v1.GetNews":        {Response: v1.News{}},
+	"v1.ListStrategies": {Request: v1.ListStrategiesParams{}, Response: v1.ListStrategiesResponse{}},
+
+	// status
+	"v1.ListUserStatus":   {Request: v1.ListUserStatusParams{}, Response: []*v1.StatusResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.ExportUserStatus": {Summary: "Export the statuses of a user", Export: v1.StatusResp{}},
+	"v1.RecommendStatus":  {Request: v1.RecommendStatusParams{}, Response: []*v1.StatusResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.ListStatus":       {Request: v1.ListStatusParams{}, Response: []*v1.StatusResp{}},
+	"v1.RecentStatus":     {Request: v1.RecentStatusParams{}, Response: []*v1.StatusResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.Timeline":         {Request: v1.ListUserStatusParams{}, Response: []*v1.StatusResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.CreateStatus":     {Request: v1.CreateStatusParams{}, Response: v1.StatusResp{}},
+	"v1.UpdateStatus":     {Request: v1.CreateStatusParams{}, Response: v1.StatusResp{}},
+	"v1.GetStatus":        {Response: v1.StatusResp{}},
+	"v1.DeleteStatus":     {},
+	"v1.LikeStatus":       {},
+	"v1.UnlikeStatus":     {},
+
+	// nft
+	"v1.GetNftAsset":      {Response: v1.NftAssetResp{}},
+	"v1.PageNftEvent":     {Request: v1.PageNftEventParams{}, Response: []*v1.NftEventResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.ListNftAssetLike": {Request: v1.LikeRequest{}, Response: []*v1.LikeResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.LikeNftAsset":     {},
+	"v1.UnlikeNftAsset":   {},
+	"v1.PageUserNftAsset": {Request: v1.PageNftAssetParams{}, Response: []*v1.NftAssetResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.MyNftAsset":       {Request: v1.PageNftAssetParams{}, Response: []*v1.NftAssetResp{}, Pagination: rest.PageQuickParams{}},
+
+	// comment
+	"v1.ListComment":   {Request: v1.ListCommentParams{}, Response: []*v1.CommentResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.CreateComment": {Request: v1.CreateCommentParams{}, Response: v1.CommentResp{}},
+	"v1.GetComment":    {Response: v1.CommentResp{}},
+	"v1.DeleteComment": {},
+	"v1.LikeComment":   {},
+	"v1.UnlikeComment": {},
+
+	// message
+	"v1.ListMessage": {Request: v1.ListMessageParams{}, Response: []*v1.MessageResp{}, Pagination: rest.PageQuickParams{}},
+	"v1.ExportMessage": {Summary: "Export the messages of the current user", Request: struct {
+		State string `query:"state"`
+	}{}, Export: v1.MessageResp{}},
+	"v1.MessageSummary": {Response: v1.MessageSummaryResp{}},
+	"v1.ReadMessage":    {Request: v1.ReadMessageParams{}},
+
+	// mises chain
+	"v1.GasPrices": {Response: v1.GasPricesResp{}},
+	"v1.ChainInfo": {Response: v1.ChainInfoResp{}},
+
+	// swap
+	"v1.PageSwapOrder":           {Request: v1.SwapOrderRequest{}, Response: []*v1.SwapOrderResponse{}, Pagination: rest.PageParams{}, RequestID: true},
+	"v1.ExportSwapOrder":         {Summary: "Export the swap orders of an address", Request: v1.SwapOrderRequest{}, Export: v1.SwapOrderResponse{}},
+	"v1.FindSwapOrder":           {Request: v1.SwapOrderRequest{}, Response: v1.SwapOrderResponse{}, RequestID: true},
+	"v1.GetSwapApproveAllowance": {Request: v1.SwapApproveRequest{}, Response: v1.ApproveAllowanceResp{}, RequestID: true},
+	"v1.ApproveSwapTransaction":  {Request: v1.SwapApproveRequest{}, Response: v1.ApproveSwapTransactionResponse{}, RequestID: true},
+	"v1.SwapTrade":               {Request: v1.SwapTradeRequest{}, Response: v1.SwapTradeInfo{}, RequestID: true},
+	"v1.SwapQuote":               {Request: v1.SwapQuoteRequest{}, Response: v1.SwapQuoteResponse{}, RequestID: true},
+	"v1.WalletsAndTokens":        {Request: v1.WalletsAndTokensRequest{}, Response: v1.WalletsAndTokensResponse(nil)},
+	"v1.ListTokens":              {Request: v1.TokenRequest{}, Response: []*v1.Token{}, RequestID: true},
+
+	// bridge, the data of most responses is relayed from the swap backend as is
+	"v1.BridgeGetCurrencies":        {Response: []*v1.BridgeGetCurrenciesItem{}},
+	"v1.BridgeGetPairsParams":       {Request: swapsvc.BridgeGetPairsParamsRequest{}, Response: anyValue},
+	"v1.BridgeGetExchangeAmount":    {Request: swapsvc.BridgeGetExchangeAmountRequest{}, Response: anyValue},
+	"v1.BridgeCreateTransaction":    {Request: swapsvc.BridgeCreateTransactionRequest{}, Response: anyValue},
+	"v1.BridgeGetTransactionInfo":   {Request: swapsvc.BridgeGetTransactionInfoRequest{}, Response: anyValue},
+	"v1.BridgeGetTransactionStatus": {Request: swapsvc.BridgeGetTransactionStatusRequest{}, Response: anyValue},
+	"v1.BridgeValidateAddress":      {Request: swapsvc.BridgeValidateAddressRequest{}, Response: anyValue},
+	"v1.BridgeGetFixRateForAmount":  {Request: swapsvc.BridgeGetFixRateForAmountRequest{}, Response: anyValue},
+	"v1.BridgeCreateFixTransaction": {Request: swapsvc.BridgeCreateFixTransactionRequest{}, Response: anyValue},
+	"v1.BridgeHistoryList":          {Request: swapsvc.BridgeHistoryListRequest{}, Response: []*v1.NewBridgeHistoryItem{}},
+
+	// flags
+	"v1.ListFlags": {Summary: "Feature flags of the client", Response: map[string]bool{}},
+
+	// mining
+	"v1.ADMobSSV":          {Summary: "AdMob server side verification callback", Response: v1.AdMiningCallbackResponse{}},
+	"v1.MintegralCallback": {Summary: "Mintegral reward callback"},
+	"v1.EstimateAdBonus":   {Request: v1.EstimateAdBonusRequest{}, Response: v1.EstimateAdBonusResponse{}},
+	"v1.FindMBAirdropUser": {Summary: "Find MB airdrop user", Response: v1.MBAirdropUser{}},
+	"v1.ClaimMBAirdrop":    {Summary: "Claim MB airdrop", Request: v1.MBAirdropClaimParams{}},
+	"v1.GeMiningConfig":    {Summary: "Mining config", Response: v1.MiningConfigResponse{}},
+	"v1.GetBonus":          {Response: v1.BonusResponse{}},
+	"v1.MyAdMining":        {Response: v1.AdMiningUserResponse{}},
+	"v1.RedeemBonus":       {Request: v1.RedeemBonusRequest{}},
+	"v1.AdMiningLog":       {Request: v1.AdMiningLogRequest{}},
+}
+
+// OpenAPI returns the openapi document of the routes registered by the last SetRoutes,
+// a route without an operation is documented without its params and data
+func OpenAPI() *openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:   docsTitle,
+		Version: buildinfo.Get().Version,
+		Description: "Every json response is an envelope {code, data}, code is 0 on success. " +
+			"Errors answer {code, message}, see the Error schema for the codes.",
+	})
+	for _, r := range Routes() {
+		builder.Add(endpoint(r))
+	}
+	return builder.Document()
+}
+
+// CheckOpenAPI reports the routes without an operation, the operations without a route and
+// the problems of the document, so the document cannot drift from the routes
+func CheckOpenAPI() error {
+	problems := []string{}
+	used := map[string]bool{}
+	for _, r := range Routes() {
+		if _, ok := operations[r.Handler]; !ok {
+			problems = append(problems, fmt.Sprintf("%s %s: no operation for handler %s", r.Method, r.Path, r.Handler))
+		}
+		used[r.Handler] = true
+	}
+	for handler := range operations {
+		if !used[handler] {
+			problems = append(problems, fmt.Sprintf("operation of handler %s has no route", handler))
+		}
+	}
+	if err := openapi.Validate(OpenAPI()); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("the openapi document does not match the routes:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// endpoint completes the operation of the handler of r with the route
+func endpoint(r Route) openapi.Endpoint {
+	e := operations[r.Handler]
+	e.Method = r.Method
+	e.Path = r.Path
+	e.Handler = r.Handler
+	e.RateLimit = r.RatePolicy
+	e.Middleware = r.Middleware
+	e.PathParams = pathParams{}
+	if e.Summary == "" {
+		e.Summary = summary(r.Handler)
+	}
+	if len(e.Tags) == 0 {
+		e.Tags = []string{tag(r.Path)}
+	}
+	for _, name := range r.Middleware {
+		switch {
+		case name == "set_current_user" && e.Auth == openapi.AuthNone:
+			e.Auth = openapi.AuthOptional
+		case name == "require_current_user":
+			e.Auth = openapi.AuthRequired
+		case strings.HasPrefix(name, "flag "):
+			// a disabled flag hides the route
+			e.Errors = append(e.Errors, codes.ErrNotFound)
+		case strings.HasPrefix(name, "strict_binding "):
+			e.Errors = append(e.Errors, codes.ErrUnsupportedMediaType)
+		}
+	}
+	return e
+}
+
+// summary spells the handler name, e.g. v1.PageUserNftAsset is "Page user nft asset"
+func summary(handler string) string {
+	name := handler[strings.LastIndex(handler, ".")+1:]
+	words := &strings.Builder{}
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words.WriteByte(' ')
+			r = unicode.ToLower(r)
+		}
+		words.WriteRune(r)
+	}
+	return words.String()
+}
+
+// tag groups the operations by the first segment of their path, e.g. swap for /api/v1/swap/quote
+func tag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/api/v1"), "/")
+	if len(segments) < 2 || segments[1] == "" || !strings.HasPrefix(path, "/api/v1/") {
+		return "health"
+	}
+	return segments[1]
+}
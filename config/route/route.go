@@ -1,26 +1,64 @@
 package route
 
 import (
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	graphqlapi "github.com/mises-id/sns-apigateway/app/apis/graphql"
 	"github.com/mises-id/sns-apigateway/app/apis/rest"
 	v1 "github.com/mises-id/sns-apigateway/app/apis/rest/v1"
+	"github.com/mises-id/sns-apigateway/app/apis/rpc"
 	appmw "github.com/mises-id/sns-apigateway/app/middleware"
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/mises-id/sns-apigateway/lib/binding"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
 	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+	"golang.org/x/time/rate"
 )
 
+// batchMutations are the routes other than GET a batch may call, they are idempotent or only
+// touch the signed in user
+var batchMutations = map[string]bool{
+	"POST /api/v1/phishing_site/check":  true,
+	"POST /api/v1/user/follow":          true,
+	"DELETE /api/v1/user/follow":        true,
+	"POST /api/v1/status/:id/like":      true,
+	"DELETE /api/v1/status/:id/like":    true,
+	"POST /api/v1/comment/:id/like":     true,
+	"DELETE /api/v1/comment/:id/like":   true,
+	"POST /api/v1/nft_asset/:id/like":   true,
+	"DELETE /api/v1/nft_asset/:id/like": true,
+	"PUT /api/v1/message/read":          true,
+}
+
+// batchStreaming are the routes streaming their response, a batch would keep it whole in memory
+var batchStreaming = map[string]bool{
+	"GET /api/v1/user/:uid/like/export":           true,
+	"GET /api/v1/user/:uid/status/export":         true,
+	"GET /api/v1/user/message/export":             true,
+	"GET /api/v1/swap/order/:from_address/export": true,
+}
+
 // SetRoutes sets the routes of echo http server
 func SetRoutes(e *echo.Echo) {
+	tableMutex.Lock()
+	table = nil
+	ownBodyLimit = map[string]bool{}
+	tableMutex.Unlock()
+	root := newGroup(e, "")
 	//e.Static("/", "assets")
-	e.GET("/", rest.Probe)
-	e.GET("/healthz", rest.Probe)
-	e.GET("/health/swap", v1.SwapHealth)
-	groupV1 := e.Group("/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware)
-	groupOpensea := e.Group("/api/v1", middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(4)), mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
-	groupV1.GET("/user/:uid", v1.FindUser, middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(4)))
-	groupV1.GET("/mises_user/:misesid", v1.FindMisesUser, middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(4)))
+	root.GET("/", rest.Probe)
+	root.GET("/healthz", rest.Probe)
+	root.GET("/readyz", rest.Ready)
+	root.GET("/health/swap", v1.SwapHealth)
+	groupV1 := newGroup(e, "/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware)
+	groupOpensea := newGroup(e, "/api/v1", userRateLimiter(), mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
+	groupV1.GET("/user/:uid", v1.FindUser, userRateLimiter())
+	groupV1.GET("/mises_user/:misesid", v1.FindMisesUser, userRateLimiter())
 	groupV1.GET("/channel_user/:misesid", v1.GetChannelUser)
 	groupV1.GET("/channel/info", v1.ChannelInfo)
 	groupV1.GET("/channel_user/page", v1.PageChannelUser)
@@ -32,7 +70,7 @@ func SetRoutes(e *echo.Echo) {
 	groupV1.GET("/twitter/callback", v1.TwitterCallback)
 	groupV1.GET("/user/:uid/friendship", v1.ListFriendship)
 	//website
-	groupV1.GET("/website_category/list", v1.ListWebsiteCategory)
+	groupV1.GET("/website_category/list", v1.ListWebsiteCategory, mw.ETag("public, max-age=3600"))
 	groupV1.GET("/website/page", v1.PageWebsite)
 	groupV1.GET("/website/search", v1.SearchWebsite)
 	groupV1.GET("/website/internal_search", v1.WebsiteInternalSearch)
@@ -42,11 +80,16 @@ func SetRoutes(e *echo.Echo) {
 	//phishing
 	groupV1.POST("/phishing_site/check", v1.PhishingCheck)
 	groupV1.GET("/web3safe/verify_contract", v1.VerifyContract)
-	userGroup := e.Group("/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
+	// the signed in and swap routes reject the params their handlers do not declare
+	strictBinding := binding.Strict(binding.Config{IgnoreQuery: rest.SharedQueryParams})
+	userGroup := newGroup(e, "/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware, strictBinding)
 
-	userGroup.POST("/upload", v1.UploadFile, middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+	userGroup.POST("/upload", v1.UploadFile, mw.Describe("body_limit 8M", middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
 		Skipper: middleware.DefaultSkipper,
 		Limit:   "8M",
+	})), binding.Strict(binding.Config{
+		ContentTypes: map[string][]string{http.MethodPost: {echo.MIMEMultipartForm}},
+		IgnoreQuery:  rest.SharedQueryParams,
 	}))
 	userGroup.GET("/user/me", v1.MyProfile)
 	userGroup.GET("/user/:uid/config", v1.GetUserConfig)
@@ -60,11 +103,15 @@ func SetRoutes(e *echo.Echo) {
 	userGroup.POST("/user/blacklist", v1.CreateBlacklist)
 	userGroup.DELETE("/user/blacklist/:uid", v1.DeleteBlacklist)
 	groupV1.GET("/news", v1.ListNews)
-	groupV1.GET("/news/:id", v1.GetNews)
+	groupV1.GET("/news/:id", v1.GetNews, mw.ETag("public, max-age=600"))
 	groupV1.GET("/strategies", v1.ListStrategies)
 
 	groupV1.GET("/user/:uid/like", v1.ListUserLike)
 	groupV1.GET("/user/:uid/status", v1.ListUserStatus)
+	// exports page through the whole history server side, keep them rare per client
+	exportRateLimiter := reloadableRateLimiter("RATE_LIMIT_EXPORT", func(cfg *env.Env) float64 { return cfg.RateLimitExport })
+	groupV1.GET("/user/:uid/like/export", v1.ExportUserLike, exportRateLimiter)
+	groupV1.GET("/user/:uid/status/export", v1.ExportUserStatus, exportRateLimiter)
 	groupV1.GET("/status/recommend", v1.RecommendStatus)
 	groupV1.GET("/status/list", v1.ListStatus)
 	groupV1.GET("/status/recent", v1.RecentStatus)
@@ -92,14 +139,15 @@ func SetRoutes(e *echo.Echo) {
 	userGroup.DELETE("/comment/:id/like", v1.UnlikeComment)
 
 	userGroup.GET("/user/message", v1.ListMessage)
+	userGroup.GET("/user/message/export", v1.ExportMessage, exportRateLimiter)
 	userGroup.GET("/user/message/summary", v1.MessageSummary)
 	userGroup.PUT("/message/read", v1.ReadMessage)
 
 	groupV1.GET("/user/recommend", v1.RecommendUser)
 
 	groupV1.GET("/mises/gasprices", v1.GasPrices)
-	groupV1.GET("/mises/chaininfo", v1.ChainInfo)
-	storeC := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+	groupV1.GET("/mises/chaininfo", v1.ChainInfo, mw.ETag("public, max-age=60"))
+	storeC := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{
 		Rate:      0.0001,
 		Burst:     20,
 		ExpiresIn: 1 * time.Hour,
@@ -111,24 +159,25 @@ func SetRoutes(e *echo.Echo) {
 	//swap
 	swapRateConfigCommon := getSwapRateConfigCommon()
 	swapRateConfiWithUserWalletAddress := getSwapRateConfigWithUserWalletAddress()
-	swapCommonRateLimiter := middleware.RateLimiterWithConfig(swapRateConfigCommon)
-	swapRateLimiterWithUserWalletAddress := middleware.RateLimiterWithConfig(swapRateConfiWithUserWalletAddress)
-	swapGroup := e.Group("/api/v1", swapCommonRateLimiter, swapRateLimiterWithUserWalletAddress, mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware)
+	swapCommonRateLimiter := rateLimiter("per ip and path without wallet", swapRateConfigCommon)
+	swapRateLimiterWithUserWalletAddress := rateLimiter("per wallet and path", swapRateConfiWithUserWalletAddress)
+	swapGroup := newGroup(e, "/api/v1", swapCommonRateLimiter, swapRateLimiterWithUserWalletAddress, mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, strictBinding)
 	swapGroup.GET("/swap/order/:from_address", v1.PageSwapOrder)
+	swapGroup.GET("/swap/order/:from_address/export", v1.ExportSwapOrder, exportRateLimiter)
 	swapGroup.GET("/swap/order/:from_address/:tx_hash", v1.FindSwapOrder)
 	swapGroup.GET("/swap/approve/allowance", v1.GetSwapApproveAllowance)
 	swapGroup.GET("/swap/approve/transaction", v1.ApproveSwapTransaction)
 	swapGroup.GET("/swap/trade", v1.SwapTrade)
 	swapGroup.GET("/swap/quote", v1.SwapQuote)
 	swapGroup.POST("/swap/wallets_and_tokens", v1.WalletsAndTokens)
-	swapGroup.GET("/swap/token/list", v1.ListTokens, middleware.GzipWithConfig(middleware.GzipConfig{
+	swapGroup.GET("/swap/token/list", v1.ListTokens, mw.Describe("gzip", middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
-	}))
+	})), mw.ETag("public, max-age=300"))
 
 	// bridge
-	bridgeRateLimiterWithIP := middleware.RateLimiterWithConfig(getBridgeRateLimiterWithIPConfig())
-	//bridgeGroup := e.Group("/api/v1", bridgeRateLimiterWithIP, mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
-	bridgeGroup := e.Group("/api/v1", bridgeRateLimiterWithIP, mw.ErrorResponseMiddleware)
+	bridgeRateLimiterWithIP := rateLimiter("per ip and path", getBridgeRateLimiterWithIPConfig())
+	//bridgeGroup := newGroup(e, "/api/v1", bridgeRateLimiterWithIP, mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
+	bridgeGroup := newGroup(e, "/api/v1", bridgeRateLimiterWithIP, mw.ErrorResponseMiddleware)
 	bridgeGroup.POST("/bridge/get_currencies", v1.BridgeGetCurrencies)
 	bridgeGroup.POST("/bridge/get_pairs_params", v1.BridgeGetPairsParams)
 	bridgeGroup.POST("/bridge/get_exchange_amount", v1.BridgeGetExchangeAmount)
@@ -136,31 +185,50 @@ func SetRoutes(e *echo.Echo) {
 	bridgeGroup.POST("/bridge/get_transaction_info", v1.BridgeGetTransactionInfo)
 	bridgeGroup.POST("/bridge/get_transaction_status", v1.BridgeGetTransactionStatus)
 	bridgeGroup.POST("/bridge/validate_address", v1.BridgeValidateAddress)
-	bridgeGroup.POST("/bridge/get_fix_rate_for_amount", v1.BridgeGetFixRateForAmount)
-	bridgeGroup.POST("/bridge/create_fix_transaction", v1.BridgeCreateFixTransaction, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
+	bridgeGroup.POST("/bridge/get_fix_rate_for_amount", v1.BridgeGetFixRateForAmount, appmw.SetCurrentUserMiddleware, appmw.RequireFlag(appmw.FlagBridgeFixRate))
+	bridgeGroup.POST("/bridge/create_fix_transaction", v1.BridgeCreateFixTransaction, appmw.SetCurrentUserMiddleware, appmw.RequireFlag(appmw.FlagBridgeFixRate), appmw.RequireCurrentUserMiddleware)
 	bridgeGroup.POST("/bridge/history_list", v1.BridgeHistoryList, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
 
-	userGroup.GET("/twitter/auth_url", v1.TwitterAuthUrl, middleware.RateLimiterWithConfig(rateConfig))
+	userGroup.GET("/twitter/auth_url", v1.TwitterAuthUrl, rateLimiter("per ip", rateConfig))
 	//userGroup.GET("/twitter/auth_url", v1.TwitterAuthUrl)
 	userGroup.GET("/airdrop/info", v1.AirdropInfo)
 	userGroup.POST("/airdrop/receive", v1.ReceiveAirdrop)
 
+	// feature flags
+	groupV1.GET("/flags", v1.ListFlags)
+
+	// api documentation, the document is built from the route table on its first request
+	groupV1.GET("/openapi.json", openapi.SpecHandler(OpenAPI), mw.ETag("public, max-age=300"))
+	groupV1.GET("/docs", openapi.DocsHandler(docsTitle, OpenAPIPath))
+
+	// graphql, the data of the home screen in one request
+	graphqlGroup := newGroup(e, "", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware)
+	graphqlGroup.GET("/graphql", graphqlapi.Serve)
+	graphqlGroup.POST("/graphql", graphqlapi.Serve)
+
+	// batch, the sub requests go through their own routes
+	groupV1.POST("/batch", v1.Batch(batchMutations, batchStreaming), strictBinding)
+
+	// gRPC-Web and Connect, the methods go through their own routes and answer their own errors
+	rpcGroup := newGroup(e, "")
+	rpcGroup.POST("/"+rpc.Gateway.Name+"/:method", rpc.Serve)
+
 	// mining
-	redeemBonusRateConfigWithUser := middleware.RateLimiterWithConfig(getRedeemBonusRateConfigWithUser())
+	redeemBonusRateConfigWithUser := rateLimiter("per eth address", getRedeemBonusRateConfigWithUser())
 	groupV1.GET("/admob/ssv", v1.ADMobSSV)
 	groupV1.GET("/adcallback/mintegral", v1.MintegralCallback)
-	groupV1.GET("/ad_mining/estimate_bonus", v1.EstimateAdBonus)
+	groupV1.GET("/ad_mining/estimate_bonus", v1.EstimateAdBonus, appmw.RequireFlag(appmw.FlagAdMining))
 	groupV1.GET("/mb_airdrop/user/:misesid", v1.FindMBAirdropUser)
 	userGroup.GET("/mb_airdrop/claim", v1.ClaimMBAirdrop, redeemBonusRateConfigWithUser)
-	groupV1.GET("/mining/config", v1.GeMiningConfig)
+	groupV1.GET("/mining/config", v1.GeMiningConfig, mw.ETag("private, no-cache"))
 	userGroup.GET("/mining/bonus", v1.GetBonus)
-	userGroup.GET("/ad_mining/me", v1.MyAdMining)
+	userGroup.GET("/ad_mining/me", v1.MyAdMining, appmw.RequireFlag(appmw.FlagAdMining))
 	userGroup.POST("/mining/redeem_bonus", v1.RedeemBonus, redeemBonusRateConfigWithUser)
-	userGroup.POST("/ad_mining/log", v1.AdMiningLog, redeemBonusRateConfigWithUser)
+	userGroup.POST("/ad_mining/log", v1.AdMiningLog, redeemBonusRateConfigWithUser, appmw.RequireFlag(appmw.FlagAdMining))
 }
 
 func getBridgeRateLimiterWithIPConfig() middleware.RateLimiterConfig {
-	bridgeRateLimitStore := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+	bridgeRateLimitStore := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{
 		Rate:      10,
 		Burst:     10,
 		ExpiresIn: 1 * time.Minute,
@@ -178,7 +246,7 @@ func getBridgeRateLimiterWithIPConfig() middleware.RateLimiterConfig {
 
 func getRedeemBonusRateConfigWithUser() middleware.RateLimiterConfig {
 
-	redeemBonusRateLimitStore := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+	redeemBonusRateLimitStore := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{
 		Rate:      1,
 		Burst:     1,
 		ExpiresIn: 1 * time.Second,
@@ -196,7 +264,7 @@ func getRedeemBonusRateConfigWithUser() middleware.RateLimiterConfig {
 }
 
 func getSwapRateConfigCommon() middleware.RateLimiterConfig {
-	swapCommonStore := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+	swapCommonStore := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{
 		Rate:      100,
 		Burst:     1000,
 		ExpiresIn: 1 * time.Minute,
@@ -220,7 +288,7 @@ func getSwapRateConfigCommon() middleware.RateLimiterConfig {
 }
 
 func getSwapRateConfigWithUserWalletAddress() middleware.RateLimiterConfig {
-	swapStoreWithUserWalletAddress := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+	swapStoreWithUserWalletAddress := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{
 		Rate:      5,
 		Burst:     60,
 		ExpiresIn: 1 * time.Minute,
@@ -253,3 +321,39 @@ func getCurrentEthAddress(c echo.Context) string {
 	}
 	return currentEthAddress
 }
+
+func userRateLimiter() echo.MiddlewareFunc {
+	return reloadableRateLimiter("RATE_LIMIT_USER", func(cfg *env.Env) float64 { return cfg.RateLimitUser })
+}
+
+// reloadableRateLimiter follows the rate limit of the config setting across reloads
+func reloadableRateLimiter(setting string, limit func(cfg *env.Env) float64) echo.MiddlewareFunc {
+	store := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: rate.Limit(limit(env.Current()))})
+	env.OnReload(func(cfg *env.Env) {
+		store.SetRate(rate.Limit(limit(cfg)))
+	})
+	config := middleware.RateLimiterConfig{Store: store, DenyHandler: countDenied(nil)}
+	return mw.Describe(fmt.Sprintf("%s%s per ip (%s)", ratePolicyPrefix, store.Policy(), setting), middleware.RateLimiterWithConfig(config))
+}
+
+// rateLimiter describes the policy of config in the route table, the rate and the burst of its
+// store followed by per, what the requests are counted by
+func rateLimiter(per string, config middleware.RateLimiterConfig) echo.MiddlewareFunc {
+	config.DenyHandler = countDenied(config.DenyHandler)
+	policy := per
+	if store, ok := config.Store.(*mw.RateLimiterStore); ok {
+		policy = store.Policy() + " " + per
+	}
+	return mw.Describe(ratePolicyPrefix+policy, middleware.RateLimiterWithConfig(config))
+}
+
+// countDenied counts the denied requests per route before answering them with deny
+func countDenied(deny func(c echo.Context, identifier string, err error) error) func(c echo.Context, identifier string, err error) error {
+	if deny == nil {
+		deny = middleware.DefaultRateLimiterConfig.DenyHandler
+	}
+	return func(c echo.Context, identifier string, err error) error {
+		metrics.CountRateLimited(c.Path())
+		return deny(c, identifier, err)
+	}
+}
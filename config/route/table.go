@@ -0,0 +1,127 @@
+package route
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	appmw "github.com/mises-id/sns-apigateway/app/middleware"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+)
+
+// Route is an entry of the effective route table
+type Route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	// Middleware is the chain in call order, group middleware first
+	Middleware []string `json:"middleware"`
+	RatePolicy []string `json:"rate_policy"`
+}
+
+const (
+	// ratePolicyPrefix starts the description of every rate limiter
+	ratePolicyPrefix = "rate_limit "
+	// bodyLimitPrefix starts the description of the body limit of a route
+	bodyLimitPrefix = "body_limit "
+)
+
+var (
+	tableMutex sync.Mutex
+	table      []Route
+	// ownBodyLimit are the method and path of the routes with their own body limit
+	ownBodyLimit = map[string]bool{}
+)
+
+func init() {
+	mw.Describe("error_response", mw.ErrorResponseMiddleware)
+	mw.Describe("set_current_user", appmw.SetCurrentUserMiddleware)
+	mw.Describe("require_current_user", appmw.RequireCurrentUserMiddleware)
+}
+
+// Routes returns the routes registered by the last SetRoutes, sorted by path and method
+func Routes() []Route {
+	tableMutex.Lock()
+	defer tableMutex.Unlock()
+	routes := append([]Route{}, table...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// OwnBodyLimit tells if the route of c has its own body limit, the server wide one skips it
+func OwnBodyLimit(c echo.Context) bool {
+	tableMutex.Lock()
+	defer tableMutex.Unlock()
+	return ownBodyLimit[c.Request().Method+" "+c.Path()]
+}
+
+// group records its routes in the route table
+type group struct {
+	*echo.Group
+	prefix     string
+	middleware []echo.MiddlewareFunc
+}
+
+func newGroup(e *echo.Echo, prefix string, m ...echo.MiddlewareFunc) *group {
+	return &group{Group: e.Group(prefix, m...), prefix: prefix, middleware: m}
+}
+
+func (g *group) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	g.record(http.MethodGet, path, h, m)
+	return g.Group.GET(path, h, m...)
+}
+
+func (g *group) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	g.record(http.MethodPost, path, h, m)
+	return g.Group.POST(path, h, m...)
+}
+
+func (g *group) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	g.record(http.MethodPut, path, h, m)
+	return g.Group.PUT(path, h, m...)
+}
+
+func (g *group) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	g.record(http.MethodPatch, path, h, m)
+	return g.Group.PATCH(path, h, m...)
+}
+
+func (g *group) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	g.record(http.MethodDelete, path, h, m)
+	return g.Group.DELETE(path, h, m...)
+}
+
+func (g *group) record(method, path string, h echo.HandlerFunc, m []echo.MiddlewareFunc) {
+	handler := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	route := Route{
+		Method:     method,
+		Path:       g.prefix + path,
+		Handler:    handler[strings.LastIndex(handler, "/")+1:],
+		Middleware: []string{},
+		RatePolicy: []string{},
+	}
+	for _, fn := range append(append([]echo.MiddlewareFunc{}, g.middleware...), m...) {
+		name := mw.Description(fn)
+		route.Middleware = append(route.Middleware, name)
+		if strings.HasPrefix(name, ratePolicyPrefix) {
+			route.RatePolicy = append(route.RatePolicy, strings.TrimPrefix(name, ratePolicyPrefix))
+		}
+	}
+	tableMutex.Lock()
+	table = append(table, route)
+	for _, name := range route.Middleware {
+		if strings.HasPrefix(name, bodyLimitPrefix) {
+			ownBodyLimit[method+" "+route.Path] = true
+		}
+	}
+	tableMutex.Unlock()
+}
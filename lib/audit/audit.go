@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Outcomes of the audited actions
+const (
+	OutcomeOK     = "ok"
+	OutcomeFailed = "failed"
+)
+
+// GenesisHash is the previous hash of the first record of a chain
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Record is a line of the audit log. Hash covers every other field, PrevHash included,
+// so a record can't be changed, removed or inserted without breaking the chain after it.
+type Record struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Target    string    `json:"target,omitempty"`
+	IP        string    `json:"ip"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// Digest is the hash of the record, computed over its json without the hash
+func (r Record) Digest() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Logger chains the records and writes them to its sink, it is safe for concurrent use
+type Logger struct {
+	mutex sync.Mutex
+	sink  Sink
+	seq   uint64
+	prev  string
+}
+
+// New creates a logger continuing the chain after last, a new chain when last is nil
+func New(sink Sink, last *Record) *Logger {
+	l := &Logger{sink: sink, prev: GenesisHash}
+	if last != nil {
+		l.seq = last.Seq
+		l.prev = last.Hash
+	}
+	return l
+}
+
+// Write chains r and writes it, the seq, hashes and time are set by the logger
+func (l *Logger) Write(r Record) (Record, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	r.Seq = l.seq + 1
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	r.PrevHash = l.prev
+	r.Hash = r.Digest()
+	line, err := json.Marshal(r)
+	if err != nil {
+		return r, err
+	}
+	if err := l.sink.Write(append(line, '\n')); err != nil {
+		// the chain does not advance, the next record links to the last written one
+		return r, err
+	}
+	l.seq = r.Seq
+	l.prev = r.Hash
+	return r, nil
+}
+
+// Close closes the sink
+func (l *Logger) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.sink.Close()
+}
+
+var (
+	defaultMutex  sync.RWMutex
+	defaultLogger *Logger
+)
+
+// SetDefault sets the logger Log writes to, nil disables the audit log
+func SetDefault(l *Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultLogger = l
+}
+
+// Default is the logger Log writes to, nil when the audit log is disabled
+func Default() *Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultLogger
+}
+
+// Log records the outcome of action by actor on target for the request of c. A failing audit
+// sink is logged and does not fail the request.
+func Log(c echo.Context, action, actor, target string, err error) {
+	l := Default()
+	if l == nil {
+		return
+	}
+	req := c.Request()
+	r := Record{
+		Action:    action,
+		Actor:     actor,
+		Target:    target,
+		IP:        c.RealIP(),
+		DeviceID:  req.Header.Get("mises-device-id"),
+		RequestID: req.Header.Get(echo.HeaderXRequestID),
+		Outcome:   OutcomeOK,
+	}
+	if r.RequestID == "" {
+		r.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	if err != nil {
+		r.Outcome = OutcomeFailed
+		r.Error = err.Error()
+	}
+	if _, err := l.Write(r); err != nil {
+		logrus.Errorf("audit: %s by %s not recorded: %v", action, actor, err)
+	}
+}
@@ -0,0 +1,213 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Sink stores the json lines of the records, in order
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// WriterSink writes the lines to an io.Writer such as stdout
+type WriterSink struct {
+	io.Writer
+}
+
+func (s WriterSink) Write(line []byte) error {
+	_, err := s.Writer.Write(line)
+	return err
+}
+
+func (s WriterSink) Close() error {
+	return nil
+}
+
+// Publisher sends a message to a queue topic, implemented by the queue clients
+type Publisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// QueueSink publishes every line as a message of topic
+type QueueSink struct {
+	Publisher Publisher
+	Topic     string
+	Timeout   time.Duration
+}
+
+func (s *QueueSink) Write(line []byte) error {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	return s.Publisher.Publish(ctx, s.Topic, bytes.TrimSuffix(line, []byte("\n")))
+}
+
+func (s *QueueSink) Close() error {
+	return nil
+}
+
+// FileSink appends the lines to a file, rotated to <path>.1 up to <path>.<MaxBackups> once it
+// reaches MaxBytes. The chain goes on across the files, verify them oldest first.
+type FileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+	mutex      sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink opens path for appending, creating its directory
+func NewFileSink(path string, maxBytes int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// the logger does not chain a line which failed, so the file must not keep it
+		if truncateErr := s.file.Truncate(s.size); truncateErr != nil {
+			return fmt.Errorf("%w, the partial line was not removed: %v", err, truncateErr)
+		}
+		return err
+	}
+	s.size += int64(n)
+	return nil
+}
+
+// rotate shifts the backups, the oldest one is removed. A failed rotation reopens the file,
+// so the next lines are appended to it
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	if err := s.shift(); err != nil {
+		if openErr := s.open(); openErr != nil {
+			return fmt.Errorf("%w, reopening %s: %v", err, s.path, openErr)
+		}
+		return err
+	}
+	return s.open()
+}
+
+func (s *FileSink) shift() error {
+	for i := s.maxBackups; i > 0; i-- {
+		from := s.path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", s.path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if s.maxBackups <= 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+// Last is the last record of the file, or of its latest backup when the file was just rotated,
+// nil for a new log
+func (s *FileSink) Last() (*Record, error) {
+	for _, path := range []string{s.path, s.path + ".1"} {
+		line, err := lastLine(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		r := &Record{}
+		if err := json.Unmarshal(line, r); err != nil {
+			return nil, fmt.Errorf("last record of %s: %w", path, err)
+		}
+		return r, nil
+	}
+	return nil, nil
+}
+
+// lastLine reads the last non empty line of path from its tail
+func lastLine(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const chunk = 64 * 1024
+	size := info.Size()
+	for tail := int64(chunk); ; tail *= 2 {
+		if tail > size {
+			tail = size
+		}
+		buf := make([]byte, tail)
+		if _, err := file.ReadAt(buf, size-tail); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+		if tail == size {
+			return buf, nil
+		}
+	}
+}
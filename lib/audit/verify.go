@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Verification is the result of a chain verification
+type Verification struct {
+	Records int
+	// First is the first record read, Last the last valid one
+	First *Record
+	Last  *Record
+	// Anchored is true when the chain starts at the genesis, false when older files are missing
+	Anchored bool
+}
+
+// Verifier checks the records of consecutive readers, oldest first
+type Verifier struct {
+	result Verification
+}
+
+// Verify checks every line of r links to the previous record and matches its hash.
+// name identifies r in the errors.
+func (v *Verifier) Verify(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("%s:%d: invalid record: %w", name, line, err)
+		}
+		if err := v.check(record); err != nil {
+			return fmt.Errorf("%s:%d: seq %d: %w", name, line, record.Seq, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (v *Verifier) check(record *Record) error {
+	if digest := record.Digest(); digest != record.Hash {
+		return fmt.Errorf("hash %s does not match the record, it was modified", record.Hash)
+	}
+	last := v.result.Last
+	if last == nil {
+		v.result.First = record
+		v.result.Anchored = record.Seq == 1 && record.PrevHash == GenesisHash
+	} else {
+		if record.PrevHash != last.Hash {
+			return fmt.Errorf("previous hash %s does not match record %d, a record was removed or inserted", record.PrevHash, last.Seq)
+		}
+		if record.Seq != last.Seq+1 {
+			return fmt.Errorf("follows record %d", last.Seq)
+		}
+	}
+	v.result.Last = record
+	v.result.Records++
+	return nil
+}
+
+// Result is the verification of the records read so far
+func (v *Verifier) Result() Verification {
+	return v.result
+}
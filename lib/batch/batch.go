@@ -0,0 +1,247 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+// Request is a sub request of a batch, Query is added to the query of Path
+type Request struct {
+	Method string            `json:"method" validate:"required,oneof=GET HEAD POST PUT PATCH DELETE"`
+	Path   string            `json:"path" validate:"required,startswith=/"`
+	Query  map[string]string `json:"query,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+}
+
+// Response is the status and body of a sub request, a body which is not json is a json string
+type Response struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Config of a batch
+type Config struct {
+	// Prefix is the path prefix of the routes a batch may call
+	Prefix string
+	// Concurrency is the most sub requests served at once
+	Concurrency int
+	// Mutations are the routes other than GET and HEAD a batch may call, keyed by method and
+	// route path, e.g. "POST /api/v1/user/follow"
+	Mutations map[string]bool
+	// Streaming are the routes streaming their response, like the exports, which may not be
+	// sent in a batch, keyed like Mutations
+	Streaming map[string]bool
+	// MaxResponseBytes caps the body of a sub request kept in memory, 0 keeps it whole
+	MaxResponseBytes int
+}
+
+// droppedHeaders are not passed to the sub requests, they describe the body or the encoding
+// of the batch rather than the ones of a sub request
+var droppedHeaders = []string{
+	echo.HeaderContentLength, echo.HeaderContentType, echo.HeaderContentEncoding, echo.HeaderAcceptEncoding,
+	echo.HeaderAccept, echo.HeaderIfModifiedSince, "If-None-Match",
+}
+
+// Run serves requests through the router of c with the headers of c, so they pass the auth and
+// the rate limits of their routes. The requests run in order, a mutation alone once the requests
+// before it are done, the other requests between two mutations concurrently.
+func Run(c echo.Context, requests []Request, config Config) []Response {
+	responses := make([]Response, len(requests))
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	for start := 0; start < len(requests); {
+		end := start + 1
+		if !mutation(requests[start].Method) {
+			for end < len(requests) && !mutation(requests[end].Method) {
+				end++
+			}
+		}
+		jobs := make(chan int)
+		wg := sync.WaitGroup{}
+		for w := 0; w < concurrency && w < end-start; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					responses[i] = serve(c, i, requests[i], config)
+				}
+			}()
+		}
+		for i := start; i < end; i++ {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		start = end
+	}
+	return responses
+}
+
+func mutation(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead
+}
+
+func serve(c echo.Context, i int, r Request, config Config) Response {
+	req, err := newRequest(c, i, r, config)
+	if err != nil {
+		return errorResponse(err)
+	}
+	w := &recorder{header: http.Header{}, limit: config.MaxResponseBytes}
+	c.Echo().ServeHTTP(w, req)
+	return w.response()
+}
+
+// SubRequest is a request of method and target sent with the headers of c, its body is json
+func SubRequest(c echo.Context, method, target string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if len(body) > 0 && string(body) != "null" {
+		reader = bytes.NewReader(body)
+	}
+	outer := c.Request()
+	req, err := http.NewRequestWithContext(outer.Context(), method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = outer.Header.Clone()
+	for _, name := range droppedHeaders {
+		req.Header.Del(name)
+	}
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	if reader != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		req.Header.Set(echo.HeaderXRequestID, id)
+	}
+	req.RemoteAddr = outer.RemoteAddr
+	req.Host = outer.Host
+	return req, nil
+}
+
+// Dispatch serves req through the router of c, with the middleware of its route
+func Dispatch(c echo.Context, req *http.Request) (Response, http.Header) {
+	w := &recorder{header: http.Header{}}
+	c.Echo().ServeHTTP(w, req)
+	return w.response(), w.header
+}
+
+// newRequest builds the http request of r, it answers an error when r may not be sent in a batch
+func newRequest(c echo.Context, i int, r Request, config Config) (*http.Request, error) {
+	method := strings.ToUpper(r.Method)
+	target, err := url.Parse(r.Path)
+	if err != nil || target.Scheme != "" || target.Host != "" {
+		return nil, codes.ErrInvalidArgument.Newf("invalid path %q", r.Path)
+	}
+	target.Path = path.Clean(target.Path)
+	if !strings.HasPrefix(target.Path, config.Prefix) {
+		return nil, codes.ErrForbidden.Newf("only the routes of %s may be sent in a batch", config.Prefix)
+	}
+
+	route := routePath(c.Echo(), method, target.Path)
+	if route == c.Path() {
+		return nil, codes.ErrForbidden.New("a batch may not contain a batch")
+	}
+	if config.Streaming[method+" "+route] {
+		return nil, codes.ErrForbidden.Newf("%s %s streams its response, it may not be sent in a batch", method, target.Path)
+	}
+	if mutation(method) && !config.Mutations[method+" "+route] {
+		return nil, codes.ErrForbidden.Newf("%s %s may not be sent in a batch", method, target.Path)
+	}
+
+	query := target.Query()
+	for name, value := range r.Query {
+		query.Set(name, value)
+	}
+	target.RawQuery = query.Encode()
+
+	req, err := SubRequest(c, method, target.RequestURI(), r.Body)
+	if err != nil {
+		return nil, codes.ErrInvalidArgument.Newf("invalid path %q", r.Path)
+	}
+	// the lines of a sub request are logged with the id of the batch
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		req.Header.Set(echo.HeaderXRequestID, fmt.Sprintf("%s-%d", id, i))
+	}
+	return req, nil
+}
+
+// routePath is the echo path of the route serving method and p, p itself without one
+func routePath(e *echo.Echo, method, p string) string {
+	c := e.NewContext(nil, nil)
+	e.Router().Find(method, p, c)
+	return c.Path()
+}
+
+func errorResponse(err error) Response {
+	code, ok := err.(codes.Code)
+	if !ok {
+		code = codes.ErrInternal
+	}
+	body, _ := json.Marshal(echo.Map{"code": code.Code, "message": code.Msg})
+	return Response{Status: code.HTTPStatus, Body: body}
+}
+
+// errResponseTooLarge stops a handler writing more than the limit of its recorder
+var errResponseTooLarge = errors.New("batch: the response exceeds the limit of a sub request")
+
+// recorder keeps the response of a sub request, up to limit bytes of its body when limit is set
+type recorder struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *recorder) Header() http.Header {
+	return w.header
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.limit > 0 && w.body.Len()+len(b) > w.limit {
+		w.overflow = true
+		return 0, errResponseTooLarge
+	}
+	return w.body.Write(b)
+}
+
+func (w *recorder) Flush() {}
+
+func (w *recorder) response() Response {
+	if w.overflow {
+		return errorResponse(codes.ErrRequestEntityTooLarge.Newf("the response is larger than the %d bytes a batch keeps of a sub request", w.limit))
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := bytes.TrimSpace(w.body.Bytes())
+	if len(body) == 0 {
+		return Response{Status: status}
+	}
+	if strings.HasPrefix(w.header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) && json.Valid(body) {
+		return Response{Status: status, Body: append(json.RawMessage{}, body...)}
+	}
+	text, _ := json.Marshal(string(body))
+	return Response{Status: status, Body: text}
+}
@@ -0,0 +1,316 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+)
+
+// the sources a request field is bound from
+const (
+	InQuery = "query"
+	InBody  = "body"
+	InPath  = "path"
+)
+
+const strictKey = "binding.strict"
+
+// DefaultContentTypes are the bodies each method takes, a method left out takes no body
+var DefaultContentTypes = map[string][]string{
+	http.MethodPost:   {echo.MIMEApplicationJSON},
+	http.MethodPut:    {echo.MIMEApplicationJSON},
+	http.MethodPatch:  {echo.MIMEApplicationJSON},
+	http.MethodDelete: {echo.MIMEApplicationJSON},
+}
+
+// Config is the strict binding of a route group
+type Config struct {
+	// ContentTypes are the media types of the bodies per method, DefaultContentTypes when nil
+	ContentTypes map[string][]string
+	// IgnoreQuery are the query params read outside of the bound params, e.g. fields or cursor
+	IgnoreQuery []string
+}
+
+// Strict makes Binder bind the requests of the routes it is used on strictly: the query params
+// and body fields the params do not declare are rejected, so are the bodies of a content type
+// the method does not take. A Strict on a route replaces the one of its group
+func Strict(cfg Config) echo.MiddlewareFunc {
+	if cfg.ContentTypes == nil {
+		cfg.ContentTypes = DefaultContentTypes
+	}
+	methods := make([]string, 0, len(cfg.ContentTypes))
+	for method, types := range cfg.ContentTypes {
+		methods = append(methods, method+" "+strings.Join(types, ","))
+	}
+	sort.Strings(methods)
+	return mw.Describe("strict_binding "+strings.Join(methods, " "), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(strictKey, &cfg)
+			return next(c)
+		}
+	})
+}
+
+// Binder binds strictly the requests of the routes using Strict, the others with its Binder,
+// then validates the params with the validator of the server. The field errors and codes of a
+// failed bind are kept, a body past its limit is answered ErrRequestEntityTooLarge and the other
+// bind errors ErrInvalidParams
+type Binder struct {
+	echo.Binder
+}
+
+// ErrInvalidParams answers the bind errors which are neither field errors nor codes
+var ErrInvalidParams = codes.ErrInvalidArgument.New("invalid query params")
+
+func (b Binder) Bind(i interface{}, c echo.Context) error {
+	if err := b.bind(i, c); err != nil {
+		return bindError(err)
+	}
+	if !isStruct(i) || c.Echo().Validator == nil {
+		return nil
+	}
+	return c.Validate(i)
+}
+
+func (b Binder) bind(i interface{}, c echo.Context) error {
+	cfg, ok := c.Get(strictKey).(*Config)
+	if !ok || !isStruct(i) {
+		return b.Binder.Bind(i, c)
+	}
+	return cfg.bind(i, c)
+}
+
+func bindError(err error) error {
+	switch err.(type) {
+	case codes.FieldsError, codes.Code:
+		return err
+	}
+	// the echo binder wraps the error of a body cut by a body limit
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return codes.ErrRequestEntityTooLarge
+	}
+	return ErrInvalidParams
+}
+
+func (cfg *Config) bind(i interface{}, c echo.Context) error {
+	binder := &echo.DefaultBinder{}
+	if err := binder.BindPathParams(c, i); err != nil {
+		return err
+	}
+	known := fieldsOf(reflect.TypeOf(i).Elem())
+	req := c.Request()
+	takesQuery := req.Method == http.MethodGet || req.Method == http.MethodDelete || req.Method == http.MethodHead
+	fields := cfg.checkQuery(c, known, takesQuery)
+	if takesQuery {
+		if err := binder.BindQueryParams(c, i); err != nil {
+			return err
+		}
+	}
+	if req.ContentLength != 0 {
+		mediaType, err := cfg.checkContentType(req)
+		if err != nil {
+			return err
+		}
+		bodyFields, err := bindBody(binder, c, i, mediaType, known)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, bodyFields...)
+	}
+	if len(fields) > 0 {
+		return codes.InvalidFields(fields...)
+	}
+	return nil
+}
+
+func (cfg *Config) checkQuery(c echo.Context, known *fieldSet, takesQuery bool) []codes.FieldError {
+	names := []string{}
+	for name := range c.QueryParams() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := []codes.FieldError{}
+	for _, name := range names {
+		if contains(cfg.IgnoreQuery, name) || (takesQuery && known.query[name]) {
+			continue
+		}
+		fields = append(fields, unknown(name, InQuery, known))
+	}
+	return fields
+}
+
+func (cfg *Config) checkContentType(req *http.Request) (string, error) {
+	types := cfg.ContentTypes[req.Method]
+	if len(types) == 0 {
+		return "", codes.ErrUnsupportedMediaType.Newf("%s requests have no body", req.Method)
+	}
+	mediaType := strings.TrimSpace(strings.Split(req.Header.Get(echo.HeaderContentType), ";")[0])
+	for _, t := range types {
+		if strings.EqualFold(mediaType, t) {
+			return t, nil
+		}
+	}
+	return "", codes.ErrUnsupportedMediaType.Newf("%s requests take %s bodies, not %q", req.Method, strings.Join(types, " or "), mediaType)
+}
+
+func bindBody(binder *echo.DefaultBinder, c echo.Context, i interface{}, mediaType string, known *fieldSet) ([]codes.FieldError, error) {
+	if mediaType != echo.MIMEApplicationJSON {
+		params, err := c.FormParams()
+		if err != nil {
+			return nil, bodyError(err)
+		}
+		fields := []codes.FieldError{}
+		for name := range params {
+			if !known.form[name] {
+				fields = append(fields, unknown(name, InBody, known))
+			}
+		}
+		sort.Slice(fields, func(a, b int) bool { return fields[a].Field < fields[b].Field })
+		return fields, binder.BindBody(c, i)
+	}
+	decoder := json.NewDecoder(c.Request().Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(i)
+	if err == nil || err == io.EOF {
+		return nil, nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []codes.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			In:      InBody,
+			Message: fmt.Sprintf("%s must be %s", typeErr.Field, kindName(typeErr.Type)),
+		}}, nil
+	}
+	// the decoder does not type its unknown field error
+	if name := strings.TrimPrefix(err.Error(), "json: unknown field "); name != err.Error() {
+		return []codes.FieldError{unknown(strings.Trim(name, `"`), InBody, known)}, nil
+	}
+	return nil, bodyError(err)
+}
+
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return codes.ErrRequestEntityTooLarge
+	}
+	return codes.ErrInvalidArgument.Newf("invalid body: %s", err.Error())
+}
+
+// unknown reports a field sent in the wrong source, or not declared at all
+func unknown(name, in string, known *fieldSet) codes.FieldError {
+	field := codes.FieldError{Field: name, Rule: "unknown", In: in, Message: fmt.Sprintf("%s is not a %s field", name, in)}
+	for _, expected := range []string{InPath, InBody, InQuery} {
+		if expected != in && known.has(expected, name) {
+			field.Message = fmt.Sprintf("%s is expected in the %s, not the %s", name, expected, in)
+			break
+		}
+	}
+	return field
+}
+
+// fieldSet are the names a params struct is bound from, per source
+type fieldSet struct {
+	query map[string]bool
+	body  map[string]bool
+	form  map[string]bool
+	path  map[string]bool
+}
+
+func (s *fieldSet) has(in, name string) bool {
+	switch in {
+	case InPath:
+		return s.path[name]
+	case InQuery:
+		return s.query[name]
+	}
+	return s.body[strings.ToLower(name)] || s.form[name]
+}
+
+var fieldSets sync.Map
+
+func fieldsOf(t reflect.Type) *fieldSet {
+	if s, ok := fieldSets.Load(t); ok {
+		return s.(*fieldSet)
+	}
+	s := &fieldSet{query: map[string]bool{}, body: map[string]bool{}, form: map[string]bool{}, path: map[string]bool{}}
+	s.add(t)
+	fieldSets.Store(t, s)
+	return s
+}
+
+// add walks the fields like the echo binder does, untagged structs are bound field by field
+func (s *fieldSet) add(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous && field.PkgPath != "" {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		tagged := false
+		for key, names := range map[string]map[string]bool{"query": s.query, "form": s.form, "param": s.path} {
+			if name := field.Tag.Get(key); name != "" {
+				names[name], tagged = true, true
+			}
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-":
+		case name != "":
+			s.body[strings.ToLower(name)] = true
+		case field.Anonymous && ft.Kind() == reflect.Struct:
+		default:
+			// encoding/json matches the field names case insensitively
+			s.body[strings.ToLower(field.Name)] = true
+		}
+		if !tagged && ft.Kind() == reflect.Struct && (field.Anonymous || name == "") {
+			s.add(ft)
+		}
+	}
+}
+
+func isStruct(i interface{}) bool {
+	t := reflect.TypeOf(i)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
+
+func kindName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct, reflect.Ptr:
+		return "an object"
+	}
+	return "a number"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
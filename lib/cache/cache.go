@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Scopes of a cache key
+const (
+	ScopePublic    = "public"
+	ScopeAnonymous = "anonymous"
+)
+
+// Scope is the auth scope of the cache key of r, a personalized payload is only cached for the
+// requests without credentials. The header is read so it does not depend on the session middleware.
+func Scope(r *http.Request, personalized bool) (string, bool) {
+	if !personalized {
+		return ScopePublic, true
+	}
+	if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	return ScopeAnonymous, true
+}
+
+// Store keeps serialized responses for a limited time
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Flush(ctx context.Context) error
+}
+
+// Loader reads through a Store and collapses concurrent misses of the same key,
+// so only one backend call is made per key at a time
+type Loader struct {
+	store Store
+	group singleflight.Group
+}
+
+func NewLoader(store Store) *Loader {
+	return &Loader{store: store}
+}
+
+func (l *Loader) Store() Store {
+	return l.store
+}
+
+// Load returns the cached value of key, or calls fn and caches its result for ttl.
+// A failed fn is never cached. With refresh the cached value is ignored but still replaced.
+func (l *Loader) Load(ctx context.Context, key string, ttl time.Duration, refresh bool, fn func() ([]byte, error)) ([]byte, error) {
+	if !refresh {
+		if value, ok, err := l.store.Get(ctx, key); err == nil && ok {
+			return value, nil
+		}
+	}
+	value, err, _ := l.group.Do(key, func() (interface{}, error) {
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		// a broken cache only costs us backend calls, so the write error is dropped
+		_ = l.store.Set(ctx, key, value, ttl)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
@@ -0,0 +1,66 @@
+package certreload
+
+import (
+	"context"
+	"crypto/tls"
+	"sync/atomic"
+
+	"github.com/mises-id/sns-apigateway/lib/filewatch"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reloader serves a tls key pair that can be replaced without a restart
+type Reloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// New loads the key pair of certFile and keyFile
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the key pair again, the current one is kept if it cannot be loaded
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Watch reloads the key pair whenever one of its files changes, until ctx is done.
+// A renewal writing the two files is applied once both are consistent.
+func (r *Reloader) Watch(ctx context.Context) error {
+	onChange := func() {
+		if err := r.Reload(); err != nil {
+			log.Errorf("tls certificate reload rejected: %v", err)
+			return
+		}
+		log.Infof("tls certificate %s reloaded", r.certFile)
+	}
+	if err := filewatch.Watch(ctx, r.certFile, onChange); err != nil {
+		return err
+	}
+	return filewatch.Watch(ctx, r.keyFile, onChange)
+}
+
+// Config returns a tls config serving the reloaded key pair over http/2 and http/1.1
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
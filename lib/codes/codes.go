@@ -58,6 +58,8 @@ const (
 	TokenExpiredCode        = 403002
 	NotFoundCode            = 404000
 	StatusRequestTimeout    = 408000
+	RequestEntityTooLarge   = 413000
+	UnsupportedMediaType    = 415000
 	UnprocessableEntityCode = 422000
 	UsernameDuplicateCode   = 422001
 	TooManyRequestCode      = 429001
@@ -67,22 +69,46 @@ const (
 )
 
 var (
-	Success                = Code{HTTPStatus: http.StatusOK, Code: SuccessCode, Msg: "success"}
-	ErrInvalidArgument     = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidArgumentCode, Msg: "invalid params"}
-	ErrInvalidAuth         = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthCode, Msg: "invalid auth params"}
-	ErrInvalidAuthMethod   = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthMethodCode, Msg: "invalid auth method"}
-	ErrInvalidAuthToken    = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthTokenCode, Msg: "invalid auth token"}
-	ErrUnauthorized        = Code{HTTPStatus: http.StatusUnauthorized, Code: UnauthorizedCode, Msg: "unauthorized"}
-	ErrAuthorizeFailed     = Code{HTTPStatus: http.StatusUnauthorized, Code: AuthorizeFailedCode, Msg: "authorize failed"}
-	ErrForbidden           = Code{HTTPStatus: http.StatusForbidden, Code: ForbiddenCode, Msg: "forbidden"}
-	ErrTokenExpired        = Code{HTTPStatus: http.StatusForbidden, Code: TokenExpiredCode, Msg: "authorization expired"}
-	ErrUsernameExisted     = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameExistedCode, Msg: "username had existed"}
-	ErrNotFound            = Code{HTTPStatus: http.StatusNotFound, Code: NotFoundCode, Msg: "not found"}
-	ErrRequestTimeout      = Code{HTTPStatus: http.StatusRequestTimeout, Code: StatusRequestTimeout, Msg: "request timed out"}
-	ErrUnprocessableEntity = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UnprocessableEntityCode, Msg: "unprocessable entity"}
-	ErrUsernameDuplicate   = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameDuplicateCode, Msg: "username duplicate"}
-	ErrTooManyRequest      = Code{HTTPStatus: http.StatusTooManyRequests, Code: TooManyRequestCode, Msg: "too many requests"}
-	ErrRequestTimeoutCode  = Code{HTTPStatus: http.StatusRequestTimeout, Code: RequestTimeoutCode, Msg: "request timeout"}
-	ErrInternal            = Code{HTTPStatus: http.StatusInternalServerError, Code: InternalCode, Msg: "Unknown error"}
-	ErrUnimplemented       = Code{HTTPStatus: http.StatusInternalServerError, Code: InternalCode, Msg: "Unknown error"}
+	Success                  = Code{HTTPStatus: http.StatusOK, Code: SuccessCode, Msg: "success"}
+	ErrInvalidArgument       = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidArgumentCode, Msg: "invalid params"}
+	ErrInvalidAuth           = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthCode, Msg: "invalid auth params"}
+	ErrInvalidAuthMethod     = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthMethodCode, Msg: "invalid auth method"}
+	ErrInvalidAuthToken      = Code{HTTPStatus: http.StatusBadRequest, Code: InvalidAuthTokenCode, Msg: "invalid auth token"}
+	ErrUnauthorized          = Code{HTTPStatus: http.StatusUnauthorized, Code: UnauthorizedCode, Msg: "unauthorized"}
+	ErrAuthorizeFailed       = Code{HTTPStatus: http.StatusUnauthorized, Code: AuthorizeFailedCode, Msg: "authorize failed"}
+	ErrForbidden             = Code{HTTPStatus: http.StatusForbidden, Code: ForbiddenCode, Msg: "forbidden"}
+	ErrTokenExpired          = Code{HTTPStatus: http.StatusForbidden, Code: TokenExpiredCode, Msg: "authorization expired"}
+	ErrUsernameExisted       = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameExistedCode, Msg: "username had existed"}
+	ErrNotFound              = Code{HTTPStatus: http.StatusNotFound, Code: NotFoundCode, Msg: "not found"}
+	ErrRequestTimeout        = Code{HTTPStatus: http.StatusRequestTimeout, Code: StatusRequestTimeout, Msg: "request timed out"}
+	ErrRequestEntityTooLarge = Code{HTTPStatus: http.StatusRequestEntityTooLarge, Code: RequestEntityTooLarge, Msg: "request body too large"}
+	ErrUnsupportedMediaType  = Code{HTTPStatus: http.StatusUnsupportedMediaType, Code: UnsupportedMediaType, Msg: "unsupported media type"}
+	ErrUnprocessableEntity   = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UnprocessableEntityCode, Msg: "unprocessable entity"}
+	ErrUsernameDuplicate     = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameDuplicateCode, Msg: "username duplicate"}
+	ErrTooManyRequest        = Code{HTTPStatus: http.StatusTooManyRequests, Code: TooManyRequestCode, Msg: "too many requests"}
+	ErrRequestTimeoutCode    = Code{HTTPStatus: http.StatusRequestTimeout, Code: RequestTimeoutCode, Msg: "request timeout"}
+	ErrInternal              = Code{HTTPStatus: http.StatusInternalServerError, Code: InternalCode, Msg: "Unknown error"}
+	ErrUnimplemented         = Code{HTTPStatus: http.StatusInternalServerError, Code: InternalCode, Msg: "Unknown error"}
 )
+
+// Errors lists the distinct error codes, in the order they are documented
+var Errors = []Code{
+	ErrInvalidArgument,
+	ErrInvalidAuth,
+	ErrInvalidAuthMethod,
+	ErrInvalidAuthToken,
+	ErrUnauthorized,
+	ErrAuthorizeFailed,
+	ErrForbidden,
+	ErrTokenExpired,
+	ErrNotFound,
+	ErrRequestTimeout,
+	ErrRequestTimeoutCode,
+	ErrRequestEntityTooLarge,
+	ErrUnsupportedMediaType,
+	ErrUnprocessableEntity,
+	ErrUsernameExisted,
+	ErrUsernameDuplicate,
+	ErrTooManyRequest,
+	ErrInternal,
+}
@@ -0,0 +1,31 @@
+package codes
+
+import "strings"
+
+// FieldError is the failed rule of a request field, Field is the name the client sent and In
+// where it was sent, when the binder rejected it
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	In      string `json:"in,omitempty"`
+	Message string `json:"message"`
+}
+
+// FieldsError is a Code carrying the request fields that failed validation
+type FieldsError struct {
+	Code
+	Fields []FieldError `json:"fields"`
+}
+
+// InvalidFields returns an ErrInvalidArgument listing the failed fields, its message joins theirs
+func InvalidFields(fields ...FieldError) FieldsError {
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Message)
+	}
+	code := ErrInvalidArgument
+	if len(messages) > 0 {
+		code = code.New(strings.Join(messages, "; "))
+	}
+	return FieldsError{Code: code, Fields: fields}
+}
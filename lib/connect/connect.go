@@ -0,0 +1,236 @@
+package connect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/batch"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	grpccodes "google.golang.org/grpc/codes"
+)
+
+// Empty is the request or the response of the methods without fields
+type Empty struct{}
+
+// Service is a curated set of rest routes served as unary rpc methods, over the Connect
+// protocol and gRPC-Web, at /<Name>/<method name>
+type Service struct {
+	// Name is the fully qualified name of the service, e.g. mises.gateway.v1.Gateway
+	Name    string
+	Methods []Method
+}
+
+// Method is a rest route served as a rpc method. The request fields are sent to the route
+// by their tags: param fields in its path, query fields in the query of a GET, the other
+// json fields in the body.
+type Method struct {
+	Name string
+	// Route is the method and the echo path of the route, e.g. "GET /api/v1/user/:uid"
+	Route    string
+	Request  interface{}
+	Response interface{}
+	// Page answers the data and the pagination of the route, in the data and pagination
+	// fields of Response, instead of its data
+	Page bool
+}
+
+// Descriptor describes the service in the .proto descriptor of the gateway
+func (s Service) Descriptor() wire.Service {
+	service := wire.Service{Name: s.Name[strings.LastIndex(s.Name, ".")+1:]}
+	for _, method := range s.Methods {
+		service.Methods = append(service.Methods, wire.ServiceMethod{
+			Name:     method.Name,
+			Request:  reflect.TypeOf(method.Request),
+			Response: reflect.TypeOf(method.Response),
+		})
+	}
+	return service
+}
+
+// Handler serves the methods of s, its route has a :method param. The routes of the methods
+// serve them with their own middleware, so a method has the auth, rate limits and error
+// codes of its route, the code of a rest error is in the Mises-Code header or trailer.
+func Handler(s Service) echo.HandlerFunc {
+	methods := map[string]Method{}
+	for _, method := range s.Methods {
+		methods[method.Name] = method
+	}
+	return func(c echo.Context) error {
+		p, ok := negotiate(c.Request())
+		if !ok {
+			c.Response().Header().Set("Accept-Post", strings.Join(contentTypes, ", "))
+			return c.NoContent(http.StatusUnsupportedMediaType)
+		}
+		method, ok := methods[c.Param("method")]
+		if !ok {
+			return p.writeError(c, &rpcError{code: grpccodes.Unimplemented, message: fmt.Sprintf("%s/%s is not implemented", s.Name, c.Param("method"))})
+		}
+		if timeout, ok := p.timeout(c.Request()); ok {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+		}
+		body, err := io.ReadAll(c.Request().Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return p.writeError(c, codeError(codes.ErrRequestEntityTooLarge))
+		}
+		if err != nil {
+			return err
+		}
+		message, rpcErr := p.readMessage(body)
+		if rpcErr != nil {
+			return p.writeError(c, rpcErr)
+		}
+		request := reflect.New(reflect.TypeOf(method.Request))
+		if err := p.unmarshal(message, request.Interface()); err != nil {
+			return p.writeError(c, codeError(codes.ErrInvalidArgument.Newf("invalid %s message: %v", request.Elem().Type().Name(), err)))
+		}
+		response, rpcErr := method.call(c, request.Elem(), p.json)
+		if rpcErr != nil && errors.Is(c.Request().Context().Err(), context.DeadlineExceeded) {
+			rpcErr = &rpcError{code: grpccodes.DeadlineExceeded, message: "the deadline was exceeded", gatewayCode: rpcErr.gatewayCode}
+		}
+		if rpcErr != nil {
+			return p.writeError(c, rpcErr)
+		}
+		return p.writeMessage(c, response)
+	}
+}
+
+// call serves request with the route of m, it answers the response message in json or protobuf
+func (m Method) call(c echo.Context, request reflect.Value, asJSON bool) ([]byte, *rpcError) {
+	httpMethod, path, _ := strings.Cut(m.Route, " ")
+	query := url.Values{}
+	body := map[string]interface{}{}
+	missing := ""
+	walkFields(request, func(field reflect.StructField, value reflect.Value) {
+		if name := field.Tag.Get("param"); name != "" {
+			param := url.PathEscape(scalar(value))
+			if param == "" && missing == "" {
+				missing = name
+			}
+			path = strings.Replace(path, ":"+name, param, 1)
+			return
+		}
+		if httpMethod == http.MethodGet || httpMethod == http.MethodHead {
+			// the zero values are sent too, the default of a route may differ from them
+			if name := field.Tag.Get("query"); name != "" && !isNil(value) {
+				query.Set(name, queryValue(value))
+			}
+			return
+		}
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			body[name] = value.Interface()
+		}
+	})
+	if missing != "" {
+		return nil, codeError(codes.ErrInvalidArgument.Newf("%s is required", missing))
+	}
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var payload []byte
+	if len(body) > 0 {
+		payload, _ = json.Marshal(body)
+	}
+	req, err := batch.SubRequest(c, httpMethod, target, payload)
+	if err != nil {
+		return nil, codeError(codes.ErrInternal)
+	}
+	resp, _ := batch.Dispatch(c, req)
+
+	envelope := struct {
+		Code       int             `json:"code"`
+		Message    string          `json:"message"`
+		Data       json.RawMessage `json:"data"`
+		Pagination json.RawMessage `json:"pagination"`
+	}{}
+	if err := json.Unmarshal(resp.Body, &envelope); err != nil {
+		return nil, codeError(codes.ErrInternal.Newf("%s answered %d without a json envelope", m.Route, resp.Status))
+	}
+	if resp.Status < http.StatusOK || resp.Status >= http.StatusMultipleChoices || envelope.Code != 0 {
+		return nil, restError(resp.Status, envelope.Code, envelope.Message)
+	}
+	data := []byte(envelope.Data)
+	if m.Page {
+		data, _ = json.Marshal(map[string]json.RawMessage{"data": envelope.Data, "pagination": envelope.Pagination})
+	}
+	if len(data) == 0 || string(data) == "null" {
+		data = []byte("{}")
+	}
+	if asJSON {
+		return data, nil
+	}
+	response := reflect.New(reflect.TypeOf(m.Response))
+	if err := json.Unmarshal(data, response.Interface()); err != nil {
+		return nil, codeError(codes.ErrInternal.Newf("%s does not decode to %s: %v", m.Route, response.Elem().Type().Name(), err))
+	}
+	message, err := wire.MarshalProto(response.Interface())
+	if err != nil {
+		return nil, codeError(codes.ErrInternal)
+	}
+	return message, nil
+}
+
+// walkFields calls fn with the fields of struct v, the fields of embedded structs inlined
+func walkFields(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			walkFields(value, fn)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		fn(field, value)
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	return !v.IsValid() || v.IsZero()
+}
+
+// isNil tells a nil pointer field, which has no value to send
+func isNil(v reflect.Value) bool {
+	return !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil())
+}
+
+// queryValue formats a scalar field like it is written in a query, its zero value included
+func queryValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// scalar formats a scalar field like it is written in a path or a query
+func scalar(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	if isZero(v) && v.Kind() != reflect.Bool {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
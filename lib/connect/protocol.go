@@ -0,0 +1,277 @@
+package connect
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	grpccodes "google.golang.org/grpc/codes"
+)
+
+// contentTypes are the content types of the unary methods, the Connect ones first
+var contentTypes = []string{
+	"application/proto",
+	"application/json",
+	"application/grpc-web",
+	"application/grpc-web+proto",
+	"application/grpc-web+json",
+	"application/grpc-web-text",
+	"application/grpc-web-text+proto",
+	"application/grpc-web-text+json",
+}
+
+// protocol is the encoding of a request and its response
+type protocol struct {
+	contentType string
+	// grpcWeb frames the messages and answers the status in a trailer frame, text base64 encodes them
+	grpcWeb bool
+	text    bool
+	json    bool
+}
+
+func negotiate(r *http.Request) (protocol, bool) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get(echo.HeaderContentType))
+	p := protocol{contentType: contentType}
+	switch contentType {
+	case "application/proto":
+	case "application/json":
+		p.json = true
+	case "application/grpc-web", "application/grpc-web+proto":
+		p.grpcWeb = true
+	case "application/grpc-web+json":
+		p.grpcWeb, p.json = true, true
+	case "application/grpc-web-text", "application/grpc-web-text+proto":
+		p.grpcWeb, p.text = true, true
+	case "application/grpc-web-text+json":
+		p.grpcWeb, p.text, p.json = true, true, true
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// timeout reads the deadline the client set in Connect-Timeout-Ms or Grpc-Timeout
+func (p protocol) timeout(r *http.Request) (time.Duration, bool) {
+	if !p.grpcWeb {
+		ms, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64)
+		if err != nil || ms <= 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	value := r.Header.Get("Grpc-Timeout")
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	unit, ok := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}[value[len(value)-1]]
+	return time.Duration(n) * unit, ok
+}
+
+// readMessage unwraps the request message from its frame
+func (p protocol) readMessage(body []byte) ([]byte, *rpcError) {
+	if !p.grpcWeb {
+		return body, nil
+	}
+	if p.text {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+		if err != nil {
+			return nil, codeError(codes.ErrInvalidArgument.New("invalid base64 body"))
+		}
+		body = decoded
+	}
+	if len(body) < 5 {
+		return nil, codeError(codes.ErrInvalidArgument.New("missing message frame"))
+	}
+	if body[0] != 0 {
+		return nil, codeError(codes.ErrInvalidArgument.New("compressed messages are not supported"))
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) != uint64(size) {
+		return nil, codeError(codes.ErrInvalidArgument.New("the body is not one message frame"))
+	}
+	return body[5:], nil
+}
+
+func (p protocol) unmarshal(message []byte, v interface{}) error {
+	if !p.json {
+		return wire.UnmarshalProto(message, v)
+	}
+	if len(bytes.TrimSpace(message)) == 0 {
+		return nil
+	}
+	return wire.UnmarshalProtoJSON(message, v)
+}
+
+func (p protocol) writeMessage(c echo.Context, message []byte) error {
+	if !p.grpcWeb {
+		return c.Blob(http.StatusOK, p.contentType, message)
+	}
+	body := frame(0, message)
+	body = append(body, trailer(grpccodes.OK, "", 0)...)
+	return p.writeGRPCWeb(c, body)
+}
+
+func (p protocol) writeError(c echo.Context, err *rpcError) error {
+	if err.gatewayCode != 0 {
+		c.Response().Header().Set(headerMisesCode, strconv.Itoa(err.gatewayCode))
+	}
+	if !p.grpcWeb {
+		return c.JSON(connectStatus(err.code), echo.Map{"code": connectCodes[err.code], "message": err.message})
+	}
+	return p.writeGRPCWeb(c, trailer(err.code, err.message, err.gatewayCode))
+}
+
+func (p protocol) writeGRPCWeb(c echo.Context, body []byte) error {
+	if p.text {
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+	}
+	return c.Blob(http.StatusOK, p.contentType, body)
+}
+
+const headerMisesCode = "Mises-Code"
+
+// frame prefixes message with its flags and size
+func frame(flags byte, message []byte) []byte {
+	b := make([]byte, 5, 5+len(message))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(message)))
+	return append(b, message...)
+}
+
+// trailer is the gRPC-Web trailer frame of a status
+func trailer(code grpccodes.Code, message string, gatewayCode int) []byte {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "grpc-status: %d\r\n", code)
+	if message != "" {
+		fmt.Fprintf(b, "grpc-message: %s\r\n", percentEncode(message))
+	}
+	if gatewayCode != 0 {
+		fmt.Fprintf(b, "%s: %d\r\n", strings.ToLower(headerMisesCode), gatewayCode)
+	}
+	return frame(0x80, []byte(b.String()))
+}
+
+// percentEncode escapes a grpc-message like the gRPC spec asks, the bytes outside of
+// printable ascii and the % are written %XX
+func percentEncode(s string) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 0x20 && c <= 0x7e && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(b, "%%%02X", s[i])
+	}
+	return b.String()
+}
+
+// rpcError is the status of a failed method, gatewayCode is the code of the rest error
+type rpcError struct {
+	code        grpccodes.Code
+	message     string
+	gatewayCode int
+}
+
+func codeError(code codes.Code) *rpcError {
+	return restError(code.HTTPStatus, code.Code, code.Msg)
+}
+
+// restError maps a rest error to the status of its http status
+func restError(status, gatewayCode int, message string) *rpcError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &rpcError{code: statusCode(status), message: message, gatewayCode: gatewayCode}
+}
+
+func statusCode(status int) grpccodes.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return grpccodes.InvalidArgument
+	case http.StatusUnauthorized:
+		return grpccodes.Unauthenticated
+	case http.StatusForbidden:
+		return grpccodes.PermissionDenied
+	case http.StatusNotFound:
+		return grpccodes.NotFound
+	case http.StatusConflict:
+		return grpccodes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return grpccodes.ResourceExhausted
+	case 499:
+		return grpccodes.Canceled
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return grpccodes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return grpccodes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return grpccodes.Unavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return grpccodes.Internal
+	}
+	return grpccodes.Unknown
+}
+
+// connectCodes are the names of the codes in the Connect protocol
+var connectCodes = map[grpccodes.Code]string{
+	grpccodes.Canceled:           "canceled",
+	grpccodes.Unknown:            "unknown",
+	grpccodes.InvalidArgument:    "invalid_argument",
+	grpccodes.DeadlineExceeded:   "deadline_exceeded",
+	grpccodes.NotFound:           "not_found",
+	grpccodes.AlreadyExists:      "already_exists",
+	grpccodes.PermissionDenied:   "permission_denied",
+	grpccodes.ResourceExhausted:  "resource_exhausted",
+	grpccodes.FailedPrecondition: "failed_precondition",
+	grpccodes.Aborted:            "aborted",
+	grpccodes.OutOfRange:         "out_of_range",
+	grpccodes.Unimplemented:      "unimplemented",
+	grpccodes.Internal:           "internal",
+	grpccodes.Unavailable:        "unavailable",
+	grpccodes.DataLoss:           "data_loss",
+	grpccodes.Unauthenticated:    "unauthenticated",
+}
+
+// connectStatus is the http status the Connect protocol answers a code with
+func connectStatus(code grpccodes.Code) int {
+	switch code {
+	case grpccodes.Canceled:
+		return 499
+	case grpccodes.InvalidArgument, grpccodes.FailedPrecondition, grpccodes.OutOfRange:
+		return http.StatusBadRequest
+	case grpccodes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case grpccodes.NotFound:
+		return http.StatusNotFound
+	case grpccodes.AlreadyExists, grpccodes.Aborted:
+		return http.StatusConflict
+	case grpccodes.PermissionDenied:
+		return http.StatusForbidden
+	case grpccodes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case grpccodes.Unimplemented:
+		return http.StatusNotImplemented
+	case grpccodes.Unavailable:
+		return http.StatusServiceUnavailable
+	case grpccodes.Unauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,146 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+// BatchFunc loads the values of keys at once, a key missing from the map is not found
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Config of a loader, the zero value batches the loads made within a millisecond
+type Config struct {
+	// Wait is how long the first load of a batch waits for the others
+	Wait time.Duration
+	// MaxBatch dispatches a batch as soon as it has that many keys, 0 is unbounded
+	MaxBatch int
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Loader collects the keys loaded concurrently into batches and caches every key it loaded
+// or was primed with for its whole life, so it lives as long as the request it serves
+type Loader[K comparable, V any] struct {
+	ctx     context.Context
+	batch   BatchFunc[K, V]
+	config  Config
+	mutex   sync.Mutex
+	cache   map[K]*result[V]
+	pending []K
+	timer   *time.Timer
+}
+
+// New returns a loader calling batch with ctx, the context of the request
+func New[K comparable, V any](ctx context.Context, batch BatchFunc[K, V], config Config) *Loader[K, V] {
+	if config.Wait <= 0 {
+		config.Wait = time.Millisecond
+	}
+	return &Loader[K, V]{ctx: ctx, batch: batch, config: config, cache: map[K]*result[V]{}}
+}
+
+// Load returns the value of key, from the cache or from the next batch
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mutex.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.pending = append(l.pending, key)
+		if l.config.MaxBatch > 0 && len(l.pending) >= l.config.MaxBatch {
+			l.dispatchLocked()
+		} else if len(l.pending) == 1 {
+			l.timer = time.AfterFunc(l.config.Wait, l.dispatch)
+		}
+	}
+	l.mutex.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany loads keys in one batch, the values and errors are in the order of keys
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, []error) {
+	values := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	wg := sync.WaitGroup{}
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key K) {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
+	return values, errs
+}
+
+// Prime caches the value of key unless it is already loaded or loading
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	r := &result[V]{done: make(chan struct{}), value: value}
+	close(r.done)
+	l.cache[key] = r
+}
+
+func (l *Loader[K, V]) dispatch() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.dispatchLocked()
+}
+
+// dispatchLocked loads the pending keys in the background
+func (l *Loader[K, V]) dispatchLocked() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if len(l.pending) == 0 {
+		return
+	}
+	keys := l.pending
+	results := make([]*result[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+	l.pending = nil
+	go func() {
+		values, err := l.call(keys)
+		for i, key := range keys {
+			r := results[i]
+			if err != nil {
+				r.err = err
+			} else if value, ok := values[key]; ok {
+				r.value = value
+			} else {
+				r.err = codes.ErrNotFound
+			}
+			close(r.done)
+		}
+	}()
+}
+
+// call runs the batch func, a panic fails the batch instead of leaving its loads waiting
+func (l *Loader[K, V]) call(keys []K) (values map[K]V, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			values, err = nil, codes.ErrInternal.Newf("dataloader batch panic: %v", recovered)
+		}
+	}()
+	return l.batch(l.ctx, keys)
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+
+	MIMEApplicationNDJSON = "application/x-ndjson"
+	MIMETextCSV           = "text/csv"
+)
+
+// Writer encodes exported items one at a time
+type Writer interface {
+	ContentType() string
+	WriteItem(item interface{}) error
+	// WriteCursor records where a resumed export has to start from
+	WriteCursor(cursor string) error
+	// WriteTruncated marks an export stopped before its end, it resumes from the last cursor
+	WriteTruncated() error
+	WriteError(err error) error
+	Flush() error
+}
+
+// NewWriter returns the writer of format, NDJSON is the default
+func NewWriter(format string, w io.Writer) Writer {
+	if strings.EqualFold(format, FormatCSV) {
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+// ndjsonWriter writes one {"item": ...}, {"cursor": ...}, {"truncated": true} or {"error": ...}
+// object per line
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) ContentType() string {
+	return MIMEApplicationNDJSON
+}
+
+func (w *ndjsonWriter) WriteItem(item interface{}) error {
+	return w.encoder.Encode(map[string]interface{}{"item": item})
+}
+
+func (w *ndjsonWriter) WriteCursor(cursor string) error {
+	return w.encoder.Encode(map[string]interface{}{"cursor": cursor})
+}
+
+func (w *ndjsonWriter) WriteTruncated() error {
+	return w.encoder.Encode(map[string]interface{}{"truncated": true})
+}
+
+func (w *ndjsonWriter) WriteError(err error) error {
+	return w.encoder.Encode(map[string]interface{}{"error": err})
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes one row per item, the columns are the top level json keys of the
+// first item and nested values are kept as json. An export which did not end is closed by
+// a cursor,<cursor> row to resume it from, after its error,<message> row if it failed.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	cursor  string
+}
+
+func (w *csvWriter) ContentType() string {
+	return MIMETextCSV
+}
+
+func (w *csvWriter) WriteItem(item interface{}) error {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	fields := map[string]interface{}{}
+	if err = decoder.Decode(&fields); err != nil {
+		return err
+	}
+	if w.columns == nil {
+		for column := range fields {
+			w.columns = append(w.columns, column)
+		}
+		sort.Strings(w.columns)
+		if err = w.w.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		row[i], err = csvCell(fields[column])
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Write(row)
+}
+
+func (w *csvWriter) WriteCursor(cursor string) error {
+	w.cursor = cursor
+	return nil
+}
+
+func (w *csvWriter) WriteTruncated() error {
+	return w.writeTrailer()
+}
+
+func (w *csvWriter) WriteError(err error) error {
+	if err := w.w.Write([]string{"error", err.Error()}); err != nil {
+		return err
+	}
+	return w.writeTrailer()
+}
+
+// writeTrailer writes the cursor row of an export which did not end
+func (w *csvWriter) writeTrailer() error {
+	if w.cursor == "" {
+		return nil
+	}
+	return w.w.Write([]string{"cursor", w.cursor})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func csvCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
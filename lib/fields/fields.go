@@ -0,0 +1,296 @@
+package fields
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+)
+
+const (
+	// FieldsParam is the query parameter listing the fields a client wants
+	FieldsParam = "fields"
+	// ExpandParam is the query parameter listing the nested objects a client wants
+	ExpandParam = "expand"
+)
+
+// node is one level of a field selection tree
+type node struct {
+	// children are the keys kept at this level, nil keeps every key
+	children map[string]*node
+	// expanded are the nested objects kept at this level, nil keeps every nested object
+	expanded map[string]*node
+}
+
+// Selector prunes response payloads down to the fields a client asked for.
+//
+// fields=id,content,user.username keeps only the listed keys, dotted paths select
+// keys of nested objects. expand=user,parent_status keeps only the listed nested
+// objects and drops every other object or object list. A key listed in either
+// parameter is always kept.
+type Selector struct {
+	fields []string
+	expand []string
+	root   *node
+}
+
+// Parse builds a selector from the raw fields and expand query values,
+// it returns nil when the client did not ask for any shaping
+func Parse(fieldsParam, expandParam string) *Selector {
+	fields := splitPaths(fieldsParam)
+	expand := splitPaths(expandParam)
+	if len(fields) == 0 && len(expand) == 0 {
+		return nil
+	}
+	selected := &node{}
+	for _, path := range fields {
+		selected.add(path, false)
+	}
+	expanded := &node{}
+	for _, path := range expand {
+		expanded.add(path, true)
+	}
+	return &Selector{
+		fields: fields,
+		expand: expand,
+		root:   merge(selected, expanded),
+	}
+}
+
+// ErrProtobufSelection answers a selection asked with a protobuf response, the pruned payload has no
+// message type to be encoded as
+var ErrProtobufSelection = codes.ErrInvalidArgument.New("fields and expand are not supported with protobuf")
+
+// FromRequest builds the selector of the request query, it is rejected when the response is negotiated
+// as protobuf
+func FromRequest(c echo.Context) (*Selector, error) {
+	s := Parse(c.QueryParam(FieldsParam), c.QueryParam(ExpandParam))
+	if s != nil && wire.Negotiate(c) == wire.MIMEApplicationProtobuf {
+		return nil, ErrProtobufSelection
+	}
+	return s, nil
+}
+
+// Validate checks every requested path against the json shape of t
+func (s *Selector) Validate(t reflect.Type) error {
+	if s == nil || t == nil {
+		return nil
+	}
+	for _, path := range s.fields {
+		if !hasPath(t, strings.Split(path, ".")) {
+			return codes.ErrInvalidArgument.Newf("invalid field %s", path)
+		}
+	}
+	for _, path := range s.expand {
+		if !hasPath(t, strings.Split(path, ".")) {
+			return codes.ErrInvalidArgument.Newf("invalid expand %s", path)
+		}
+	}
+	return nil
+}
+
+// Apply validates the selection against data and returns the pruned payload
+func (s *Selector) Apply(data interface{}) (interface{}, error) {
+	if s == nil || data == nil {
+		return data, nil
+	}
+	if err := s.Validate(reflect.TypeOf(data)); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return prune(generic, s.root), nil
+}
+
+// String returns the normalized selection, useful as part of a cache key
+func (s *Selector) String() string {
+	if s == nil {
+		return ""
+	}
+	fields := append([]string{}, s.fields...)
+	expand := append([]string{}, s.expand...)
+	sort.Strings(fields)
+	sort.Strings(expand)
+	return FieldsParam + "=" + strings.Join(fields, ",") + "&" + ExpandParam + "=" + strings.Join(expand, ",")
+}
+
+func splitPaths(param string) []string {
+	paths := make([]string, 0)
+	for _, path := range strings.Split(param, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (n *node) add(path string, expand bool) {
+	current := n
+	for _, key := range strings.Split(path, ".") {
+		var next map[string]*node
+		if expand {
+			if current.expanded == nil {
+				current.expanded = map[string]*node{}
+			}
+			next = current.expanded
+		} else {
+			if current.children == nil {
+				current.children = map[string]*node{}
+			}
+			next = current.children
+		}
+		child, ok := next[key]
+		if !ok {
+			child = &node{}
+			next[key] = child
+		}
+		current = child
+	}
+}
+
+// merge combines the fields tree and the expand tree into a single tree
+func merge(selected, expanded *node) *node {
+	if selected == nil && expanded == nil {
+		return nil
+	}
+	if selected == nil {
+		selected = &node{}
+	}
+	if expanded == nil {
+		expanded = &node{}
+	}
+	return &node{
+		children: mergeChildren(selected.children, expanded.children),
+		expanded: mergeChildren(selected.expanded, expanded.expanded),
+	}
+}
+
+func mergeChildren(a, b map[string]*node) map[string]*node {
+	if a == nil && b == nil {
+		return nil
+	}
+	result := map[string]*node{}
+	for key, child := range a {
+		result[key] = merge(child, b[key])
+	}
+	for key, child := range b {
+		if _, ok := result[key]; !ok {
+			result[key] = merge(nil, child)
+		}
+	}
+	return result
+}
+
+func prune(value interface{}, n *node) interface{} {
+	if n == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = prune(item, n)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			selected, inChildren := n.children[key]
+			expanded, inExpanded := n.expanded[key]
+			if n.children != nil && !inChildren && !inExpanded {
+				delete(v, key)
+				continue
+			}
+			if n.expanded != nil && !inChildren && !inExpanded && isNested(item) {
+				delete(v, key)
+				continue
+			}
+			v[key] = prune(item, merge(selected, expanded))
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func isNested(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasPath reports whether the json encoding of t can contain the given key path
+func hasPath(t reflect.Type, keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	t = elem(t)
+	switch t.Kind() {
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		field, ok := jsonField(t, keys[0])
+		if !ok {
+			return false
+		}
+		return hasPath(field, keys[1:])
+	default:
+		return false
+	}
+}
+
+func elem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
+
+func jsonField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := elem(field.Type)
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := jsonField(embedded, key); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == key {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,158 @@
+package flags
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flag is a feature switch. A disabled flag is off for everyone, an enabled one is on for the listed
+// users and devices, and for the given percentage of the other subjects on the listed platforms.
+type Flag struct {
+	Description string   `yaml:"description" json:"description"`
+	Enabled     bool     `yaml:"enabled" json:"enabled"`
+	Percentage  float64  `yaml:"percentage" json:"percentage"`
+	UIDs        []uint64 `yaml:"uids" json:"uids"`
+	MisesIDs    []string `yaml:"misesids" json:"misesids"`
+	DeviceIDs   []string `yaml:"device_ids" json:"device_ids"`
+	// Platforms limits the percentage rollout, e.g. iPhone, Android, Windows
+	Platforms []string `yaml:"platforms" json:"platforms"`
+}
+
+// Subject is who a flag is evaluated for
+type Subject struct {
+	UID      uint64
+	MisesID  string
+	DeviceID string
+	Platform string
+}
+
+// Set holds flags by name, a set is never modified once loaded
+type Set map[string]*Flag
+
+// Enabled evaluates the flag name for subject, unknown flags are off
+func (s Set) Enabled(name string, subject Subject) bool {
+	flag, ok := s[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if subject.UID > 0 && containsUint(flag.UIDs, subject.UID) ||
+		subject.MisesID != "" && contains(flag.MisesIDs, subject.MisesID) ||
+		subject.DeviceID != "" && contains(flag.DeviceIDs, subject.DeviceID) {
+		return true
+	}
+	if len(flag.Platforms) > 0 && !contains(flag.Platforms, subject.Platform) {
+		return false
+	}
+	if flag.Percentage >= 100 {
+		return true
+	}
+	key := subject.key()
+	if key == "" || flag.Percentage <= 0 {
+		return false
+	}
+	return bucket(name, key) < flag.Percentage*100
+}
+
+// Evaluate returns every flag of the set evaluated for subject
+func (s Set) Evaluate(subject Subject) map[string]bool {
+	result := make(map[string]bool, len(s))
+	for name := range s {
+		result[name] = s.Enabled(name, subject)
+	}
+	return result
+}
+
+// key identifies the subject across requests, so a rollout keeps its subjects
+func (subject Subject) key() string {
+	switch {
+	case subject.UID > 0:
+		return "uid:" + strconv.FormatUint(subject.UID, 10)
+	case subject.MisesID != "":
+		return "misesid:" + subject.MisesID
+	case subject.DeviceID != "":
+		return "device:" + subject.DeviceID
+	}
+	return ""
+}
+
+// bucket spreads subjects over 0-9999, salted with the flag name so rollouts of different flags are independent
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + key))
+	return float64(h.Sum32() % 10000)
+}
+
+// Load reads a yaml file of flags by name
+func Load(path string) (Set, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := Set{}
+	if err = yaml.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("parse flags %s: %w", path, err)
+	}
+	for name, flag := range set {
+		if flag == nil {
+			return nil, fmt.Errorf("flag %s: empty definition", name)
+		}
+		if flag.Percentage < 0 || flag.Percentage > 100 {
+			return nil, fmt.Errorf("flag %s: percentage %v out of 0-100", name, flag.Percentage)
+		}
+	}
+	return set, nil
+}
+
+// Store holds the live flag set, the defaults apply to the flags missing from the loaded set
+type Store struct {
+	defaults Set
+	current  atomic.Pointer[Set]
+}
+
+// NewStore returns a store serving defaults until a set is loaded
+func NewStore(defaults Set) *Store {
+	store := &Store{defaults: defaults}
+	store.current.Store(&defaults)
+	return store
+}
+
+// Current returns the live set, requests keep the set they started with across reloads
+func (store *Store) Current() Set {
+	return *store.current.Load()
+}
+
+// Replace swaps the live set for set merged over the defaults
+func (store *Store) Replace(set Set) {
+	merged := make(Set, len(store.defaults)+len(set))
+	for name, flag := range store.defaults {
+		merged[name] = flag
+	}
+	for name, flag := range set {
+		merged[name] = flag
+	}
+	store.current.Store(&merged)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint(values []uint64, value uint64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
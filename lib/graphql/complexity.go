@@ -0,0 +1,524 @@
+package graphql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// sizeArgs are the arguments bounding the length of a list field
+var sizeArgs = []string{"limit", "first", "page_size"}
+
+// keyArgs are the arguments listing the keys of a list field, its length is the one of the list
+var keyArgs = []string{"uids"}
+
+// Complexity is the cost of the operation of query, every field costs 1 plus the cost of its
+// selections, times the length of the list it returns for a list field. The length is the value of its limit,
+// first or page_size argument, the number of its uids, or the one of the page object holding the list,
+// listSize when there is none. A query which does not parse costs 0, its execution reports why.
+func Complexity(schema *types.Schema, query, operationName string, variables map[string]interface{}, listSize int) int {
+	doc, ok := parseDocument(query)
+	if !ok {
+		return 0
+	}
+	var op *operation
+	for _, candidate := range doc.operations {
+		if operationName == "" && len(doc.operations) == 1 || candidate.name == operationName {
+			op = candidate
+			break
+		}
+	}
+	if op == nil {
+		return 0
+	}
+	a := &analysis{schema: schema, doc: doc, variables: variables, listSize: listSize, visiting: map[string]bool{}}
+	return int(a.cost(schema.EntryPoints[op.kind], op.selections, 0))
+}
+
+type analysis struct {
+	schema    *types.Schema
+	doc       *document
+	variables map[string]interface{}
+	listSize  int
+	visiting  map[string]bool
+}
+
+// cost of selections of parent, size is the size argument of the page object parent, 0 outside of a page
+func (a *analysis) cost(parent types.NamedType, selections []*selection, size int64) int64 {
+	total := int64(0)
+	for _, s := range selections {
+		switch {
+		case s.spread != "":
+			fragment, ok := a.doc.fragments[s.spread]
+			// a cycle is rejected by the validation
+			if !ok || a.visiting[s.spread] {
+				continue
+			}
+			a.visiting[s.spread] = true
+			total = saturate(total + a.cost(a.typeOf(fragment.on, parent), fragment.selections, size))
+			a.visiting[s.spread] = false
+		case s.name == "":
+			total = saturate(total + a.cost(a.typeOf(s.on, parent), s.selections, size))
+		default:
+			field := fieldOf(parent, s.name)
+			var (
+				child types.NamedType
+				list  bool
+			)
+			if field != nil {
+				child, list = unwrap(field.Type)
+			}
+			own := a.size(field, s)
+			if !list {
+				cost := saturate(1 + a.cost(child, s.selections, own))
+				total = saturate(total + cost)
+				continue
+			}
+			if own == 0 {
+				own = size
+			}
+			cost := saturate(a.cost(child, s.selections, 0) * a.bound(own))
+			total = saturate(total + 1 + cost)
+		}
+	}
+	return total
+}
+
+// size is the value of the size argument of the field of s, 0 without one
+func (a *analysis) size(field *types.FieldDefinition, s *selection) int64 {
+	if field == nil {
+		return 0
+	}
+	for _, name := range keyArgs {
+		if value, ok := s.args[name]; ok {
+			if value.variable != "" {
+				if keys, ok := a.variables[value.variable].([]interface{}); ok {
+					return int64(len(keys))
+				}
+				continue
+			}
+			if value.items != nil {
+				return *value.items
+			}
+		}
+	}
+	for _, name := range sizeArgs {
+		if value, ok := s.args[name]; ok {
+			if value.variable != "" {
+				if n, ok := number(a.variables[value.variable]); ok {
+					return n
+				}
+				continue
+			}
+			if value.number != nil {
+				return *value.number
+			}
+		}
+		if arg := field.Arguments.Get(name); arg != nil && arg.Default != nil {
+			if n, ok := number(arg.Default.Deserialize(nil)); ok {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// bound counts a missing or negative size as the default list size
+func (a *analysis) bound(n int64) int64 {
+	if n <= 0 {
+		return int64(a.listSize)
+	}
+	return n
+}
+
+func (a *analysis) typeOf(name string, parent types.NamedType) types.NamedType {
+	if name == "" {
+		return parent
+	}
+	return a.schema.Types[name]
+}
+
+func fieldOf(parent types.NamedType, name string) *types.FieldDefinition {
+	switch t := parent.(type) {
+	case *types.ObjectTypeDefinition:
+		return t.Fields.Get(name)
+	case *types.InterfaceTypeDefinition:
+		return t.Fields.Get(name)
+	}
+	return nil
+}
+
+// unwrap returns the named type of t and whether it is a list
+func unwrap(t types.Type) (types.NamedType, bool) {
+	list := false
+	for {
+		switch wrapper := t.(type) {
+		case *types.NonNull:
+			t = wrapper.OfType
+		case *types.List:
+			list = true
+			t = wrapper.OfType
+		case types.NamedType:
+			return wrapper, list
+		default:
+			return nil, list
+		}
+	}
+}
+
+func number(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// saturate keeps the cost of a hostile query from overflowing
+func saturate(n int64) int64 {
+	if n > math.MaxInt32 || n < 0 {
+		return math.MaxInt32
+	}
+	return n
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	selections []*selection
+}
+
+type fragment struct {
+	on         string
+	selections []*selection
+}
+
+// selection is a field, a fragment spread or, without name and spread, an inline fragment
+type selection struct {
+	name       string
+	args       map[string]argument
+	spread     string
+	on         string
+	selections []*selection
+}
+
+// argument keeps the integer literals, the length of the list literals and the variables, the
+// only values the cost reads
+type argument struct {
+	variable string
+	number   *int64
+	items    *int64
+}
+
+// parseDocument reads the operations and fragments of an executable document, it is lenient
+// as the validation of the schema reports the errors
+func parseDocument(source string) (doc *document, ok bool) {
+	defer func() {
+		if recover() != nil {
+			doc, ok = nil, false
+		}
+	}()
+	p := &parser{lexer: lexer{source: source}}
+	p.next()
+	doc = &document{fragments: map[string]*fragment{}}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.token.is(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.selectionSet()})
+		case p.token.is(tokenName, "fragment"):
+			p.next()
+			name := p.expect(tokenName)
+			p.expectValue(tokenName, "on")
+			f := &fragment{on: p.expect(tokenName)}
+			p.directives()
+			f.selections = p.selectionSet()
+			doc.fragments[name] = f
+		case p.token.kind == tokenName:
+			op := &operation{kind: p.token.value}
+			p.next()
+			if p.token.kind == tokenName {
+				op.name = p.expect(tokenName)
+			}
+			if p.token.is(tokenPunct, "(") {
+				p.skipGroup("(", ")")
+			}
+			p.directives()
+			op.selections = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		default:
+			panic("unexpected token")
+		}
+	}
+	return doc, true
+}
+
+type parser struct {
+	lexer lexer
+	token token
+}
+
+func (p *parser) next() {
+	p.token = p.lexer.next()
+}
+
+func (p *parser) expect(kind tokenKind) string {
+	if p.token.kind != kind {
+		panic("unexpected token")
+	}
+	value := p.token.value
+	p.next()
+	return value
+}
+
+func (p *parser) expectValue(kind tokenKind, value string) {
+	if !p.token.is(kind, value) {
+		panic("unexpected token")
+	}
+	p.next()
+}
+
+func (p *parser) selectionSet() []*selection {
+	p.expectValue(tokenPunct, "{")
+	selections := []*selection{}
+	for !p.token.is(tokenPunct, "}") {
+		selections = append(selections, p.selection())
+	}
+	p.next()
+	return selections
+}
+
+func (p *parser) selection() *selection {
+	if p.token.is(tokenPunct, "...") {
+		p.next()
+		s := &selection{}
+		if p.token.kind == tokenName && p.token.value != "on" {
+			s.spread = p.expect(tokenName)
+			p.directives()
+			return s
+		}
+		if p.token.is(tokenName, "on") {
+			p.next()
+			s.on = p.expect(tokenName)
+		}
+		p.directives()
+		s.selections = p.selectionSet()
+		return s
+	}
+	s := &selection{name: p.expect(tokenName)}
+	if p.token.is(tokenPunct, ":") {
+		p.next()
+		s.name = p.expect(tokenName)
+	}
+	if p.token.is(tokenPunct, "(") {
+		s.args = p.arguments()
+	}
+	p.directives()
+	if p.token.is(tokenPunct, "{") {
+		s.selections = p.selectionSet()
+	}
+	return s
+}
+
+func (p *parser) arguments() map[string]argument {
+	args := map[string]argument{}
+	p.expectValue(tokenPunct, "(")
+	for !p.token.is(tokenPunct, ")") {
+		name := p.expect(tokenName)
+		p.expectValue(tokenPunct, ":")
+		args[name] = p.value()
+	}
+	p.next()
+	return args
+}
+
+func (p *parser) value() argument {
+	switch {
+	case p.token.is(tokenPunct, "$"):
+		p.next()
+		return argument{variable: p.expect(tokenName)}
+	case p.token.kind == tokenInt:
+		n, err := strconv.ParseInt(p.token.value, 10, 64)
+		p.next()
+		if err != nil {
+			return argument{}
+		}
+		return argument{number: &n}
+	case p.token.is(tokenPunct, "["):
+		p.next()
+		n := int64(0)
+		for !p.token.is(tokenPunct, "]") {
+			if p.token.kind == tokenEOF {
+				panic("unexpected end")
+			}
+			p.value()
+			n++
+		}
+		p.next()
+		return argument{items: &n}
+	case p.token.is(tokenPunct, "{"):
+		p.skipGroup("{", "}")
+	default:
+		p.next()
+	}
+	return argument{}
+}
+
+func (p *parser) directives() {
+	for p.token.is(tokenPunct, "@") {
+		p.next()
+		p.expect(tokenName)
+		if p.token.is(tokenPunct, "(") {
+			p.skipGroup("(", ")")
+		}
+	}
+}
+
+// skipGroup skips a balanced group of tokens, e.g. the variable definitions
+func (p *parser) skipGroup(open, close string) {
+	depth := 0
+	for {
+		switch {
+		case p.token.kind == tokenEOF:
+			panic("unexpected end")
+		case p.token.is(tokenPunct, open):
+			depth++
+		case p.token.is(tokenPunct, close):
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func (t token) is(kind tokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+func (l *lexer) next() token {
+	l.skipIgnored()
+	if l.pos >= len(l.source) {
+		return token{kind: tokenEOF}
+	}
+	start := l.pos
+	c := l.source[l.pos]
+	switch {
+	case strings.HasPrefix(l.source[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "..."}
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c)}
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.source) && (l.source[l.pos] == '_' || isLetter(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.source[start:l.pos]}
+	case c == '-' || isDigit(c):
+		kind := tokenInt
+		l.pos++
+		for l.pos < len(l.source) {
+			c := l.source[l.pos]
+			if c == '.' || c == 'e' || c == 'E' || c == '+' || (c == '-' && kind == tokenFloat) {
+				kind = tokenFloat
+			} else if !isDigit(c) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: kind, value: l.source[start:l.pos]}
+	case strings.HasPrefix(l.source[l.pos:], `"""`):
+		end := strings.Index(l.source[l.pos+3:], `"""`)
+		for end >= 0 && l.source[l.pos+3+end-1] == '\\' {
+			next := strings.Index(l.source[l.pos+3+end+3:], `"""`)
+			if next < 0 {
+				end = -1
+				break
+			}
+			end += 3 + next
+		}
+		if end < 0 {
+			panic("unterminated string")
+		}
+		l.pos += 3 + end + 3
+		return token{kind: tokenString, value: l.source[start:l.pos]}
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.source) && l.source[l.pos] != '"' {
+			if l.source[l.pos] == '\\' {
+				l.pos++
+			}
+			if l.source[l.pos] == '\n' {
+				panic("unterminated string")
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.source) {
+			panic("unterminated string")
+		}
+		l.pos++
+		return token{kind: tokenString, value: l.source[start:l.pos]}
+	}
+	panic("unexpected character")
+}
+
+// skipIgnored skips the white space, the commas and the comments
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.source) {
+		switch c := l.source[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case strings.HasPrefix(l.source[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		case c == '#':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' && l.source[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
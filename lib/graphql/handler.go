@@ -0,0 +1,214 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/cache"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+)
+
+// Config of a graphql handler
+type Config struct {
+	// MaxDepth rejects the queries whose fields are nested deeper, 0 disables it
+	MaxDepth int
+	// MaxComplexity rejects the queries costing more, 0 disables it, see Complexity
+	MaxComplexity int
+	// ListSize is the length assumed of a list field without a size argument
+	ListSize int
+	// MaxBatch is the most operations of a batched request
+	MaxBatch int
+	// PersistedQueries keeps the queries by their hash, nil disables the persisted queries
+	PersistedQueries     cache.Store
+	PersistedQueryTTL    time.Duration
+	DisableIntrospection bool
+	// Context adds the values of a request, like its dataloaders, to the context of the resolvers,
+	// it is called once per http request so the operations of a batch share them
+	Context func(ctx context.Context, c echo.Context) context.Context
+}
+
+// Request is an operation, sent as a json body, in a json array of a batch, or as query params
+// of a GET whose variables and extensions are json encoded
+type Request struct {
+	Query         string                 `json:"query" query:"query"`
+	OperationName string                 `json:"operationName" query:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    *Extensions            `json:"extensions"`
+}
+
+// Handler serves the operations of a schema
+type Handler struct {
+	schema *graphqlgo.Schema
+	config Config
+}
+
+type echoContextKey struct{}
+
+// New parses the schema sdl, resolved by the methods and fields of resolver
+func New(sdl string, resolver interface{}, config Config) (*Handler, error) {
+	opts := []graphqlgo.SchemaOpt{graphqlgo.UseFieldResolvers(), graphqlgo.MaxDepth(config.MaxDepth)}
+	if config.DisableIntrospection {
+		opts = append(opts, graphqlgo.DisableIntrospection())
+	}
+	schema, err := graphqlgo.ParseSchema(sdl, resolver, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxBatch <= 0 {
+		config.MaxBatch = 1
+	}
+	return &Handler{schema: schema, config: config}, nil
+}
+
+// EchoContext is the request of the resolver context ctx, for the helpers shared with the rest handlers
+func EchoContext(ctx context.Context) echo.Context {
+	c, _ := ctx.Value(echoContextKey{}).(echo.Context)
+	return c
+}
+
+// Serve answers the operations of the request, the errors of an operation are in its response
+// so a batch answers every operation, the errors of the request are answered by WriteError
+func (h *Handler) Serve(c echo.Context) error {
+	requests, batch, err := h.requests(c)
+	if err != nil {
+		return WriteError(c, err)
+	}
+	ctx := context.WithValue(c.Request().Context(), echoContextKey{}, c)
+	if h.config.Context != nil {
+		ctx = h.config.Context(ctx, c)
+	}
+	responses := make([]*graphqlgo.Response, len(requests))
+	// the operations of a batch run together, so their dataloaders batch their loads
+	wg := sync.WaitGroup{}
+	for i, r := range requests {
+		wg.Add(1)
+		go func(i int, r *Request) {
+			defer wg.Done()
+			responses[i] = h.exec(ctx, c, r)
+		}(i, r)
+	}
+	wg.Wait()
+	if batch {
+		return c.JSON(http.StatusOK, responses)
+	}
+	return c.JSON(http.StatusOK, responses[0])
+}
+
+func (h *Handler) exec(ctx context.Context, c echo.Context, r *Request) *graphqlgo.Response {
+	query, queryErr := h.query(ctx, r)
+	if queryErr != nil {
+		return &graphqlgo.Response{Errors: []*errors.QueryError{queryErr}}
+	}
+	if h.config.MaxComplexity > 0 {
+		complexity := Complexity(h.schema.ASTSchema(), query, r.OperationName, r.Variables, h.config.ListSize)
+		if complexity > h.config.MaxComplexity {
+			return &graphqlgo.Response{Errors: []*errors.QueryError{queryError(codes.ErrInvalidArgument.Newf(
+				"query has complexity %d that exceeds max complexity %d", complexity, h.config.MaxComplexity))}}
+		}
+	}
+	resp := h.schema.Exec(ctx, query, r.OperationName, r.Variables)
+	for _, queryErr := range resp.Errors {
+		if queryErr.ResolverError == nil {
+			// syntax and validation errors
+			setCode(queryErr, codes.ErrInvalidArgument, nil)
+			continue
+		}
+		code, fields := mw.ErrorCode(queryErr.ResolverError)
+		if code.HTTPStatus >= http.StatusInternalServerError {
+			logging.FromContext(c).WithField("path", queryErr.Path).Error(queryErr.ResolverError)
+		}
+		queryErr.Message = code.Msg
+		setCode(queryErr, code, fields)
+	}
+	return resp
+}
+
+// requests reads the operations of a GET or of a POST body, batch tells if the body is an array
+func (h *Handler) requests(c echo.Context) ([]*Request, bool, error) {
+	if c.Request().Method == http.MethodGet {
+		params := c.QueryParams()
+		r := &Request{Query: params.Get("query"), OperationName: params.Get("operationName")}
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &r.Variables); err != nil {
+				return nil, false, codes.ErrInvalidArgument.New("variables is not a json object")
+			}
+		}
+		if extensions := params.Get("extensions"); extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &r.Extensions); err != nil {
+				return nil, false, codes.ErrInvalidArgument.New("extensions is not a json object")
+			}
+		}
+		return []*Request{r}, false, nil
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		return nil, false, codes.ErrRequestEntityTooLarge
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		requests := []*Request{}
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return nil, true, codes.ErrInvalidArgument.New("invalid graphql batch")
+		}
+		if len(requests) == 0 || len(requests) > h.config.MaxBatch {
+			return nil, true, codes.ErrInvalidArgument.Newf("a batch has between 1 and %d operations", h.config.MaxBatch)
+		}
+		for _, r := range requests {
+			if r == nil {
+				return nil, true, codes.ErrInvalidArgument.New("invalid graphql batch")
+			}
+		}
+		return requests, true, nil
+	}
+	r := &Request{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, false, codes.ErrInvalidArgument.New("invalid graphql request")
+	}
+	return []*Request{r}, false, nil
+}
+
+// WriteError answers err as the errors of a graphql response, with the http status of its code
+// and the code of the rest responses in its extensions
+func WriteError(c echo.Context, err error) error {
+	code, fields := mw.ErrorCode(err)
+	if code.HTTPStatus >= http.StatusInternalServerError {
+		logging.FromContext(c).Error(err)
+	}
+	queryErr := &errors.QueryError{Message: code.Msg}
+	setCode(queryErr, code, fields)
+	return c.JSON(code.HTTPStatus, &graphqlgo.Response{Errors: []*errors.QueryError{queryErr}})
+}
+
+// queryError is an error of the request rather than of a field
+func queryError(code codes.Code) *errors.QueryError {
+	queryErr := &errors.QueryError{Message: code.Msg}
+	setCode(queryErr, code, nil)
+	return queryErr
+}
+
+// setCode adds the code of the rest responses to the extensions of the error
+func setCode(queryErr *errors.QueryError, code codes.Code, fields []codes.FieldError) {
+	if queryErr.Extensions == nil {
+		queryErr.Extensions = map[string]interface{}{}
+	}
+	queryErr.Extensions["code"] = code.Code
+	if len(fields) > 0 {
+		queryErr.Extensions["fields"] = fields
+	}
+}
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+const (
+	// PersistedQueryNotFound asks the client to send the query along its hash, the clients
+	// of the automatic persisted queries protocol match this message
+	PersistedQueryNotFound     = "PersistedQueryNotFound"
+	PersistedQueryNotSupported = "PersistedQueryNotSupported"
+
+	// persistedQueryPrefix starts the keys of the persisted queries in their store, the store
+	// of the gateway adds its own prefix
+	persistedQueryPrefix = "persisted:"
+)
+
+// Extensions of a request
+type Extensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// PersistedQuery refers to a query by the sha256 hash of its text
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// query is the text of the query of r, read from the persisted queries when r only has its hash,
+// a query sent with its hash is persisted
+func (h *Handler) query(ctx context.Context, r *Request) (string, *errors.QueryError) {
+	if r.Extensions == nil || r.Extensions.PersistedQuery == nil {
+		if strings.TrimSpace(r.Query) == "" {
+			return "", queryError(codes.ErrInvalidArgument.New("query is required"))
+		}
+		return r.Query, nil
+	}
+	store := h.config.PersistedQueries
+	if store == nil {
+		return "", queryError(codes.ErrInvalidArgument.New(PersistedQueryNotSupported))
+	}
+	persisted := r.Extensions.PersistedQuery
+	if persisted.Version != 1 {
+		return "", queryError(codes.ErrInvalidArgument.Newf("unsupported persisted query version %d", persisted.Version))
+	}
+	hash := strings.ToLower(persisted.Sha256Hash)
+	if r.Query == "" {
+		query, ok, err := store.Get(ctx, persistedQueryPrefix+hash)
+		if err != nil || !ok {
+			return "", queryError(codes.ErrNotFound.New(PersistedQueryNotFound))
+		}
+		return string(query), nil
+	}
+	sum := sha256.Sum256([]byte(r.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return "", queryError(codes.ErrInvalidArgument.New("provided sha does not match query"))
+	}
+	// a broken store only costs the client a retry with the query, so the write error is dropped
+	_ = store.Set(ctx, persistedQueryPrefix+hash, []byte(r.Query), h.config.PersistedQueryTTL)
+	return r.Query, nil
+}
@@ -0,0 +1,40 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Uint64 is the scalar of the ids, like the uid, which do not fit the 32 bits of an Int,
+// it is written as a json number like in the rest responses and read from a number or a string
+type Uint64 uint64
+
+func (Uint64) ImplementsGraphQLType(name string) bool {
+	return name == "Uint64"
+}
+
+func (n *Uint64) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case int32:
+		if input >= 0 {
+			*n = Uint64(input)
+			return nil
+		}
+	case float64:
+		if input >= 0 && input == float64(uint64(input)) {
+			*n = Uint64(input)
+			return nil
+		}
+	case string:
+		value, err := strconv.ParseUint(input, 10, 64)
+		if err == nil {
+			*n = Uint64(value)
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not an Uint64", input)
+}
+
+func (n Uint64) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(n), 10), nil
+}
@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats of the log lines
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// loggerKey is the echo context key of the request logger
+const loggerKey = "logger"
+
+// errorKey is the echo context key of an error already answered, logged with the access line
+const errorKey = "logger.error"
+
+// DeviceIDHeader is the header the apps send their device id in
+const DeviceIDHeader = "mises-device-id"
+
+// Setup sets the redacting formatter of the standard logger. The json lines keep the field
+// names of the stackdriver format the gateway always logged in.
+func Setup(format string, service string) {
+	var formatter logrus.Formatter
+	switch format {
+	case FormatText:
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+	default:
+		formatter = &serviceFormatter{
+			service: service,
+			Formatter: &logrus.JSONFormatter{
+				TimestampFormat: time.RFC3339,
+				FieldMap: logrus.FieldMap{
+					logrus.FieldKeyTime:  "timestamp",
+					logrus.FieldKeyLevel: "severity",
+					logrus.FieldKeyMsg:   "message",
+				},
+			},
+		}
+	}
+	logrus.SetFormatter(&RedactingFormatter{Formatter: formatter})
+}
+
+// serviceFormatter adds the serviceContext error reporting groups the lines by, and writes the
+// severity upper case like the gateway always did
+type serviceFormatter struct {
+	logrus.Formatter
+	service string
+}
+
+func (f *serviceFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Data["serviceContext"] = map[string]string{"service": f.service}
+	line, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	// the quotes of the keys and values written by the formatter are escaped, so only the
+	// severity field matches
+	level := entry.Level.String()
+	return bytes.Replace(line, []byte(`"severity":"`+level+`"`), []byte(`"severity":"`+strings.ToUpper(level)+`"`), 1), nil
+}
+
+// accessSampleRatio holds the float64 bits of the share of 2xx access lines written
+var accessSampleRatio atomic.Uint64
+
+func init() {
+	SetAccessSampleRatio(1)
+}
+
+// SetAccessSampleRatio sets the share of the 2xx access lines written, the others are always written
+func SetAccessSampleRatio(ratio float64) {
+	accessSampleRatio.Store(math.Float64bits(ratio))
+}
+
+func sampled(status int) bool {
+	if status >= http.StatusMultipleChoices {
+		return true
+	}
+	ratio := math.Float64frombits(accessSampleRatio.Load())
+	return ratio >= 1 || rand.Float64() < ratio
+}
+
+// Middleware carries the request logger in the echo context and writes an access line once the
+// handler returns, at warn level for 4xx and error level for 5xx. skipper skips the access line only.
+// It runs after the request id middleware so the lines carry the generated ids.
+func Middleware(skipper func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.Set(loggerKey, fields(c))
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// write the error now so the line reports its status, echo skips committed responses
+				c.Error(err)
+			}
+			if skipper != nil && skipper(c) {
+				return err
+			}
+			res := c.Response()
+			if !sampled(res.Status) {
+				return err
+			}
+			latency := time.Since(start)
+			entry := FromContext(c).WithFields(logrus.Fields{
+				"remote_ip":           c.RealIP(),
+				"host":                req.Host,
+				"method":              req.Method,
+				"uri":                 req.RequestURI,
+				"user_agent":          req.UserAgent(),
+				"status":              res.Status,
+				"bytes_out":           res.Size,
+				"latency":             latency.Seconds(),
+				"latency_human":       latency.String(),
+				"user_wallet_address": req.Header.Get("User-Wallet-Address"),
+			})
+			if err != nil {
+				entry = entry.WithField("error", err)
+			} else if answered, ok := c.Get(errorKey).(error); ok {
+				entry = entry.WithField("error", answered)
+			}
+			level := logrus.InfoLevel
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				level = logrus.ErrorLevel
+			case res.Status >= http.StatusBadRequest:
+				level = logrus.WarnLevel
+			}
+			entry.Logf(level, "%s %d %s %s", c.RealIP(), res.Status, req.Method, req.RequestURI)
+			return err
+		}
+	}
+}
+
+// SetError keeps err, already answered by the handler chain, for the access line so it is logged
+// once, it is false outside of Middleware where the caller logs it itself
+func SetError(c echo.Context, err error) bool {
+	if _, ok := c.Get(loggerKey).(*logrus.Entry); !ok {
+		return false
+	}
+	c.Set(errorKey, err)
+	return true
+}
+
+// FromContext is the logger of the request, its lines carry the request_id, trace_id, route,
+// uid and device_id. Outside of Middleware it is the standard logger with the fields of c.
+func FromContext(c echo.Context) *logrus.Entry {
+	entry, ok := c.Get(loggerKey).(*logrus.Entry)
+	if !ok {
+		entry = fields(c)
+	}
+	// the trace and the user are known once the tracing and session middleware ran
+	if span := trace.SpanContextFromContext(c.Request().Context()); span.HasTraceID() {
+		entry = entry.WithField("trace_id", span.TraceID().String())
+	}
+	if uid, ok := c.Get("CurrentUID").(uint64); ok && uid > 0 {
+		entry = entry.WithField("uid", uid)
+	}
+	return entry
+}
+
+func fields(c echo.Context) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"route":      route(c),
+		"device_id":  c.Request().Header.Get(DeviceIDHeader),
+	})
+}
+
+// requestID is the id of the caller or the one the request id middleware generated
+func requestID(c echo.Context) string {
+	if id := c.Request().Header.Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+func route(c echo.Context) string {
+	if path := c.Path(); path != "" {
+		return path
+	}
+	return c.Request().URL.Path
+}
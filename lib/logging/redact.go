@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[redacted]"
+
+// Rule masks the matches of Pattern in the logged messages and string fields
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Mask    func(match string) string
+}
+
+// Rules are applied in order, tokens first so a jwt is not masked as something else
+var Rules = []Rule{
+	{Name: "bearer", Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`), Mask: func(string) string { return "Bearer " + redacted }},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Mask: func(string) string { return redacted }},
+	{Name: "token", Pattern: regexp.MustCompile(`(?i)\b(token|access_token|refresh_token|sig|signature|password|secret)=[^&\s"]+`), Mask: maskParam},
+	{Name: "email", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Mask: maskEmail},
+	{Name: "wallet", Pattern: regexp.MustCompile(`\b(0x[0-9a-fA-F]{40}|mises1[02-9ac-hj-np-z]{38})\b`), Mask: maskMiddle},
+	{Name: "mobile", Pattern: regexp.MustCompile(`(\+\d{1,3}[ -]?\d{6,14}|\b1[3-9]\d{9})\b`), Mask: maskMobile},
+}
+
+// SensitiveFields are redacted whatever their value
+var SensitiveFields = map[string]bool{
+	"authorization": true,
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"sig":           true,
+}
+
+// Redact masks the wallet addresses, emails, mobile numbers and tokens of s
+func Redact(s string) string {
+	for _, rule := range Rules {
+		s = rule.Pattern.ReplaceAllStringFunc(s, rule.Mask)
+	}
+	return s
+}
+
+// maskParam keeps the parameter name
+func maskParam(match string) string {
+	return match[:strings.IndexByte(match, '=')+1] + redacted
+}
+
+// maskEmail keeps the domain
+func maskEmail(match string) string {
+	return "***" + match[strings.LastIndexByte(match, '@'):]
+}
+
+// maskMiddle keeps the prefix and the last characters, enough to tell two addresses apart
+func maskMiddle(match string) string {
+	return match[:6] + "..." + match[len(match)-4:]
+}
+
+// maskMobile keeps the last 4 digits
+func maskMobile(match string) string {
+	return "***" + match[len(match)-4:]
+}
+
+// RedactingFormatter redacts the message and the fields of the entries before Formatter writes them
+type RedactingFormatter struct {
+	logrus.Formatter
+}
+
+// Format formats a redacted copy of entry, the fields of the caller are left untouched
+func (f *RedactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	copied := *entry
+	copied.Message = Redact(entry.Message)
+	copied.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		copied.Data[key] = redactField(key, value)
+	}
+	return f.Formatter.Format(&copied)
+}
+
+func redactField(key string, value interface{}) interface{} {
+	if SensitiveFields[strings.ToLower(key)] {
+		return redacted
+	}
+	switch v := value.(type) {
+	case string:
+		return Redact(v)
+	case error:
+		return Redact(v.Error())
+	case fmt.Stringer:
+		return Redact(v.String())
+	}
+	return value
+}
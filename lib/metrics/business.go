@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes of the business operations
+const (
+	OutcomeOK = "ok"
+	// OutcomeError is a call the backend answered with an error for the item, e.g. SwapQuoteInfo.Error
+	OutcomeError = "error"
+	// OutcomeFailed is a call that did not get an answer from the backend
+	OutcomeFailed = "failed"
+)
+
+const (
+	// LabelOther replaces the values of a label once its limit is reached
+	LabelOther = "other"
+	// LabelUnknown replaces empty values
+	LabelUnknown = "unknown"
+)
+
+// BoundedLabel admits up to Max distinct values and reports the rest as LabelOther,
+// so values taken from requests can't grow the series without limit
+type BoundedLabel struct {
+	Max    int
+	mutex  sync.Mutex
+	values map[string]struct{}
+}
+
+// NewBoundedLabel creates a label admitting max distinct values
+func NewBoundedLabel(max int) *BoundedLabel {
+	return &BoundedLabel{Max: max, values: map[string]struct{}{}}
+}
+
+// Value returns v if it was seen before or there is room for it, LabelOther otherwise
+func (l *BoundedLabel) Value(v string) string {
+	if v == "" {
+		return LabelUnknown
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.values[v]; ok {
+		return v
+	}
+	if len(l.values) >= l.Max {
+		return LabelOther
+	}
+	l.values[v] = struct{}{}
+	return v
+}
+
+// AllowedLabel reports the values of its allowlist and LabelOther for the rest, a value is added
+// with Allow once the backend confirmed it, up to Max values
+type AllowedLabel struct {
+	Max    int
+	mutex  sync.RWMutex
+	values map[string]struct{}
+}
+
+// NewAllowedLabel creates a label admitting the known values and up to max values in all
+func NewAllowedLabel(max int, known ...string) *AllowedLabel {
+	l := &AllowedLabel{Max: max, values: map[string]struct{}{}}
+	for _, v := range known {
+		l.values[strings.ToLower(v)] = struct{}{}
+	}
+	return l
+}
+
+// Allow admits v, e.g. a pair the backend created a transaction for, unless the label is full
+func (l *AllowedLabel) Allow(v string) {
+	if v == "" {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.values) < l.Max {
+		l.values[strings.ToLower(v)] = struct{}{}
+	}
+}
+
+// Value returns v lower cased if it is admitted, LabelOther otherwise
+func (l *AllowedLabel) Value(v string) string {
+	if v == "" {
+		return LabelUnknown
+	}
+	v = strings.ToLower(v)
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if _, ok := l.values[v]; ok {
+		return v
+	}
+	return LabelOther
+}
+
+var (
+	// the chains and aggregators the swap backend supports, the others are reported as LabelOther
+	chainLabel = NewAllowedLabel(50,
+		"1", "10", "25", "56", "100", "137", "250", "324", "1101", "8453", "42161", "43114", "59144", "1313161554")
+	aggregatorLabel = NewAllowedLabel(50,
+		"1inch", "0x", "paraswap", "openocean", "kyberswap", "dodo", "uniswap", "sushiswap", "pancakeswap", "odos", "lifi")
+	// pairLabel admits the pairs the backend created a transaction for
+	pairLabel      = NewAllowedLabel(200)
+	adNetworkLabel = NewBoundedLabel(10)
+	routeLabel     = NewBoundedLabel(200)
+
+	swapQuotes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swap_quotes_total",
+		Help:      "Swap quotes returned by the aggregators, by chain, aggregator and outcome.",
+	}, []string{"chain", "aggregator", "outcome"})
+	swapQuoteAggregators = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "swap_quote_aggregators",
+		Help:      "Aggregators answering a swap quote without error, by chain.",
+		Buckets:   []float64{0, 1, 2, 3, 4, 5, 6, 8, 10, 15},
+	}, []string{"chain"})
+	swapTrades = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swap_trades_total",
+		Help:      "Swap trade requests, by chain and outcome.",
+	}, []string{"chain", "outcome"})
+	bridgeTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bridge_transactions_total",
+		Help:      "Bridge transactions created, by rate type (float or fixed), currency pair and outcome.",
+	}, []string{"rate", "pair", "outcome"})
+	adCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ad_callbacks_total",
+		Help:      "Ad network reward callbacks, by network and outcome.",
+	}, []string{"network", "outcome"})
+	miningActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mining_actions_total",
+		Help:      "Ad mining logs and bonus redemptions, by action and outcome.",
+	}, []string{"action", "outcome"})
+	airdropClaims = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mb_airdrop_claims_total",
+		Help:      "MB airdrop claims, by outcome.",
+	}, []string{"outcome"})
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests denied by a rate limiter, by route.",
+	}, []string{"route"})
+)
+
+func init() {
+	prometheus.MustRegister(swapQuotes, swapQuoteAggregators, swapTrades, bridgeTransactions, adCallbacks, miningActions, airdropClaims, rateLimited)
+}
+
+// Outcome is OutcomeFailed when err is set, OutcomeOK otherwise
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailed
+	}
+	return OutcomeOK
+}
+
+// ItemOutcome is the outcome of an answer carrying its own error message
+func ItemOutcome(errMsg string) string {
+	if errMsg != "" {
+		return OutcomeError
+	}
+	return OutcomeOK
+}
+
+func chainValue(chainID uint64) string {
+	return chainLabel.Value(strconv.FormatUint(chainID, 10))
+}
+
+// CountSwapQuote counts a quote of aggregator on chainID
+func CountSwapQuote(chainID uint64, aggregator, outcome string) {
+	swapQuotes.WithLabelValues(chainValue(chainID), aggregatorLabel.Value(aggregator), outcome).Inc()
+}
+
+// ObserveSwapQuoteAggregators records how many aggregators answered a quote on chainID
+func ObserveSwapQuoteAggregators(chainID uint64, answered int) {
+	swapQuoteAggregators.WithLabelValues(chainValue(chainID)).Observe(float64(answered))
+}
+
+// CountSwapTrade counts a trade request on chainID
+func CountSwapTrade(chainID uint64, outcome string) {
+	swapTrades.WithLabelValues(chainValue(chainID), outcome).Inc()
+}
+
+// CountBridgeTransaction counts a bridge transaction, rate is "float" or "fixed" and pair is "from/to",
+// the pair is a label once the backend created a transaction for it
+func CountBridgeTransaction(rate, pair, outcome string) {
+	if outcome == OutcomeOK {
+		pairLabel.Allow(pair)
+	}
+	bridgeTransactions.WithLabelValues(rate, pairLabel.Value(pair), outcome).Inc()
+}
+
+// CountAdCallback counts a reward callback of an ad network
+func CountAdCallback(network, outcome string) {
+	adCallbacks.WithLabelValues(adNetworkLabel.Value(network), outcome).Inc()
+}
+
+// CountMiningAction counts an ad mining log or a bonus redemption
+func CountMiningAction(action, outcome string) {
+	miningActions.WithLabelValues(action, outcome).Inc()
+}
+
+// CountAirdropClaim counts a MB airdrop claim
+func CountAirdropClaim(outcome string) {
+	airdropClaims.WithLabelValues(outcome).Inc()
+}
+
+// CountRateLimited counts a request denied by a rate limiter on route
+func CountRateLimited(route string) {
+	rateLimited.WithLabelValues(routeLabel.Value(route)).Inc()
+}
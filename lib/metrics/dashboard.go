@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type dashboard struct {
+	Title         string      `json:"title"`
+	UID           string      `json:"uid"`
+	Tags          []string    `json:"tags"`
+	Timezone      string      `json:"timezone"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Refresh       string      `json:"refresh"`
+	Time          timeRange   `json:"time"`
+	Templating    templating  `json:"templating"`
+	Panels        []panel     `json:"panels"`
+	Annotations   annotations `json:"annotations"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type annotations struct {
+	List []interface{} `json:"list"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      interface{} `json:"query"`
+	Datasource *datasource `json:"datasource,omitempty"`
+	Multi      bool        `json:"multi,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	Refresh    int         `json:"refresh,omitempty"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type panel struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Type        string      `json:"type"`
+	Datasource  datasource  `json:"datasource"`
+	GridPos     gridPos     `json:"gridPos"`
+	FieldConfig fieldConfig `json:"fieldConfig"`
+	Targets     []target    `json:"targets"`
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type fieldConfig struct {
+	Defaults fieldDefaults `json:"defaults"`
+}
+
+type fieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+type target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+var prometheusDatasource = datasource{Type: "prometheus", UID: "${datasource}"}
+
+// Dashboard returns the grafana dashboard of the Definitions, filtered by the backend variable
+func Dashboard() ([]byte, error) {
+	board := dashboard{
+		Title:         "API gateway / gRPC backends",
+		UID:           "apigateway-grpc-client",
+		Tags:          []string{"apigateway", "grpc"},
+		Timezone:      "browser",
+		SchemaVersion: 38,
+		Refresh:       "30s",
+		Time:          timeRange{From: "now-6h", To: "now"},
+		Annotations:   annotations{List: []interface{}{}},
+		Templating: templating{List: []variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			{
+				Name:       "backend",
+				Label:      "Backend",
+				Type:       "query",
+				Datasource: &prometheusDatasource,
+				Query:      fmt.Sprintf("label_values(%s_count, backend)", rpcDurationDefinition.FullName()),
+				Multi:      true,
+				IncludeAll: true,
+				Refresh:    2,
+			},
+		}},
+		Panels: []panel{},
+	}
+	for _, definition := range Definitions {
+		for _, p := range panels(definition) {
+			p.ID = len(board.Panels) + 1
+			// two panels per row
+			p.GridPos = gridPos{H: 8, W: 12, X: (len(board.Panels) % 2) * 12, Y: (len(board.Panels) / 2) * 8}
+			p.Datasource = prometheusDatasource
+			board.Panels = append(board.Panels, p)
+		}
+	}
+	return json.MarshalIndent(board, "", "  ")
+}
+
+// panels returns the panels showing definition, grouped by its first label
+func panels(definition Definition) []panel {
+	name := definition.FullName()
+	by := definition.Labels[0]
+	selector := `{backend=~"$backend"}`
+	legend := "{{" + by + "}}"
+	switch definition.Type {
+	case "histogram":
+		quantiles := []target{}
+		for i, percentile := range []string{"50", "95", "99"} {
+			quantiles = append(quantiles, target{
+				RefID:        string(rune('A' + i)),
+				Expr:         fmt.Sprintf("histogram_quantile(0.%s, sum by (le, %s) (rate(%s_bucket%s[$__rate_interval])))", percentile, by, name, selector),
+				LegendFormat: fmt.Sprintf("p%s %s", percentile, legend),
+			})
+		}
+		result := []panel{{
+			Title:       title(definition) + " quantiles",
+			Description: definition.Help,
+			Type:        "timeseries",
+			FieldConfig: fieldConfig{Defaults: fieldDefaults{Unit: definition.Unit}},
+			Targets:     quantiles,
+		}}
+		if contains(definition.Labels, "code") {
+			result = append(result, panel{
+				Title:       title(definition) + " rate by code",
+				Description: definition.Help,
+				Type:        "timeseries",
+				FieldConfig: fieldConfig{Defaults: fieldDefaults{Unit: "reqps"}},
+				Targets: []target{{
+					RefID:        "A",
+					Expr:         fmt.Sprintf("sum by (%s, code) (rate(%s_count%s[$__rate_interval]))", by, name, selector),
+					LegendFormat: legend + " {{code}}",
+				}},
+			}, panel{
+				Title:       title(definition) + " error ratio",
+				Description: "Share of the calls not answered with OK.",
+				Type:        "timeseries",
+				FieldConfig: fieldConfig{Defaults: fieldDefaults{Unit: "percentunit"}},
+				Targets: []target{{
+					RefID: "A",
+					Expr: fmt.Sprintf(`sum by (%s) (rate(%s_count{backend=~"$backend", code!="OK"}[$__rate_interval])) / sum by (%s) (rate(%s_count%s[$__rate_interval]))`,
+						by, name, by, name, selector),
+					LegendFormat: legend,
+				}},
+			})
+		}
+		return result
+	case "gauge":
+		return []panel{{
+			Title:       title(definition),
+			Description: definition.Help,
+			Type:        "timeseries",
+			FieldConfig: fieldConfig{Defaults: fieldDefaults{Unit: definition.Unit}},
+			Targets: []target{{
+				RefID:        "A",
+				Expr:         fmt.Sprintf("sum by (%s) (%s%s)", by, name, selector),
+				LegendFormat: legend,
+			}},
+		}}
+	}
+	return nil
+}
+
+// title is the name of definition in words, e.g. "grpc client in flight"
+func title(definition Definition) string {
+	return strings.ReplaceAll(strings.TrimSuffix(definition.Name, "_seconds"), "_", " ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
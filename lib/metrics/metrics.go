@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "apigateway"
+
+// Definition describes a metric, the dashboard is generated from the definitions
+type Definition struct {
+	Name   string   `json:"name"`
+	Help   string   `json:"help"`
+	Type   string   `json:"type"`
+	Labels []string `json:"labels"`
+	// Unit is the grafana unit of the panel
+	Unit string `json:"unit"`
+}
+
+// FullName is the name prometheus exports
+func (d Definition) FullName() string {
+	return prometheus.BuildFQName(namespace, "", d.Name)
+}
+
+var (
+	rpcDurationDefinition = Definition{
+		Name:   "grpc_client_handling_seconds",
+		Help:   "Latency of the grpc calls to the backends, by backend, method and status code.",
+		Type:   "histogram",
+		Labels: []string{"backend", "method", "code"},
+		Unit:   "s",
+	}
+	rpcInFlightDefinition = Definition{
+		Name:   "grpc_client_in_flight",
+		Help:   "Grpc calls to the backends waiting for their response.",
+		Type:   "gauge",
+		Labels: []string{"backend", "method"},
+		Unit:   "short",
+	}
+	poolWaitDefinition = Definition{
+		Name:   "grpc_client_pool_wait_seconds",
+		Help:   "Time spent waiting for a connection of the backend pool.",
+		Type:   "histogram",
+		Labels: []string{"backend"},
+		Unit:   "s",
+	}
+
+	// Definitions of the metrics registered by this package
+	Definitions = []Definition{rpcDurationDefinition, rpcInFlightDefinition, poolWaitDefinition}
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      rpcDurationDefinition.Name,
+		Help:      rpcDurationDefinition.Help,
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, rpcDurationDefinition.Labels)
+	rpcInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      rpcInFlightDefinition.Name,
+		Help:      rpcInFlightDefinition.Help,
+	}, rpcInFlightDefinition.Labels)
+	poolWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      poolWaitDefinition.Name,
+		Help:      poolWaitDefinition.Help,
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, poolWaitDefinition.Labels)
+)
+
+func init() {
+	// the default registry is the one the metrics server exports
+	prometheus.MustRegister(rpcDuration, rpcInFlight, poolWait)
+}
+
+// UnaryClientInterceptor measures the latency, status code and in flight calls to backend
+func UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		inFlight := rpcInFlight.WithLabelValues(backend, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		rpcDuration.WithLabelValues(backend, method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObservePoolWait records the time taken to get a connection of the backend pool
+func ObservePoolWait(backend string, wait time.Duration) {
+	poolWait.WithLabelValues(backend).Observe(wait.Seconds())
+}
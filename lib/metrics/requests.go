@@ -0,0 +1,18 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var slowRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "slow_requests_total",
+	Help:      "Requests slower than the threshold or a multiple of the p95 of their route, by route and reason.",
+}, []string{"route", "reason"})
+
+func init() {
+	prometheus.MustRegister(slowRequests)
+}
+
+// CountSlowRequest counts a slow request of route, reason is threshold or p95
+func CountSlowRequest(route, reason string) {
+	slowRequests.WithLabelValues(routeLabel.Value(route), reason).Inc()
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+)
+
+// BodyLimit caps the request bodies at limit bytes, a zero limit disables it. The skipped
+// requests are the ones of the routes with their own, larger, limit
+func BodyLimit(limit int64, skipper middleware.Skipper) echo.MiddlewareFunc {
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if limit <= 0 || skipper(c) {
+				return next(c)
+			}
+			req := c.Request()
+			if req.ContentLength > limit {
+				code := codes.ErrRequestEntityTooLarge
+				return wire.Render(c, code.HTTPStatus, echo.Map{
+					"code":    code.Code,
+					"message": code.Msg,
+				})
+			}
+			// a chunked body is cut at the limit while it is read
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit)
+			return next(c)
+		}
+	}
+}
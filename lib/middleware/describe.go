@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+var descriptions sync.Map
+
+// Describe names m for the route table, e.g. "rate_limit 4/s per ip", and returns m wrapped in a
+// middleware answering its name. The name of a function, e.g. a middleware registered in an init,
+// is also kept by its code pointer.
+func Describe(name string, m echo.MiddlewareFunc) echo.MiddlewareFunc {
+	descriptions.Store(reflect.ValueOf(m).Pointer(), name)
+	return describe(name, m)
+}
+
+// Description returns the name m was described with, or its function name
+func Description(m echo.MiddlewareFunc) string {
+	pointer := reflect.ValueOf(m).Pointer()
+	// the closures of a literal share its code pointer, e.g. two rate limiters with different
+	// stores, so a described middleware is asked its name
+	if pointer == describedPointer {
+		if name, ok := m(probe)(nil).(described); ok {
+			return string(name)
+		}
+	}
+	if name, ok := descriptions.Load(pointer); ok {
+		return name.(string)
+	}
+	name := runtime.FuncForPC(pointer).Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// described is the name a described middleware answers to probe
+type described string
+
+func (d described) Error() string {
+	return string(d)
+}
+
+func probe(c echo.Context) error {
+	return nil
+}
+
+var (
+	probePointer     = reflect.ValueOf(probe).Pointer()
+	describedPointer = reflect.ValueOf(describe("", nil)).Pointer()
+)
+
+func describe(name string, m echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if reflect.ValueOf(next).Pointer() == probePointer {
+			return func(echo.Context) error {
+				return described(name)
+			}
+		}
+		return m(next)
+	}
+}
@@ -3,7 +3,8 @@ package middleware
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/mises-id/sns-apigateway/lib/codes"
-	log "github.com/sirupsen/logrus"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	"github.com/mises-id/sns-apigateway/lib/wire"
 	grpccodes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -13,7 +14,7 @@ var ErrTooManyRequestFunc = func(c echo.Context, identifier string, err error) e
 	if err != nil {
 		code = code.New(err.Error())
 	}
-	return c.JSON(code.HTTPStatus, echo.Map{
+	return wire.Render(c, code.HTTPStatus, echo.Map{
 		"code":    code.Code,
 		"message": code.Msg,
 	})
@@ -25,43 +26,62 @@ var ErrorResponseMiddleware = func(next echo.HandlerFunc) echo.HandlerFunc {
 			if _, ok := err.(*echo.HTTPError); ok {
 				return err
 			}
-			var msg string
-			statusErr, ok := status.FromError(err)
-			if ok {
-				msg = statusErr.Message()
-				switch statusErr.Code() {
-				case grpccodes.NotFound:
-					err = codes.ErrNotFound
-				case grpccodes.InvalidArgument:
-					err = codes.ErrInvalidArgument
-				case grpccodes.PermissionDenied:
-					err = codes.ErrForbidden
-				case grpccodes.Unauthenticated:
-					err = codes.ErrUnauthorized
-				case grpccodes.AlreadyExists:
-					err = codes.ErrUsernameExisted
-				case grpccodes.DeadlineExceeded:
-					err = codes.ErrRequestTimeoutCode
-				case grpccodes.Unavailable:
-					err = codes.ErrInternal
-				}
+			code, fields := ErrorCode(err)
+			if !logging.SetError(c, err) {
+				logging.FromContext(c).WithField("uri", c.Request().RequestURI).Error(err)
 			}
 
-			code, ok := err.(codes.Code)
-			if !ok {
-				code = codes.ErrInternal
-			} else {
-				if msg != "" {
-					code = code.New(msg)
-				}
+			body := echo.Map{
+				"code":    code.Code,
+				"message": code.Msg,
 			}
-			log.WithFields(map[string]interface{}{
-				"RequestID": c.Response().Header().Get(echo.HeaderXRequestID),
-				"Uri":       c.Request().RequestURI,
-			}).Error(err)
-
-			return c.JSON(code.HTTPStatus, code)
+			if len(fields) > 0 {
+				body["fields"] = fields
+			}
+			return wire.Render(c, code.HTTPStatus, body)
 		}
 		return nil
 	}
 }
+
+// ErrorCode translates err to the code of its response, with the invalid fields of a codes.FieldsError,
+// the grpc errors of the backends are translated from their status
+func ErrorCode(err error) (codes.Code, []codes.FieldError) {
+	var (
+		msg    string
+		fields []codes.FieldError
+	)
+	if fieldsErr, ok := err.(codes.FieldsError); ok {
+		err, fields = fieldsErr.Code, fieldsErr.Fields
+	}
+	statusErr, ok := status.FromError(err)
+	if ok {
+		msg = statusErr.Message()
+		switch statusErr.Code() {
+		case grpccodes.NotFound:
+			err = codes.ErrNotFound
+		case grpccodes.InvalidArgument:
+			err = codes.ErrInvalidArgument
+		case grpccodes.PermissionDenied:
+			err = codes.ErrForbidden
+		case grpccodes.Unauthenticated:
+			err = codes.ErrUnauthorized
+		case grpccodes.AlreadyExists:
+			err = codes.ErrUsernameExisted
+		case grpccodes.DeadlineExceeded:
+			err = codes.ErrRequestTimeoutCode
+		case grpccodes.Unavailable:
+			err = codes.ErrInternal
+		}
+	}
+
+	code, ok := err.(codes.Code)
+	if !ok {
+		code = codes.ErrInternal
+	} else {
+		if msg != "" {
+			code = code.New(msg)
+		}
+	}
+	return code, fields
+}
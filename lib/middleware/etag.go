@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	HeaderETag         = "ETag"
+	HeaderIfNoneMatch  = "If-None-Match"
+	HeaderCacheControl = "Cache-Control"
+)
+
+// ETagConfig defines the config for ETag middleware
+type ETagConfig struct {
+	Skipper middleware.Skipper
+	// CacheControl is sent with every cacheable response, e.g. "public, max-age=300"
+	CacheControl string
+}
+
+type etagResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   *bytes.Buffer
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *etagResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// Flush is a no-op, the body is only sent once the handler returns
+func (w *etagResponseWriter) Flush() {}
+
+// ETag returns a ETag middleware with the given Cache-Control policy
+func ETag(cacheControl string) echo.MiddlewareFunc {
+	return Describe("etag "+cacheControl, ETagWithConfig(ETagConfig{CacheControl: cacheControl}))
+}
+
+// ETagWithConfig returns a middleware that adds a strong ETag to successful GET responses
+// and answers If-None-Match / If-Modified-Since with 304 Not Modified.
+// A handler may provide its own version by setting the ETag or Last-Modified header.
+// Register it after Gzip so the tag is computed on the uncompressed body.
+func ETagWithConfig(config ETagConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if config.Skipper(c) || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+				return next(c)
+			}
+			res := c.Response()
+			rw := res.Writer
+			writer := &etagResponseWriter{ResponseWriter: rw, status: http.StatusOK, body: new(bytes.Buffer)}
+			res.Writer = writer
+			err := next(c)
+			res.Writer = rw
+			if err != nil {
+				return err
+			}
+			if writer.status != http.StatusOK {
+				rw.WriteHeader(writer.status)
+				_, err = rw.Write(writer.body.Bytes())
+				return err
+			}
+
+			header := res.Header()
+			if config.CacheControl != "" && header.Get(HeaderCacheControl) == "" {
+				header.Set(HeaderCacheControl, config.CacheControl)
+			}
+			etag := header.Get(HeaderETag)
+			if etag == "" {
+				sum := sha256.Sum256(writer.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				header.Set(HeaderETag, etag)
+			}
+			if notModified(req, etag, header.Get(echo.HeaderLastModified)) {
+				// no body is sent, so gzip must neither advertise an encoding nor flush a footer
+				header.Del(echo.HeaderContentEncoding)
+				header.Del(echo.HeaderContentLength)
+				header.Del(echo.HeaderContentType)
+				res.Status = http.StatusNotModified
+				res.Size = 0
+				rw.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+			rw.WriteHeader(writer.status)
+			_, err = rw.Write(writer.body.Bytes())
+			return err
+		}
+	}
+}
+
+func notModified(req *http.Request, etag, lastModified string) bool {
+	if match := req.Header.Get(HeaderIfNoneMatch); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		// If-Modified-Since is ignored when If-None-Match is present, see RFC 7232 3.3
+		return false
+	}
+	since := req.Header.Get(echo.HeaderIfModifiedSince)
+	if since == "" || lastModified == "" {
+		return false
+	}
+	sinceTime, err := http.ParseTime(since)
+	if err != nil {
+		return false
+	}
+	modifiedTime, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modifiedTime.Truncate(time.Second).After(sinceTime)
+}
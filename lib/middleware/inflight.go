@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InFlight counts the requests being served, so a shutdown can wait for them
+type InFlight struct {
+	count atomic.Int64
+}
+
+// Middleware counts the requests passing through it
+func (f *InFlight) Middleware() echo.MiddlewareFunc {
+	return Describe("in_flight", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			f.count.Add(1)
+			defer f.count.Add(-1)
+			return next(c)
+		}
+	})
+}
+
+// Count returns the number of requests being served
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
+
+// Wait blocks until no request is being served or ctx is done, returning the requests left
+func (f *InFlight) Wait(ctx context.Context) int64 {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		count := f.Count()
+		if count == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return count
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiterStore is a memory rate limiter store like middleware.RateLimiterMemoryStore
+// whose rate can be changed at runtime and whose buckets can be reset
+type RateLimiterStore struct {
+	mutex         sync.Mutex
+	rate          rate.Limit
+	burst         int
+	burstFromRate bool
+	expiresIn     time.Duration
+	visitors      map[string]*visitor
+	lastCleanup   time.Time
+}
+
+type visitor struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+var (
+	storesMutex sync.Mutex
+	stores      []*RateLimiterStore
+)
+
+// NewRateLimiterStore returns a store with the defaults of middleware.NewRateLimiterMemoryStoreWithConfig,
+// the burst follows the rate, at least 1, when it is not set
+func NewRateLimiterStore(config middleware.RateLimiterMemoryStoreConfig) *RateLimiterStore {
+	store := &RateLimiterStore{
+		burst:         config.Burst,
+		burstFromRate: config.Burst == 0,
+		expiresIn:     config.ExpiresIn,
+	}
+	if store.expiresIn == 0 {
+		store.expiresIn = middleware.DefaultRateLimiterMemoryStoreConfig.ExpiresIn
+	}
+	store.SetRate(config.Rate)
+	storesMutex.Lock()
+	stores = append(stores, store)
+	storesMutex.Unlock()
+	return store
+}
+
+// Allow implements middleware.RateLimiterStore
+func (s *RateLimiterStore) Allow(identifier string) (bool, error) {
+	now := time.Now()
+	s.mutex.Lock()
+	limiter, ok := s.visitors[identifier]
+	if !ok {
+		limiter = &visitor{Limiter: rate.NewLimiter(s.rate, s.burst)}
+		s.visitors[identifier] = limiter
+	}
+	limiter.lastSeen = now
+	if now.Sub(s.lastCleanup) > s.expiresIn {
+		for id, v := range s.visitors {
+			if now.Sub(v.lastSeen) > s.expiresIn {
+				delete(s.visitors, id)
+			}
+		}
+		s.lastCleanup = now
+	}
+	s.mutex.Unlock()
+	return limiter.AllowN(now, 1), nil
+}
+
+// SetRate replaces the rate, a new rate starts every client with a full bucket.
+// It is a no-op when limit is unchanged.
+func (s *RateLimiterStore) SetRate(limit rate.Limit) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.visitors != nil && s.rate == limit {
+		return
+	}
+	s.rate = limit
+	if s.burstFromRate {
+		// a rate below 1/s still lets a client through once its token is back
+		s.burst = int(limit)
+		if s.burst < 1 {
+			s.burst = 1
+		}
+	}
+	s.visitors = map[string]*visitor{}
+}
+
+// Policy describes the rate and the burst of the store, e.g. "10/s burst 20"
+func (s *RateLimiterStore) Policy() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return fmt.Sprintf("%v/s burst %d", float64(s.rate), s.burst)
+}
+
+// Reset gives the clients identified by key a full bucket, returning how many were reset. An
+// identifier is the key alone or the key followed by the path of the route
+func (s *RateLimiterStore) Reset(key string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for id := range s.visitors {
+		if id == key || strings.HasPrefix(id, key+"/") {
+			delete(s.visitors, id)
+			count++
+		}
+	}
+	return count
+}
+
+// ResetRateLimits resets the buckets of key in every store, e.g. an ip or a wallet address
+func ResetRateLimits(key string) int {
+	storesMutex.Lock()
+	defer storesMutex.Unlock()
+	count := 0
+	for _, store := range stores {
+		count += store.Reset(key)
+	}
+	return count
+}
@@ -0,0 +1,363 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/export"
+	"github.com/mises-id/sns-apigateway/lib/fields"
+)
+
+// Auth is the authentication an endpoint accepts
+type Auth int
+
+const (
+	// AuthNone ignores the Authorization header
+	AuthNone Auth = iota
+	// AuthOptional identifies the user when a token is sent
+	AuthOptional
+	// AuthRequired answers unauthorized without a token
+	AuthRequired
+)
+
+const (
+	// BearerAuth is the security scheme of the session tokens returned by the sign in
+	BearerAuth = "bearerAuth"
+	// ErrorSchema is the component schema of the error responses
+	ErrorSchema = "Error"
+)
+
+// Endpoint describes a route, the values of Request, PathParams, Response, Pagination and Export
+// are only used for their type
+type Endpoint struct {
+	Method string
+	// OperationID defaults to the name of the handler
+	OperationID string
+	// Path is the echo path, e.g. /api/v1/user/:uid
+	Path       string
+	Handler    string
+	Summary    string
+	Tags       []string
+	Auth       Auth
+	RateLimit  []string
+	Middleware []string
+	// Request is the params the handler binds, nil when it binds none
+	Request interface{}
+	// PathParams is a struct whose param tags type the path params, they are strings by default
+	PathParams interface{}
+	// Files are the multipart file fields the handler reads besides Request
+	Files []string
+	// Response is the data of the response envelope, nil for null, a *Schema describes it
+	// as is, e.g. for a map
+	Response interface{}
+	// Pagination is the pagination of the envelope, nil when the response is not paged
+	Pagination interface{}
+	// RequestID adds the request_id the client sent to the envelope
+	RequestID bool
+	// Export is an item streamed by an export instead of the envelope
+	Export interface{}
+	// ContentType is the media type of a body returned as is instead of the envelope
+	ContentType string
+	// Redirect answers with a redirection instead of the envelope
+	Redirect bool
+	// Errors are the errors of the handler, the errors of the middleware are added
+	Errors []codes.Code
+}
+
+// Builder collects the endpoints of a document
+type Builder struct {
+	doc          *Document
+	gen          *Generator
+	tags         map[string]bool
+	operationIDs map[string]bool
+}
+
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   map[string]*PathItem{},
+		},
+		gen:          NewGenerator(),
+		tags:         map[string]bool{},
+		operationIDs: map[string]bool{},
+	}
+}
+
+// Add describes e in the document
+func (b *Builder) Add(e Endpoint) {
+	path, pathParams := templatePath(e.Path)
+	op := &Operation{
+		OperationID: b.operationID(e.OperationID, e.Handler),
+		Summary:     e.Summary,
+		Tags:        e.Tags,
+		Parameters:  b.pathParameters(pathParams, e.PathParams),
+		Responses:   map[string]*Response{},
+		RateLimit:   e.RateLimit,
+		Middleware:  e.Middleware,
+		Handler:     e.Handler,
+	}
+	for _, tag := range e.Tags {
+		b.tags[tag] = true
+	}
+
+	request := typeOf(e.Request)
+	switch e.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		op.Parameters = append(op.Parameters, b.gen.Parameters(request, "query", "query")...)
+	}
+	if e.Method != http.MethodGet && e.Method != http.MethodHead && (request != nil || len(e.Files) > 0) {
+		op.RequestBody = b.requestBody(e.Method, request, e.Files)
+	}
+
+	switch {
+	case e.Redirect:
+		op.Responses[strconv.Itoa(http.StatusMovedPermanently)] = &Response{
+			Description: "redirection",
+			Headers:     map[string]*Header{"Location": {Schema: &Schema{Type: "string", Format: "uri"}}},
+		}
+	case e.Export != nil:
+		op.Parameters = append(op.Parameters,
+			&Parameter{Name: "format", In: "query", Description: "format of the export, ndjson by default",
+				Schema: &Schema{Type: "string", Enum: []interface{}{export.FormatNDJSON, export.FormatCSV}}},
+			&Parameter{Name: "cursor", In: "query", Description: "cursor of the last line of an interrupted export",
+				Schema: &Schema{Type: "string"}},
+		)
+		op.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+			Description: "every item, one per line, followed by the cursor of the next page, an export stopped at its page limit ends with a truncated line, in csv an export which did not end is closed by a cursor row",
+			Content: map[string]*MediaType{
+				export.MIMEApplicationNDJSON: {Schema: &Schema{Type: "object", Properties: map[string]*Schema{
+					"item":      b.gen.Schema(typeOf(e.Export)),
+					"cursor":    {Type: "string"},
+					"truncated": {Type: "boolean"},
+					"error":     {Ref: SchemaRefPrefix + ErrorSchema},
+				}}},
+				export.MIMETextCSV: {Schema: &Schema{Type: "string"}},
+			},
+		}
+	case e.ContentType != "":
+		op.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+			Description: "success",
+			Content:     map[string]*MediaType{e.ContentType: {Schema: &Schema{Type: "string"}}},
+		}
+	default:
+		op.Parameters = append(op.Parameters,
+			&Parameter{Name: fields.FieldsParam, In: "query", Description: "comma separated fields of data to return",
+				Schema: &Schema{Type: "string"}},
+			&Parameter{Name: fields.ExpandParam, In: "query", Description: "comma separated nested objects of data to return",
+				Schema: &Schema{Type: "string"}},
+		)
+		op.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+			Description: "success",
+			Content:     map[string]*MediaType{"application/json": {Schema: b.envelope(e)}},
+		}
+	}
+
+	switch e.Auth {
+	case AuthOptional:
+		op.Security = []SecurityRequirement{{BearerAuth: {}}, {}}
+	case AuthRequired:
+		op.Security = []SecurityRequirement{{BearerAuth: {}}}
+	}
+	b.addErrors(op, b.errors(e, len(pathParams) > 0))
+
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(e.Method)] = op
+}
+
+// Document returns the document of the endpoints added so far
+func (b *Builder) Document() *Document {
+	b.doc.Tags = []Tag{}
+	for tag := range b.tags {
+		b.doc.Tags = append(b.doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(b.doc.Tags, func(i, j int) bool { return b.doc.Tags[i].Name < b.doc.Tags[j].Name })
+	b.doc.Components.Schemas = b.gen.Schemas()
+	b.doc.Components.Schemas[ErrorSchema] = errorSchema()
+	b.doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+		BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "token returned by the sign in"},
+	}
+	return b.doc
+}
+
+// operationID defaults to the handler name without its package, it is numbered when it
+// serves several routes
+func (b *Builder) operationID(id, handler string) string {
+	if id == "" {
+		id = handler[strings.LastIndex(handler, ".")+1:]
+	}
+	base := id
+	for i := 2; b.operationIDs[id]; i++ {
+		id = fmt.Sprintf("%s%d", base, i)
+	}
+	b.operationIDs[id] = true
+	return id
+}
+
+func (b *Builder) pathParameters(names []string, typed interface{}) []*Parameter {
+	params := b.gen.Parameters(typeOf(typed), "param", "path")
+	byName := map[string]*Parameter{}
+	for _, param := range params {
+		byName[param.Name] = param
+	}
+	result := make([]*Parameter, 0, len(names))
+	for _, name := range names {
+		param, ok := byName[name]
+		if !ok {
+			param = &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		}
+		result = append(result, param)
+	}
+	return result
+}
+
+func (b *Builder) requestBody(method string, request reflect.Type, files []string) *RequestBody {
+	body := &RequestBody{Required: method != http.MethodDelete, Content: map[string]*MediaType{}}
+	form := b.gen.FormSchema(request)
+	if len(files) > 0 {
+		for _, file := range files {
+			form.Properties[file] = &Schema{Type: "string", Format: "binary"}
+		}
+		body.Content["multipart/form-data"] = &MediaType{Schema: form}
+		return body
+	}
+	body.Content["application/json"] = &MediaType{Schema: b.gen.Schema(request)}
+	if len(form.Properties) > 0 {
+		body.Content["application/x-www-form-urlencoded"] = &MediaType{Schema: form}
+	}
+	return body
+}
+
+// envelope is the schema of the success response of e
+func (b *Builder) envelope(e Endpoint) *Schema {
+	data := &Schema{Type: "null"}
+	if schema, ok := e.Response.(*Schema); ok {
+		data = schema
+	} else if e.Response != nil {
+		data = b.gen.Schema(typeOf(e.Response))
+	}
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer", Enum: []interface{}{codes.SuccessCode}},
+			"data": data,
+		},
+		Required: []string{"code", "data"},
+	}
+	if e.RequestID {
+		schema.Properties["request_id"] = &Schema{Type: "string", Description: "request_id of the request"}
+	}
+	if e.Pagination != nil {
+		schema.Properties["pagination"] = b.gen.Schema(typeOf(e.Pagination))
+		schema.Required = append(schema.Required, "pagination")
+	}
+	return schema
+}
+
+// errors are the errors of the handler of e and of its middleware
+func (b *Builder) errors(e Endpoint, hasPathParams bool) []codes.Code {
+	errs := []codes.Code{}
+	if e.Request != nil || hasPathParams {
+		errs = append(errs, codes.ErrInvalidArgument)
+	}
+	if e.Auth != AuthNone {
+		errs = append(errs, codes.ErrInvalidAuth, codes.ErrInvalidAuthMethod, codes.ErrInvalidAuthToken, codes.ErrTokenExpired)
+	}
+	if e.Auth == AuthRequired {
+		errs = append(errs, codes.ErrUnauthorized)
+	}
+	if len(e.RateLimit) > 0 {
+		errs = append(errs, codes.ErrTooManyRequest)
+	}
+	return append(append(errs, e.Errors...), codes.ErrInternal)
+}
+
+// addErrors adds a response per http status of errs, with an example per code
+func (b *Builder) addErrors(op *Operation, errs []codes.Code) {
+	for _, err := range errs {
+		status := strconv.Itoa(err.HTTPStatus)
+		response, ok := op.Responses[status]
+		if !ok {
+			response = &Response{Content: map[string]*MediaType{"application/json": {
+				Schema:   &Schema{Ref: SchemaRefPrefix + ErrorSchema},
+				Examples: map[string]*Example{},
+			}}}
+			op.Responses[status] = response
+		}
+		examples := response.Content["application/json"].Examples
+		key := strconv.Itoa(err.Code)
+		if _, ok := examples[key]; ok {
+			continue
+		}
+		examples[key] = &Example{Summary: err.Msg, Value: err}
+		if response.Description != "" {
+			response.Description += ", "
+		}
+		response.Description += fmt.Sprintf("%d %s", err.Code, err.Msg)
+	}
+}
+
+func errorSchema() *Schema {
+	lines := []string{"The code identifies the error, the message details it. Codes:", ""}
+	for _, err := range codes.Errors {
+		lines = append(lines, fmt.Sprintf("- %d (http %d): %s", err.Code, err.HTTPStatus, err.Msg))
+	}
+	return &Schema{
+		Type:        "object",
+		Description: strings.Join(lines, "\n"),
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+			"fields": {
+				Type:        "array",
+				Description: "the request fields that failed validation, only set on 400000",
+				Items: &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"field":   {Type: "string"},
+						"rule":    {Type: "string"},
+						"in":      {Type: "string", Description: "where the field was sent, when the binder rejected it", Enum: []interface{}{"query", "body", "path"}},
+						"message": {Type: "string"},
+					},
+					Required: []string{"field", "rule", "message"},
+				},
+			},
+		},
+		Required: []string{"code", "message"},
+	}
+}
+
+// templatePath turns the echo path params into OpenAPI templates, e.g. /user/:uid into
+// /user/{uid}, and returns the param names
+func templatePath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	names := []string{}
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		case segment == "*":
+			names = append(names, "path")
+			segments[i] = "{path}"
+		}
+	}
+	return strings.Join(segments, "/"), names
+}
+
+func typeOf(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	return reflect.TypeOf(v)
+}
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// SpecHandler serves the document returned by build as JSON, it is built on the first request
+// since the routes are only known once they are all registered
+func SpecHandler(build func() *Document) echo.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c echo.Context) error {
+		once.Do(func() {
+			body, err = json.Marshal(build())
+		})
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, body)
+	}
+}
+
+// DocsHandler serves the bundled documentation page of the document served at specURL,
+// the page has no external dependency
+func DocsHandler(title, specURL string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		page := &strings.Builder{}
+		if err := docsTemplate.Execute(page, map[string]string{"Title": title, "SpecURL": specURL}); err != nil {
+			return err
+		}
+		return c.HTML(http.StatusOK, page.String())
+	}
+}
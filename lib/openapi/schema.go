@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SchemaRefPrefix starts the reference of every component schema
+const SchemaRefPrefix = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// Generator derives the JSON schemas of go types from the way encoding/json and the echo
+// binder handle them. Named structs become component schemas referred to by $ref.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// Schemas returns the component schemas of every named struct seen so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of the JSON encoding of values of type t, nil is any value
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	t = indirect(t)
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType), reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType), reflect.PtrTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &Schema{Ref: SchemaRefPrefix + g.component(t)}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	}
+	return scalarSchema(t.Kind())
+}
+
+// component registers the schema of the named struct t and returns its name, a name taken by
+// a type of another package is qualified with the package name
+func (g *Generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := invalidNameChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	// reserve the name first, structs may refer to themselves
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return name
+}
+
+// object is the schema of struct t, fields of embedded structs are inlined
+func (g *Generator) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			options := strings.Split(tag, ",")
+			name := options[0]
+			if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+				walk(indirect(field.Type))
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if _, ok := schema.Properties[name]; ok {
+				continue
+			}
+			if hasOption(options[1:], "string") {
+				schema.Properties[name] = &Schema{Type: "string"}
+				continue
+			}
+			schema.Properties[name] = g.Schema(field.Type)
+		}
+	}
+	walk(t)
+	return schema
+}
+
+// Parameters returns the parameters bound from the fields of struct t tagged with tag, e.g.
+// "query", located in. Untagged struct fields are walked like the echo binder does.
+func (g *Generator) Parameters(t reflect.Type, tag, in string) []*Parameter {
+	params := []*Parameter{}
+	if t == nil || indirect(t).Kind() != reflect.Struct {
+		return params
+	}
+	seen := map[string]bool{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "" {
+				if indirect(field.Type).Kind() == reflect.Struct && indirect(field.Type) != timeType {
+					walk(indirect(field.Type))
+				}
+				continue
+			}
+			if (field.PkgPath != "" && !field.Anonymous) || name == "-" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			schema := g.Schema(field.Type)
+			if schema.Ref != "" || schema.Type == "object" {
+				schema = &Schema{Type: "string"}
+			}
+			params = append(params, &Parameter{Name: name, In: in, Required: in == "path", Schema: schema})
+		}
+	}
+	walk(indirect(t))
+	return params
+}
+
+// FormSchema is the object schema of the fields of struct t tagged with form
+func (g *Generator) FormSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, param := range g.Parameters(t, "form", "formData") {
+		schema.Properties[param.Name] = param.Schema
+	}
+	return schema
+}
+
+func scalarSchema(kind reflect.Kind) *Schema {
+	switch kind {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "uint32"}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "uint64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	}
+	return &Schema{}
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
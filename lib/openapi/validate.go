@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks every reference of doc resolves, the operation ids are unique and every
+// templated path param is declared
+func Validate(doc *Document) error {
+	problems := []string{}
+	checkSchema := func(where string, schema *Schema) {
+		walkSchema(schema, func(s *Schema) {
+			if s.Ref == "" {
+				return
+			}
+			name := strings.TrimPrefix(s.Ref, SchemaRefPrefix)
+			if _, ok := doc.Components.Schemas[name]; !ok || name == s.Ref {
+				problems = append(problems, fmt.Sprintf("%s: unresolved %s", where, s.Ref))
+			}
+		})
+	}
+	for name, schema := range doc.Components.Schemas {
+		checkSchema("schema "+name, schema)
+	}
+
+	operationIDs := map[string]string{}
+	for path, item := range doc.Paths {
+		_, names := templatePath(strings.NewReplacer("{", ":", "}", "").Replace(path))
+		for method, op := range *item {
+			where := method + " " + path
+			if other, ok := operationIDs[op.OperationID]; ok {
+				problems = append(problems, fmt.Sprintf("%s: operationId %s is used by %s", where, op.OperationID, other))
+			}
+			operationIDs[op.OperationID] = where
+			declared := map[string]bool{}
+			for _, param := range op.Parameters {
+				if param.In == "path" {
+					declared[param.Name] = true
+				}
+				checkSchema(where+" parameter "+param.Name, param.Schema)
+			}
+			for _, name := range names {
+				if !declared[name] {
+					problems = append(problems, fmt.Sprintf("%s: path param %s is not declared", where, name))
+				}
+			}
+			if op.RequestBody != nil {
+				for mediaType, content := range op.RequestBody.Content {
+					checkSchema(where+" request "+mediaType, content.Schema)
+				}
+			}
+			if len(op.Responses) == 0 {
+				problems = append(problems, where+": no response")
+			}
+			for status, response := range op.Responses {
+				for mediaType, content := range response.Content {
+					checkSchema(where+" response "+status+" "+mediaType, content.Schema)
+				}
+			}
+			for _, requirement := range op.Security {
+				for scheme := range requirement {
+					if _, ok := doc.Components.SecuritySchemes[scheme]; !ok {
+						problems = append(problems, fmt.Sprintf("%s: unknown security scheme %s", where, scheme))
+					}
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid openapi document:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func walkSchema(schema *Schema, fn func(*Schema)) {
+	if schema == nil {
+		return
+	}
+	fn(schema)
+	for _, property := range schema.Properties {
+		walkSchema(property, fn)
+	}
+	walkSchema(schema.Items, fn)
+	walkSchema(schema.AdditionalProperties, fn)
+}
@@ -0,0 +1,640 @@
+package sdkgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+)
+
+// goPackage is the package name of the go client
+const goPackage = "misesapi"
+
+// goWriter writes the go client of an api
+type goWriter struct {
+	a *api
+	b bytes.Buffer
+}
+
+func goClient(a *api) ([]byte, error) {
+	w := &goWriter{a: a}
+	w.header()
+	w.errorCodes()
+	w.b.WriteString(goRuntime)
+	if w.hasMultipart() {
+		w.b.WriteString("\n" + goMultipartRuntime)
+	}
+	w.line("")
+	w.line("// Pagination is the pagination of the paged responses, the last_id of the quick pages or the")
+	w.line("// page_num of the numbered ones")
+	w.line("type Pagination %s", w.structType(a.pagination))
+	w.line("")
+	for _, name := range a.typeNames {
+		w.namedType(name, a.types[name])
+	}
+	for _, op := range a.operations {
+		w.operation(op)
+	}
+	source, err := format.Source(w.b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format go client: %w", err)
+	}
+	return source, nil
+}
+
+func (w *goWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteByte('\n')
+}
+
+func (w *goWriter) hasMultipart() bool {
+	for _, op := range w.a.operations {
+		if op.multipart {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *goWriter) header() {
+	w.line("// %s", header)
+	w.line("")
+	w.line("// Package %s is the go client of the %s.", goPackage, w.a.title)
+	if len(w.a.skipped) > 0 {
+		w.line("//")
+		w.line("// Not generated as they do not answer json: %s.", strings.Join(w.a.skipped, ", "))
+	}
+	w.line("package %s", goPackage)
+	w.line("")
+	w.line("import (")
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strconv", "strings", "time"}
+	if w.hasMultipart() {
+		imports = append(imports, "mime/multipart")
+	}
+	for _, path := range imports {
+		w.line("%q", path)
+	}
+	w.line(")")
+	w.line("")
+}
+
+func (w *goWriter) errorCodes() {
+	w.line("// ErrorCode identifies the error of a failed call")
+	w.line("type ErrorCode int")
+	w.line("")
+	w.line("const (")
+	for _, err := range w.a.errors {
+		w.line("// ErrorCode%s is answered with http %d", err.name, err.status)
+		w.line("ErrorCode%s ErrorCode = %d", err.name, err.code)
+	}
+	w.line(")")
+	w.line("")
+	w.line("func (c ErrorCode) String() string {")
+	w.line("switch c {")
+	for _, err := range w.a.errors {
+		w.line("case ErrorCode%s:", err.name)
+		w.line("return %q", err.message)
+	}
+	w.line("}")
+	w.line("return strconv.Itoa(int(c))")
+	w.line("}")
+	w.line("")
+}
+
+func (w *goWriter) namedType(name string, schema *openapi.Schema) {
+	if schema.Type == "object" && schema.AdditionalProperties == nil {
+		w.line("type %s %s", name, w.structType(schema))
+	} else {
+		w.line("type %s %s", name, w.typ(schema))
+	}
+	w.line("")
+}
+
+// typ is the go type of the json values of schema, the component schemas are pointed to
+func (w *goWriter) typ(schema *openapi.Schema) string {
+	if schema == nil {
+		return "interface{}"
+	}
+	if schema.Ref != "" {
+		return "*" + w.a.typeName(schema.Ref)
+	}
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		switch schema.Format {
+		case "int32", "uint32", "uint64":
+			return schema.Format
+		}
+		return "int64"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + w.typ(schema.Items)
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + w.typ(schema.AdditionalProperties)
+		}
+		if len(schema.Properties) > 0 {
+			return w.structType(schema)
+		}
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// paramType is the go type of a path or query param, sent as its fmt.Sprint
+func (w *goWriter) paramType(schema *openapi.Schema) string {
+	switch {
+	case schema == nil:
+		return "string"
+	case schema.Type == "array":
+		return "[]" + w.paramType(schema.Items)
+	case schema.Type == "string", schema.Ref != "", schema.Type == "object", schema.Type == "":
+		return "string"
+	}
+	return w.typ(schema)
+}
+
+func (w *goWriter) structType(schema *openapi.Schema) string {
+	names := sortedKeys(schema.Properties)
+	fields := fieldNames(names)
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, name := range names {
+		property := schema.Properties[name]
+		if text := comment(property.Description); text != "" {
+			fmt.Fprintf(&b, "// %s\n", text)
+		}
+		fmt.Fprintf(&b, "%s %s `json:\"%s,omitempty\"`\n", fields[name], w.typ(property), name)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// fieldNames maps names to distinct go field names, in the order of names
+func fieldNames(names []string) map[string]string {
+	result := map[string]string{}
+	taken := map[string]bool{}
+	for _, name := range names {
+		field := exportedName(name)
+		for i := 2; taken[field]; i++ {
+			field = exportedName(name) + strconv.Itoa(i)
+		}
+		taken[field] = true
+		result[name] = field
+	}
+	return result
+}
+
+func (w *goWriter) operation(op *operation) {
+	params := append(append([]*openapi.Parameter{}, op.pathParams...), op.query...)
+	paramNames := make([]string, 0, len(params))
+	for _, param := range params {
+		paramNames = append(paramNames, param.Name)
+	}
+	fields := fieldNames(paramNames)
+
+	w.line("// %s are the path and query params of %s", op.params, op.id)
+	w.line("type %s struct {", op.params)
+	for _, param := range params {
+		if text := comment(param.Description); text != "" {
+			w.line("// %s", text)
+		}
+		w.line("%s %s", fields[param.Name], w.paramType(param.Schema))
+	}
+	w.line("}")
+	w.line("")
+
+	form := ""
+	if op.multipart {
+		form = w.form(op)
+	}
+
+	data := w.typ(op.data)
+	if op.dataType != "" {
+		w.line("// %s is the data of %s", op.dataType, op.id)
+		w.line("type %s %s", op.dataType, data)
+		w.line("")
+		data = "*" + op.dataType
+	}
+	signature := "ctx context.Context, params " + op.params
+	body := ""
+	switch {
+	case form != "":
+		body = "*" + form
+	case op.body != nil:
+		body = w.typ(op.body)
+	}
+	if body != "" {
+		signature += ", body " + body
+	}
+
+	w.line("// %s calls %s%s", op.id, op.method+" "+op.path, w.summary(op))
+	w.line("func (c *Client) %s(%s) (*Response[%s], error) {", op.id, signature, data)
+	w.line("query := url.Values{}")
+	for _, param := range op.query {
+		if param.Schema != nil && param.Schema.Type == "array" {
+			w.line("addQuery(query, %q, params.%s)", param.Name, fields[param.Name])
+		} else {
+			w.line("setQuery(query, %q, params.%s)", param.Name, fields[param.Name])
+		}
+	}
+	payload := "nil"
+	if body != "" {
+		payload = "body"
+		if strings.HasPrefix(body, "*") {
+			payload = "payload"
+			w.line("var payload interface{}")
+			w.line("if body != nil {")
+			w.line("payload = body")
+			w.line("}")
+		}
+	}
+	mode := map[auth]string{authNone: "authNone", authOptional: "authOptional", authRequired: "authRequired"}[op.auth]
+	w.line("resp := &Response[%s]{}", data)
+	w.line("if err := c.do(ctx, %q, %s, query, %s, %s, resp); err != nil {", op.method, w.path(op, fields), payload, mode)
+	w.line("return nil, err")
+	w.line("}")
+	w.line("return resp, nil")
+	w.line("}")
+	w.line("")
+
+	if op.paging != pagingNone {
+		w.each(op, fields)
+	}
+}
+
+func (w *goWriter) summary(op *operation) string {
+	text := comment(op.summary)
+	switch op.auth {
+	case authRequired:
+		text = comment(text, "it needs the token")
+	case authOptional:
+		text = comment(text, "it sends the token when there is one")
+	}
+	if text == "" {
+		return ""
+	}
+	return ": " + text
+}
+
+// path is the go expression of the path of op, e.g. "/api/v1/user/"+pathValue(params.UID)+"/comment"
+func (w *goWriter) path(op *operation, fields map[string]string) string {
+	parts := []string{}
+	literal := ""
+	rest := op.path
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start < 0 || end < start {
+			literal += rest
+			break
+		}
+		literal += rest[:start]
+		if literal != "" {
+			parts = append(parts, strconv.Quote(literal))
+			literal = ""
+		}
+		parts = append(parts, fmt.Sprintf("pathValue(params.%s)", fields[rest[start+1:end]]))
+		rest = rest[end+1:]
+	}
+	if literal != "" {
+		parts = append(parts, strconv.Quote(literal))
+	}
+	return strings.Join(parts, "+")
+}
+
+// form writes the multipart form type of op and returns its name
+func (w *goWriter) form(op *operation) string {
+	name := w.a.freeName(op.id+"Form", op.id+"CallForm")
+	names := sortedKeys(op.body.Properties)
+	fields := fieldNames(names)
+	w.line("// %s is the multipart form of %s", name, op.id)
+	w.line("type %s struct {", name)
+	for _, property := range names {
+		schema := op.body.Properties[property]
+		if schema.Format == "binary" {
+			w.line("%s *File", fields[property])
+			continue
+		}
+		w.line("%s %s", fields[property], w.paramType(schema))
+	}
+	w.line("}")
+	w.line("")
+	w.line("func (f *%s) encode() ([]byte, string, error) {", name)
+	w.line("buf := &bytes.Buffer{}")
+	w.line("form := multipart.NewWriter(buf)")
+	for _, property := range names {
+		schema := op.body.Properties[property]
+		switch {
+		case schema.Format == "binary":
+			w.line("if err := writeFile(form, %q, f.%s); err != nil {", property, fields[property])
+		case schema.Type == "array":
+			w.line("for _, value := range f.%s {", fields[property])
+			w.line("if err := writeField(form, %q, value); err != nil {", property)
+			w.line("return nil, \"\", err")
+			w.line("}")
+			w.line("}")
+			continue
+		default:
+			w.line("if err := writeField(form, %q, f.%s); err != nil {", property, fields[property])
+		}
+		w.line("return nil, \"\", err")
+		w.line("}")
+	}
+	w.line("if err := form.Close(); err != nil {")
+	w.line("return nil, \"\", err")
+	w.line("}")
+	w.line("return buf.Bytes(), form.FormDataContentType(), nil")
+	w.line("}")
+	w.line("")
+	return name
+}
+
+// each writes the helper walking the pages of op
+func (w *goWriter) each(op *operation, fields map[string]string) {
+	pagination := fieldNames(sortedKeys(w.a.pagination.Properties))
+	item := w.typ(op.data.Items)
+	signature := "ctx context.Context, params " + op.params
+	args := "ctx, params"
+	if op.body != nil {
+		signature += ", body " + w.typ(op.body)
+		args += ", body"
+	}
+	w.line("// %sEach calls fn with the items of %s, page after page from the page of params", op.id, op.id)
+	w.line("func (c *Client) %sEach(%s, fn func(%s) error) error {", op.id, signature, item)
+	if op.paging == pagingPage {
+		w.line("if params.%s < 1 {", fields["page_num"])
+		w.line("params.%s = 1", fields["page_num"])
+		w.line("}")
+	}
+	w.line("for {")
+	w.line("resp, err := c.%s(%s)", op.id, args)
+	w.line("if err != nil {")
+	w.line("return err")
+	w.line("}")
+	w.line("for _, item := range resp.Data {")
+	w.line("if err := fn(item); err != nil {")
+	w.line("return err")
+	w.line("}")
+	w.line("}")
+	switch op.paging {
+	case pagingQuick:
+		next := "resp.Pagination." + pagination["last_id"]
+		w.line("if len(resp.Data) == 0 || resp.Pagination == nil || %s == \"\" || %s == params.%s {", next, next, fields["last_id"])
+		w.line("return nil")
+		w.line("}")
+		w.line("params.%s = %s", fields["last_id"], next)
+	case pagingPage:
+		w.line("if len(resp.Data) == 0 || resp.Pagination == nil || int64(params.%s) >= int64(resp.Pagination.%s) {", fields["page_num"], pagination["total_page"])
+		w.line("return nil")
+		w.line("}")
+		w.line("params.%s++", fields["page_num"])
+	}
+	w.line("}")
+	w.line("}")
+	w.line("")
+}
+
+// goRuntime is the part of the go client which does not depend on the api
+const goRuntime = `// FieldError is a request field that failed validation, In is where it was sent when the
+// gateway did not expect it there
+type FieldError struct {
+	Field   string ` + "`json:\"field\"`" + `
+	Rule    string ` + "`json:\"rule\"`" + `
+	In      string ` + "`json:\"in,omitempty\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+// Error is a call answered with an error code
+type Error struct {
+	// Status is the http status of the response
+	Status  int       ` + "`json:\"-\"`" + `
+	Code    ErrorCode ` + "`json:\"code\"`" + `
+	Message string    ` + "`json:\"message\"`" + `
+	// Fields are the request fields that failed validation
+	Fields    []FieldError ` + "`json:\"fields,omitempty\"`" + `
+	RequestID string       ` + "`json:\"-\"`" + `
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mises api: %d %s", int(e.Code), e.Message)
+}
+
+// Response is the envelope of a successful call
+type Response[T any] struct {
+	Code       ErrorCode   ` + "`json:\"code\"`" + `
+	Data       T           ` + "`json:\"data\"`" + `
+	RequestID  string      ` + "`json:\"request_id,omitempty\"`" + `
+	Pagination *Pagination ` + "`json:\"pagination,omitempty\"`" + `
+}
+
+// Client calls the gateway, NewClient returns one with the default retries
+type Client struct {
+	// BaseURL is the scheme and host of the gateway, e.g. https://api.mises.site
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Token returns the session token of the sign in, no token is sent when it is nil or empty
+	Token func(ctx context.Context) (string, error)
+	// Retries is the number of retries of a failed call, RetryDelay the delay of the first one,
+	// doubled after each unless the response has a Retry-After. GET, HEAD, PUT and DELETE are
+	// retried on network errors, 429, 502, 503 and 504, the other methods only on 429
+	Retries    int
+	RetryDelay time.Duration
+	// Header is sent with every request
+	Header http.Header
+}
+
+// NewClient returns a client of the gateway at baseURL, retrying twice from 200ms
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Retries: 2, RetryDelay: 200 * time.Millisecond}
+}
+
+type auth int
+
+const (
+	authNone auth = iota
+	authOptional
+	authRequired
+)
+
+// encoder is a request body which is not json
+type encoder interface {
+	encode() (body []byte, contentType string, err error)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, auth auth, out interface{}) error {
+	var payload []byte
+	contentType := ""
+	switch body := body.(type) {
+	case nil:
+	case encoder:
+		var err error
+		if payload, contentType, err = body.encode(); err != nil {
+			return err
+		}
+	default:
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	token := ""
+	if auth != authNone && c.Token != nil {
+		var err error
+		if token, err = c.Token(ctx); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		for key, values := range c.Header {
+			req.Header[key] = append([]string(nil), values...)
+		}
+		req.Header.Set("Accept", "application/json")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.httpClient().Do(req)
+		if delay, ok := c.retry(ctx, method, attempt, resp, err); ok {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decode(resp, out)
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// retry returns the delay before the next attempt, false when the call is not retried
+func (c *Client) retry(ctx context.Context, method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= c.Retries || ctx.Err() != nil {
+		return 0, false
+	}
+	idempotent := method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+	switch {
+	case err != nil:
+		if !idempotent {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case idempotent && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable ||
+		resp.StatusCode == http.StatusGatewayTimeout):
+	default:
+		return 0, false
+	}
+	delay := c.RetryDelay << attempt
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	return delay, true
+}
+
+// decode reads the envelope of resp into out, or returns its *Error
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	apiErr := &Error{Status: resp.StatusCode, RequestID: resp.Header.Get("X-Request-Id")}
+	if err := json.Unmarshal(data, apiErr); err != nil || resp.StatusCode >= http.StatusBadRequest || apiErr.Code != 0 {
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	return json.Unmarshal(data, out)
+}
+
+// setQuery sets the query param key, unless value is its zero value
+func setQuery[T comparable](query url.Values, key string, value T) {
+	var zero T
+	if value != zero {
+		query.Set(key, fmt.Sprint(value))
+	}
+}
+
+func addQuery[T any](query url.Values, key string, values []T) {
+	for _, value := range values {
+		query.Add(key, fmt.Sprint(value))
+	}
+}
+
+func pathValue(value interface{}) string {
+	return url.PathEscape(fmt.Sprint(value))
+}
+`
+
+// goMultipartRuntime encodes the multipart forms
+const goMultipartRuntime = `// File is a file field of a multipart form
+type File struct {
+	Name string
+	Data []byte
+}
+
+func writeField[T comparable](form *multipart.Writer, name string, value T) error {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return form.WriteField(name, fmt.Sprint(value))
+}
+
+func writeFile(form *multipart.Writer, name string, file *File) error {
+	if file == nil {
+		return nil
+	}
+	part, err := form.CreateFormFile(name, file.Name)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(file.Data)
+	return err
+}
+`
@@ -0,0 +1,416 @@
+// Package sdkgen generates the typescript and go clients of the gateway from its openapi document.
+// The clients are committed under sdk/, a change of the routes or of their types is released to the
+// apps by regenerating them.
+package sdkgen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+)
+
+const (
+	// TypeScriptFile is the typescript client, relative to the sdk dir
+	TypeScriptFile = "typescript/index.ts"
+	// GoFile is the go client, relative to the sdk dir
+	GoFile = "go/client.go"
+
+	header = "Code generated by mises sdk. DO NOT EDIT."
+)
+
+// reserved are the names of the runtime of the clients, a schema of the same name is suffixed
+var reserved = map[string]bool{
+	"ApiError": true, "Client": true, "ClientOptions": true, "Envelope": true, "Error": true,
+	"ErrorCode": true, "FieldError": true, "File": true, "Pagination": true, "Response": true,
+	"Request": true, "Record": true, "Date": true, "Promise": true, "Blob": true, "FormData": true,
+}
+
+// initialisms are upper cased in the go names
+var initialisms = map[string]bool{
+	"api": true, "eth": true, "html": true, "http": true, "id": true, "ip": true, "json": true,
+	"nft": true, "uid": true, "uri": true, "url": true, "uuid": true,
+}
+
+// Generate returns the files of the clients of doc, keyed by their path in the sdk dir
+func Generate(doc *openapi.Document) (map[string][]byte, error) {
+	a, err := newAPI(doc)
+	if err != nil {
+		return nil, err
+	}
+	goSource, err := goClient(a)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		TypeScriptFile: typeScriptClient(a),
+		GoFile:         goSource,
+	}, nil
+}
+
+// Write writes files to dir
+func Write(dir string, files map[string][]byte) error {
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check fails when a file of dir is missing or differs from files
+func Check(dir string, files map[string][]byte) error {
+	stale := []string{}
+	for _, name := range sortedKeys(files) {
+		current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || !bytes.Equal(current, files[name]) {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("%s out of date with the api in %s, regenerate with mises sdk", strings.Join(stale, ", "), dir)
+	}
+	return nil
+}
+
+// api is the part of the document the clients call
+type api struct {
+	title   string
+	version string
+	// types are the component schemas by type name, typeNames their sorted names
+	types     map[string]*openapi.Schema
+	typeNames []string
+	// refs maps the component names to their type name
+	refs       map[string]string
+	pagination *openapi.Schema
+	errors     []errorCode
+	operations []*operation
+	// skipped are the operations which do not answer the json envelope, e.g. the exports
+	skipped []string
+}
+
+type errorCode struct {
+	name    string
+	code    int
+	status  int
+	message string
+}
+
+// auth is how an operation sends the token
+type auth string
+
+const (
+	authNone     auth = "none"
+	authOptional auth = "optional"
+	authRequired auth = "required"
+)
+
+// paging is how the pages of an operation are walked
+type paging int
+
+const (
+	pagingNone paging = iota
+	// pagingQuick follows the last_id of the pagination
+	pagingQuick
+	// pagingPage counts page_num up to the total_page of the pagination
+	pagingPage
+)
+
+type operation struct {
+	id      string
+	summary string
+	method  string
+	path    string
+	// params names the type of the path and query params of the operation
+	params     string
+	pathParams []*openapi.Parameter
+	query      []*openapi.Parameter
+	body       *openapi.Schema
+	// multipart bodies are forms, the others json
+	multipart    bool
+	bodyRequired bool
+	data         *openapi.Schema
+	// dataType names the data when it is an object of its own, empty otherwise
+	dataType string
+	// pagination is the pagination schema of the envelope, nil when the data is not paged
+	pagination *openapi.Schema
+	auth       auth
+	paging     paging
+}
+
+func newAPI(doc *openapi.Document) (*api, error) {
+	a := &api{
+		title:   doc.Info.Title,
+		version: doc.Info.Version,
+		types:   map[string]*openapi.Schema{},
+		refs:    map[string]string{},
+	}
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		if name == openapi.ErrorSchema {
+			continue
+		}
+		typeName := exportedName(name)
+		for reserved[typeName] || a.types[typeName] != nil {
+			typeName += "Type"
+		}
+		a.refs[name] = typeName
+		a.types[typeName] = doc.Components.Schemas[name]
+		a.typeNames = append(a.typeNames, typeName)
+	}
+	sort.Strings(a.typeNames)
+	a.errors = errorCodes()
+
+	pagination := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{}}
+	for _, path := range sortedKeys(doc.Paths) {
+		item := *doc.Paths[path]
+		for _, method := range sortedMethods(item) {
+			op, ok, err := a.operation(method, path, item[method], doc)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				a.skipped = append(a.skipped, fmt.Sprintf("%s (%s %s)", item[method].OperationID, strings.ToUpper(method), path))
+				continue
+			}
+			a.operations = append(a.operations, op)
+		}
+	}
+	// the pagination of the envelope merges the properties of every pagination schema
+	for _, op := range a.operations {
+		if op.pagination == nil {
+			continue
+		}
+		for _, name := range sortedKeys(op.pagination.Properties) {
+			if _, ok := pagination.Properties[name]; !ok {
+				pagination.Properties[name] = op.pagination.Properties[name]
+			}
+		}
+	}
+	a.pagination = pagination
+	sort.Slice(a.operations, func(i, j int) bool { return a.operations[i].id < a.operations[j].id })
+	return a, nil
+}
+
+// operation returns the client operation of op, false when op does not answer the json envelope
+func (a *api) operation(method, path string, op *openapi.Operation, doc *openapi.Document) (*operation, bool, error) {
+	envelope := jsonSchema(op.Responses["200"])
+	if envelope == nil || envelope.Properties["code"] == nil || envelope.Properties["data"] == nil {
+		return nil, false, nil
+	}
+	result := &operation{
+		id:      exportedName(op.OperationID),
+		summary: op.Summary,
+		method:  strings.ToUpper(method),
+		path:    path,
+		data:    envelope.Properties["data"],
+		auth:    authNone,
+	}
+	for _, requirement := range op.Security {
+		if len(requirement) == 0 {
+			result.auth = authOptional
+			break
+		}
+		result.auth = authRequired
+	}
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			result.pathParams = append(result.pathParams, param)
+		case "query":
+			result.query = append(result.query, param)
+		}
+	}
+	if op.RequestBody != nil {
+		result.bodyRequired = op.RequestBody.Required
+		if media, ok := op.RequestBody.Content["multipart/form-data"]; ok {
+			result.body, result.multipart = media.Schema, true
+		} else if media, ok := op.RequestBody.Content["application/json"]; ok {
+			result.body = media.Schema
+		} else {
+			return nil, false, fmt.Errorf("%s %s: no json or multipart request body", result.method, path)
+		}
+	}
+	result.params = a.freeName(result.id+"Params", result.id+"CallParams")
+	if data := result.data; data.Ref == "" && data.Type == "object" && len(data.Properties) > 0 {
+		result.dataType = a.freeName(result.id+"Data", result.id+"CallData")
+	}
+
+	if schema := envelope.Properties["pagination"]; schema != nil {
+		if schema.Ref != "" {
+			schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, openapi.SchemaRefPrefix)]
+		}
+		result.pagination = schema
+	}
+	if result.pagination != nil && result.data.Type == "array" {
+		switch {
+		case isString(result.pagination.Properties["last_id"]) && isString(queryParam(result, "last_id")):
+			result.paging = pagingQuick
+		case isInteger(result.pagination.Properties["total_page"]) && isInteger(queryParam(result, "page_num")):
+			result.paging = pagingPage
+		}
+	}
+	return result, true, nil
+}
+
+// freeName is name unless a type or the runtime of the clients already uses it, alt then
+func (a *api) freeName(name, alt string) string {
+	if _, taken := a.types[name]; taken || reserved[name] {
+		return alt
+	}
+	return name
+}
+
+// typeName is the type of the component schema ref refers to
+func (a *api) typeName(ref string) string {
+	return a.refs[strings.TrimPrefix(ref, openapi.SchemaRefPrefix)]
+}
+
+// errorCodes names the codes of codes.Errors after their message, e.g. 400000 invalid params
+// is InvalidParams
+func errorCodes() []errorCode {
+	result := []errorCode{}
+	names := map[string]bool{}
+	for _, err := range codes.Errors {
+		name := exportedName(err.Msg)
+		if names[name] {
+			name = fmt.Sprintf("%s%d", name, err.Code)
+		}
+		names[name] = true
+		result = append(result, errorCode{name: name, code: err.Code, status: err.HTTPStatus, message: err.Msg})
+	}
+	return result
+}
+
+func jsonSchema(response *openapi.Response) *openapi.Schema {
+	if response == nil {
+		return nil
+	}
+	media, ok := response.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+	return media.Schema
+}
+
+func queryParam(op *operation, name string) *openapi.Schema {
+	for _, param := range op.query {
+		if param.Name == name {
+			return param.Schema
+		}
+	}
+	return nil
+}
+
+func isString(schema *openapi.Schema) bool {
+	return schema != nil && schema.Type == "string" && schema.Format == ""
+}
+
+func isInteger(schema *openapi.Schema) bool {
+	return schema != nil && schema.Type == "integer"
+}
+
+// sortedMethods are the methods of item in the usual order of a route table
+func sortedMethods(item openapi.PathItem) []string {
+	order := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	methods := []string{}
+	for _, method := range order {
+		if _, ok := item[strings.ToLower(method)]; ok {
+			methods = append(methods, strings.ToLower(method))
+		}
+	}
+	return methods
+}
+
+// words splits name into its words, at the non alphanumeric chars and the lower to upper case changes
+func words(name string) []string {
+	result := []string{}
+	current := []rune{}
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				result = append(result, string(current))
+				current = current[:0]
+			}
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) && len(current) > 0 {
+			previous := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextLower) {
+				result = append(result, string(current))
+				current = current[:0]
+			}
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+// exportedName is name as an exported go identifier, e.g. status_id is StatusID
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		lower := strings.ToLower(word)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		b.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+	result := b.String()
+	if result == "" || unicode.IsDigit([]rune(result)[0]) {
+		result = "X" + result
+	}
+	return result
+}
+
+// camelName is name as a typescript method name, e.g. ListComment is listComment
+func camelName(name string) string {
+	parts := words(name)
+	if len(parts) == 0 {
+		return "x"
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(parts[0]))
+	for _, word := range parts[1:] {
+		runes := []rune(word)
+		b.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+	return b.String()
+}
+
+// comment is a line of documentation, empty when there is none
+func comment(parts ...string) string {
+	texts := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			texts = append(texts, part)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(texts, ", ")), " ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
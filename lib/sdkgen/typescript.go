@@ -0,0 +1,453 @@
+package sdkgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsWriter writes the typescript client of an api
+type tsWriter struct {
+	a *api
+	b bytes.Buffer
+}
+
+func typeScriptClient(a *api) []byte {
+	w := &tsWriter{a: a}
+	w.line("// %s", header)
+	w.line("//")
+	w.line("// Client of the %s.", a.title)
+	if len(a.skipped) > 0 {
+		w.line("// Not generated as they do not answer json: %s.", strings.Join(a.skipped, ", "))
+	}
+	w.line("")
+	w.line("/** Codes of the errors of the gateway, the code of an ApiError */")
+	w.line("export enum ErrorCode {")
+	for _, err := range a.errors {
+		w.line("  /** %s, http %d */", err.message, err.status)
+		w.line("  %s = %d,", err.name, err.code)
+	}
+	w.line("}")
+	w.line("")
+	w.b.WriteString(tsRuntime)
+	w.line("")
+	w.line("/** Pagination of the paged responses, the last_id of the quick pages or the page_num of the numbered ones */")
+	w.interfaceType("Pagination", a.pagination)
+	w.line("")
+	w.line("/** Envelope of a successful call */")
+	w.line("export interface Envelope<T> {")
+	w.line("  code: number;")
+	w.line("  data: T;")
+	w.line("  request_id?: string;")
+	w.line("  pagination?: Pagination;")
+	w.line("}")
+	for _, name := range a.typeNames {
+		w.line("")
+		w.namedType(name, a.types[name])
+	}
+	for _, op := range a.operations {
+		w.line("")
+		w.params(op)
+		if op.dataType != "" {
+			w.line("")
+			w.line("/** Data of %s */", camelName(op.id))
+			w.interfaceType(op.dataType, op.data)
+		}
+	}
+	w.line("")
+	w.client()
+	return w.b.Bytes()
+}
+
+func (w *tsWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteByte('\n')
+}
+
+func (w *tsWriter) namedType(name string, schema *openapi.Schema) {
+	if schema.Type == "object" && schema.AdditionalProperties == nil {
+		w.interfaceType(name, schema)
+		return
+	}
+	w.line("export type %s = %s;", name, w.typ(schema))
+}
+
+func (w *tsWriter) interfaceType(name string, schema *openapi.Schema) {
+	w.line("export interface %s {", name)
+	for _, property := range sortedKeys(schema.Properties) {
+		w.property("  ", property, schema.Properties[property], contains(schema.Required, property))
+	}
+	w.line("}")
+}
+
+func (w *tsWriter) property(indent, name string, schema *openapi.Schema, required bool) {
+	if text := comment(schema.Description); text != "" {
+		w.line("%s/** %s */", indent, strings.ReplaceAll(text, "*/", "* /"))
+	}
+	optional := "?"
+	if required {
+		optional = ""
+	}
+	w.line("%s%s%s: %s;", indent, tsKey(name), optional, w.typ(schema))
+}
+
+// typ is the typescript type of the json values of schema
+func (w *tsWriter) typ(schema *openapi.Schema) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if schema.Ref != "" {
+		return w.a.typeName(schema.Ref)
+	}
+	if len(schema.Enum) > 0 {
+		values := []string{}
+		for _, value := range schema.Enum {
+			literal, _ := json.Marshal(value)
+			values = append(values, string(literal))
+		}
+		return strings.Join(values, " | ")
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Format == "binary" {
+			return "Blob"
+		}
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		item := w.typ(schema.Items)
+		if strings.Contains(item, " ") && !strings.HasPrefix(item, "{") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return "Record<string, " + w.typ(schema.AdditionalProperties) + ">"
+		}
+		if len(schema.Properties) == 0 {
+			return "Record<string, unknown>"
+		}
+		fields := []string{}
+		for _, name := range sortedKeys(schema.Properties) {
+			optional := "?"
+			if contains(schema.Required, name) {
+				optional = ""
+			}
+			fields = append(fields, fmt.Sprintf("%s%s: %s", tsKey(name), optional, w.typ(schema.Properties[name])))
+		}
+		return "{ " + strings.Join(fields, "; ") + " }"
+	}
+	return "unknown"
+}
+
+// params writes the interface of the path and query params of op
+func (w *tsWriter) params(op *operation) {
+	w.line("/** Path and query params of %s */", camelName(op.id))
+	w.line("export interface %s {", op.params)
+	for _, param := range op.pathParams {
+		w.property("  ", param.Name, paramSchema(param), true)
+	}
+	for _, param := range op.query {
+		w.property("  ", param.Name, paramSchema(param), false)
+	}
+	w.line("}")
+}
+
+func (w *tsWriter) client() {
+	w.b.WriteString(tsClient)
+	for _, op := range w.a.operations {
+		w.line("")
+		w.operation(op)
+		if op.paging != pagingNone {
+			w.line("")
+			w.all(op)
+		}
+	}
+	w.line("}")
+}
+
+// paramsRequired tells whether a call of op needs params, for its path params
+func paramsRequired(op *operation) bool {
+	return len(op.pathParams) > 0
+}
+
+// signature is the parameter list of the method of op
+func (w *tsWriter) signature(op *operation) string {
+	params := "params: " + op.params
+	if !paramsRequired(op) {
+		params += " = {}"
+	}
+	if op.body == nil {
+		return params
+	}
+	body := "body: " + w.typ(op.body)
+	if op.multipart {
+		body = "body: FormData"
+	}
+	if !op.bodyRequired {
+		if paramsRequired(op) {
+			body += " | undefined"
+		} else {
+			body = strings.Replace(body, "body:", "body?:", 1)
+		}
+	}
+	return body + ", " + params
+}
+
+func (w *tsWriter) operation(op *operation) {
+	w.line("  /** %s */", comment(op.method+" "+op.path, op.summary, tsAuth(op.auth)))
+	data := w.typ(op.data)
+	if op.dataType != "" {
+		data = op.dataType
+	}
+	w.line("  %s(%s): Promise<Envelope<%s>> {", camelName(op.id), w.signature(op), data)
+	path := strings.ReplaceAll(op.path, "`", "\\`")
+	omit := []string{}
+	for _, param := range op.pathParams {
+		path = strings.ReplaceAll(path, "{"+param.Name+"}", "${pathValue("+tsAccess("params", param.Name)+")}")
+		omit = append(omit, fmt.Sprintf("%q", param.Name))
+	}
+	query := "params"
+	if len(omit) > 0 {
+		query = "omit(params, " + strings.Join(omit, ", ") + ")"
+	}
+	args := fmt.Sprintf("%q, `%s`, %t, %s", op.method, path, op.auth != authNone, query)
+	if op.body != nil {
+		args += ", body"
+	}
+	w.line("    return this.request(%s);", args)
+	w.line("  }")
+}
+
+// all writes the async generator walking the pages of op
+func (w *tsWriter) all(op *operation) {
+	name := camelName(op.id)
+	args := "page"
+	if op.body != nil {
+		args = "body, page"
+	}
+	w.line("  /** Every item of %s, page after page from the page of params */", name)
+	w.line("  async *%sAll(%s): AsyncGenerator<%s> {", name, w.signature(op), w.typ(op.data.Items))
+	switch op.paging {
+	case pagingQuick:
+		w.line("    for (let page = { ...params }; ; ) {")
+		w.line("      const response = await this.%s(%s);", name, args)
+		w.line("      const items = response.data ?? [];")
+		w.line("      yield* items;")
+		w.line("      const next = response.pagination?.last_id;")
+		w.line("      if (!items.length || !next || next === page.last_id) return;")
+		w.line("      page = { ...page, last_id: next };")
+		w.line("    }")
+	case pagingPage:
+		w.line("    for (let page = { ...params, page_num: params.page_num || 1 }; ; page = { ...page, page_num: page.page_num + 1 }) {")
+		w.line("      const response = await this.%s(%s);", name, args)
+		w.line("      const items = response.data ?? [];")
+		w.line("      yield* items;")
+		w.line("      if (!items.length || page.page_num >= (response.pagination?.total_page ?? 0)) return;")
+		w.line("    }")
+	}
+	w.line("  }")
+}
+
+func tsAuth(mode auth) string {
+	switch mode {
+	case authRequired:
+		return "needs the token"
+	case authOptional:
+		return "sends the token when there is one"
+	}
+	return ""
+}
+
+// paramSchema is the schema of the value of param, sent as a string
+func paramSchema(param *openapi.Parameter) *openapi.Schema {
+	schema := param.Schema
+	if schema == nil || schema.Ref != "" || schema.Type == "object" || schema.Type == "" {
+		schema = &openapi.Schema{Type: "string"}
+	}
+	if param.Description == "" {
+		return schema
+	}
+	described := *schema
+	described.Description = param.Description
+	return &described
+}
+
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+func tsAccess(object, name string) string {
+	if tsIdentifier.MatchString(name) {
+		return object + "." + name
+	}
+	return fmt.Sprintf("%s[%q]", object, name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// tsRuntime is the part of the typescript client before the types of the api
+const tsRuntime = `/** Request field that failed validation, in is where it was sent when the gateway did not expect it there */
+export interface FieldError {
+  field: string;
+  rule: string;
+  in?: "query" | "body" | "path";
+  message: string;
+}
+
+/** Call answered with an error code */
+export class ApiError extends Error {
+  constructor(
+    /** http status of the response */
+    readonly status: number,
+    readonly code: ErrorCode | number,
+    message: string,
+    /** request fields that failed validation */
+    readonly fields: FieldError[] = [],
+    readonly requestId?: string,
+  ) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+export interface ClientOptions {
+  /** scheme and host of the gateway, e.g. https://api.mises.site */
+  baseURL: string;
+  /** session token of the sign in, or a function returning it, no token is sent when it is empty */
+  token?: string | (() => string | undefined | Promise<string | undefined>);
+  /**
+   * Retries of a failed call, 2 by default. GET, HEAD, PUT and DELETE are retried on network errors,
+   * 429, 502, 503 and 504, the other methods only on 429.
+   */
+  retries?: number;
+  /** delay of the first retry, doubled after each unless the response has a Retry-After, 200 by default */
+  retryDelayMs?: number;
+  /** headers sent with every request */
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+`
+
+// tsClient starts the client class, the methods of the operations follow
+const tsClient = `type Query = Record<string, unknown>;
+
+const idempotent = ["GET", "HEAD", "PUT", "DELETE"];
+const retriedStatuses = [502, 503, 504];
+
+function sleep(ms: number): Promise<void> {
+  return new Promise((resolve) => setTimeout(resolve, ms));
+}
+
+function pathValue(value: unknown): string {
+  return encodeURIComponent(String(value));
+}
+
+function omit(params: object, ...keys: string[]): Query {
+  const query: Query = { ...params };
+  for (const key of keys) delete query[key];
+  return query;
+}
+
+export class Client {
+  private readonly options: ClientOptions;
+  private readonly fetcher: typeof fetch;
+
+  constructor(options: ClientOptions) {
+    this.options = { ...options, baseURL: options.baseURL.replace(/\/+$/, "") };
+    this.fetcher = options.fetch ?? ((input, init) => fetch(input, init));
+  }
+
+  private async token(): Promise<string | undefined> {
+    const token = this.options.token;
+    return typeof token === "function" ? await token() : token;
+  }
+
+  /** delay before the next attempt, undefined when the call is not retried */
+  private retryDelay(method: string, attempt: number, response?: Response): number | undefined {
+    if (attempt >= (this.options.retries ?? 2)) return undefined;
+    const retried = response
+      ? response.status === 429 || (idempotent.includes(method) && retriedStatuses.includes(response.status))
+      : idempotent.includes(method);
+    if (!retried) return undefined;
+    const retryAfter = Number(response?.headers.get("Retry-After") ?? NaN);
+    if (retryAfter >= 0) return retryAfter * 1000;
+    return (this.options.retryDelayMs ?? 200) * 2 ** attempt;
+  }
+
+  private async request<T>(method: string, path: string, auth: boolean, query: Query, body?: unknown): Promise<Envelope<T>> {
+    const url = new URL(this.options.baseURL + path);
+    for (const [key, value] of Object.entries(query)) {
+      if (value === undefined || value === null || value === "") continue;
+      if (Array.isArray(value)) value.forEach((item) => url.searchParams.append(key, String(item)));
+      else url.searchParams.set(key, String(value));
+    }
+    const headers: Record<string, string> = { ...this.options.headers, Accept: "application/json" };
+    if (auth) {
+      const token = await this.token();
+      if (token) headers.Authorization = ` + "`Bearer ${token}`" + `;
+    }
+    let payload: FormData | string | undefined;
+    if (body instanceof FormData) {
+      payload = body;
+    } else if (body !== undefined) {
+      payload = JSON.stringify(body);
+      headers["Content-Type"] = "application/json";
+    }
+
+    for (let attempt = 0; ; attempt++) {
+      let response: Response;
+      try {
+        response = await this.fetcher(url.toString(), { method, headers, body: payload });
+      } catch (err) {
+        const delay = this.retryDelay(method, attempt);
+        if (delay === undefined) throw err;
+        await sleep(delay);
+        continue;
+      }
+      const delay = this.retryDelay(method, attempt, response);
+      if (delay !== undefined) {
+        await sleep(delay);
+        continue;
+      }
+      const text = await response.text();
+      let envelope: any;
+      try {
+        envelope = text ? JSON.parse(text) : undefined;
+      } catch {
+        envelope = undefined;
+      }
+      if (!response.ok || !envelope || envelope.code !== 0) {
+        throw new ApiError(
+          response.status,
+          envelope?.code ?? 0,
+          envelope?.message ?? response.statusText,
+          envelope?.fields ?? [],
+          response.headers.get("X-Request-Id") ?? undefined,
+        );
+      }
+      return envelope as Envelope<T>;
+    }
+  }
+`
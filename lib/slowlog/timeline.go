@@ -0,0 +1,84 @@
+package slowlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+)
+
+// Phase is a step of a request, bind, a backend call or the serialization of the response
+type Phase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"-"`
+	Millis   float64       `json:"ms"`
+}
+
+// Timeline collects the phases of a request, the backend calls of a handler may run concurrently
+type Timeline struct {
+	mutex  sync.Mutex
+	phases []Phase
+}
+
+// Add appends a phase, a nil timeline ignores it
+func (t *Timeline) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.phases = append(t.phases, Phase{Name: name, Duration: d, Millis: millis(d)})
+}
+
+// Phases returns a copy of the phases in the order they ended
+func (t *Timeline) Phases() []Phase {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]Phase(nil), t.phases...)
+}
+
+type timelineKey struct{}
+
+// WithTimeline returns a context carrying a new timeline
+func WithTimeline(ctx context.Context) (context.Context, *Timeline) {
+	t := &Timeline{}
+	return context.WithValue(ctx, timelineKey{}, t), t
+}
+
+// FromContext is the timeline of the request of ctx, nil outside of the middleware
+func FromContext(ctx context.Context) *Timeline {
+	t, _ := ctx.Value(timelineKey{}).(*Timeline)
+	return t
+}
+
+// Observe adds the phase name started at start to the timeline of ctx, to be deferred
+func Observe(ctx context.Context, name string, start time.Time) {
+	FromContext(ctx).Add(name, time.Since(start))
+}
+
+// Binder times the binding of the request params
+type Binder struct {
+	echo.Binder
+}
+
+func (b Binder) Bind(i interface{}, c echo.Context) error {
+	defer Observe(c.Request().Context(), "bind", time.Now())
+	return b.Binder.Bind(i, c)
+}
+
+// UnaryClientInterceptor adds every call to backend to the timeline of the request
+func UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		defer Observe(ctx, backend+method, time.Now())
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,282 @@
+package slowlog
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	"github.com/mises-id/sns-apigateway/lib/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons of a slow request
+const (
+	ReasonThreshold = "threshold"
+	ReasonP95       = "p95"
+)
+
+// minSamples a route needs before its p95 is trusted
+const minSamples = 50
+
+// maxRoutes bounds the windows kept, the routes past it are only checked against the threshold
+const maxRoutes = 1024
+
+// Config of the slow requests, a zero Threshold or P95Multiple disables the rule
+type Config struct {
+	// Threshold is the latency any request is slow above
+	Threshold time.Duration
+	// P95Multiple is the multiple of the p95 of its route a request is slow above
+	P95Multiple float64
+	// MinLatency keeps the fast routes from reporting a request slow by the p95 rule
+	MinLatency time.Duration
+}
+
+// Event is a slow request with the breakdown of its latency
+type Event struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	URI       string    `json:"uri"`
+	Status    int       `json:"status"`
+	RequestID string    `json:"request_id"`
+	Reason    string    `json:"reason"`
+	Millis    float64   `json:"ms"`
+	P95Millis float64   `json:"p95_ms"`
+	Phases    []Phase   `json:"phases"`
+	// OtherMillis is the time not spent in a phase, negative when backend calls overlapped
+	OtherMillis float64 `json:"other_ms"`
+}
+
+// RouteStats are the latency percentiles of a route over its window
+type RouteStats struct {
+	Route     string  `json:"route"`
+	Samples   int     `json:"samples"`
+	P50Millis float64 `json:"p50_ms"`
+	P95Millis float64 `json:"p95_ms"`
+	P99Millis float64 `json:"p99_ms"`
+}
+
+// window holds the last latencies of a route, the p95 is recomputed every size/8 samples
+type window struct {
+	samples []time.Duration
+	next    int
+	full    bool
+	stale   int
+	p95     time.Duration
+}
+
+func (w *window) add(d time.Duration) {
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+	w.stale++
+}
+
+func (w *window) count() int {
+	if w.full {
+		return len(w.samples)
+	}
+	return w.next
+}
+
+func (w *window) sorted() []time.Duration {
+	sorted := append([]time.Duration(nil), w.samples[:w.count()]...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// currentP95 is 0 until the window has minSamples
+func (w *window) currentP95() time.Duration {
+	if w.count() < minSamples {
+		return 0
+	}
+	if w.p95 == 0 || w.stale >= len(w.samples)/8 {
+		w.p95 = percentile(w.sorted(), 0.95)
+		w.stale = 0
+	}
+	return w.p95
+}
+
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Tracker keeps a rolling window of latencies per route and the last slow requests
+type Tracker struct {
+	windowSize int
+	mutex      sync.Mutex
+	routes     map[string]*window
+	events     []Event
+	keep       int
+	config     atomic.Value
+	// patterns are the route paths of each echo the middleware serves
+	patterns sync.Map
+}
+
+// NewTracker keeps windowSize latencies per route and the last keep slow requests
+func NewTracker(windowSize, keep int) *Tracker {
+	t := &Tracker{windowSize: windowSize, keep: keep, routes: map[string]*window{}}
+	t.config.Store(Config{})
+	return t
+}
+
+// Default is the tracker of the gateway server, reported on the admin server
+var Default = NewTracker(512, 100)
+
+// SetConfig changes the rules of the slow requests
+func (t *Tracker) SetConfig(cfg Config) {
+	t.config.Store(cfg)
+}
+
+// Middleware carries a timeline in the request context, measures the request and logs it
+// with its breakdown when it is slow
+func (t *Tracker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, timeline := WithTimeline(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// the error is rendered now so its serialization is part of the request
+				c.Error(err)
+			}
+			latency := time.Since(start)
+			route := c.Path()
+			if route == "" || !t.matched(c) {
+				return err
+			}
+			reason, p95 := t.observe(route, latency)
+			if reason == "" {
+				return err
+			}
+			event := t.record(c, route, reason, latency, p95, timeline.Phases())
+			metrics.CountSlowRequest(route, reason)
+			breakdown := make([]string, 0, len(event.Phases)+1)
+			for _, phase := range event.Phases {
+				breakdown = append(breakdown, fmt.Sprintf("%s=%.1fms", phase.Name, phase.Millis))
+			}
+			breakdown = append(breakdown, fmt.Sprintf("other=%.1fms", event.OtherMillis))
+			logging.FromContext(c).WithFields(logrus.Fields{
+				"latency":   event.Millis,
+				"p95":       event.P95Millis,
+				"reason":    reason,
+				"breakdown": strings.Join(breakdown, " "),
+			}).Warnf("slow request %s %s took %s", c.Request().Method, route, latency)
+			return err
+		}
+	}
+}
+
+// observe adds latency to the window of route and returns why it is slow, with the p95 it was
+// compared to, the p95 is taken before the request is added
+func (t *Tracker) observe(route string, latency time.Duration) (string, time.Duration) {
+	cfg := t.config.Load().(Config)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var p95 time.Duration
+	w, ok := t.routes[route]
+	if !ok && len(t.routes) < maxRoutes {
+		w = &window{samples: make([]time.Duration, t.windowSize)}
+		t.routes[route] = w
+	}
+	if w != nil {
+		p95 = w.currentP95()
+		w.add(latency)
+	}
+	switch {
+	case cfg.Threshold > 0 && latency > cfg.Threshold:
+		return ReasonThreshold, p95
+	case cfg.P95Multiple > 0 && p95 > 0 && latency >= cfg.MinLatency &&
+		float64(latency) > cfg.P95Multiple*float64(p95):
+		return ReasonP95, p95
+	}
+	return "", p95
+}
+
+// matched tells if the path of the request is a route of the table, echo sets the path of the
+// others to their raw url, which would grow a window per url. The table is read on the first
+// request, the routes are all registered by then.
+func (t *Tracker) matched(c echo.Context) bool {
+	patterns, ok := t.patterns.Load(c.Echo())
+	if !ok {
+		paths := map[string]bool{}
+		for _, r := range c.Echo().Routes() {
+			paths[r.Path] = true
+		}
+		patterns, _ = t.patterns.LoadOrStore(c.Echo(), paths)
+	}
+	return patterns.(map[string]bool)[c.Path()]
+}
+
+func (t *Tracker) record(c echo.Context, route, reason string, latency, p95 time.Duration, phases []Phase) Event {
+	event := Event{
+		Time:      time.Now(),
+		Method:    c.Request().Method,
+		Route:     route,
+		URI:       logging.Redact(c.Request().RequestURI),
+		Status:    c.Response().Status,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		Reason:    reason,
+		Millis:    millis(latency),
+		P95Millis: millis(p95),
+		Phases:    phases,
+	}
+	other := latency
+	for _, phase := range phases {
+		other -= phase.Duration
+	}
+	event.OtherMillis = millis(other)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, event)
+	if len(t.events) > t.keep {
+		t.events = t.events[len(t.events)-t.keep:]
+	}
+	return event
+}
+
+// Events returns the last slow requests, newest first
+func (t *Tracker) Events() []Event {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	events := make([]Event, len(t.events))
+	for i, event := range t.events {
+		events[len(t.events)-1-i] = event
+	}
+	return events
+}
+
+// Stats returns the percentiles of every route, sorted by route
+func (t *Tracker) Stats() []RouteStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := make([]RouteStats, 0, len(t.routes))
+	for route, w := range t.routes {
+		sorted := w.sorted()
+		stats = append(stats, RouteStats{
+			Route:     route,
+			Samples:   len(sorted),
+			P50Millis: millis(percentile(sorted, 0.5)),
+			P95Millis: millis(percentile(sorted, 0.95)),
+			P99Millis: millis(percentile(sorted, 0.99)),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
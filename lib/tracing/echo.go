@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span per request, continuing the trace of the traceparent header.
+// The span is named by the route, the uid set by the session middleware and the chain_id
+// param or query are added once the handler returns.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+			ctx, span := Tracer().Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.HTTPTarget(req.URL.RequestURI()),
+					attribute.String("http.request_id", requestID(c)),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// write the error now so the span reports its status, echo skips committed responses
+				c.Error(err)
+				span.RecordError(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			if uid, ok := c.Get("CurrentUID").(uint64); ok && uid > 0 {
+				span.SetAttributes(UIDAttribute(uid))
+			}
+			chainID := c.Param("chain_id")
+			if chainID == "" {
+				chainID = c.QueryParam("chain_id")
+			}
+			if chainID != "" {
+				span.SetAttributes(attribute.String("chain_id", chainID))
+			}
+			return err
+		}
+	}
+}
+
+// requestID is the id of the caller or the one the request id middleware generated
+func requestID(c echo.Context) string {
+	if id := c.Request().Header.Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
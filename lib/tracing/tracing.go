@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	instrumentation = "github.com/mises-id/sns-apigateway"
+)
+
+// Config selects where the spans are exported
+type Config struct {
+	Exporter string
+	// Endpoint is the host:port of the otlp collector, the OTEL_EXPORTER_OTLP_* env variables apply when empty
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+	ServiceName string
+	Version     string
+}
+
+// Setup installs the global tracer provider and the w3c trace context propagator,
+// the returned func flushes the spans left and stops the exporter
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{}
+		if config.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %s", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider := NewProvider(config, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider sampling config.SampleRatio of the new traces,
+// a request keeps the sampling decision of its caller
+func NewProvider(config Config, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(config.Version),
+	)
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	}, options...)
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Tracer returns the tracer of the gateway from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// HashUID groups the spans of a user without exporting the raw uid
+func HashUID(uid uint64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(uid)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// UIDAttribute is the hashed uid attribute of a span
+func UIDAttribute(uid uint64) attribute.KeyValue {
+	return attribute.String("enduser.id_hash", HashUID(uid))
+}
@@ -0,0 +1,183 @@
+package validation
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+)
+
+const (
+	// MaxPageSize is the largest page a list endpoint returns
+	MaxPageSize = 100
+	// MaxChainID is the largest chain id EIP-2294 allows
+	MaxChainID = 4503599627370476
+
+	// embedded names the anonymous fields, they are dropped from the field paths
+	embedded = "\x00"
+)
+
+var (
+	evmAddress = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	misesID    = regexp.MustCompile(`^(did:mises:)?mises1[02-9ac-hj-np-z]{38}$`)
+	decimal    = regexp.MustCompile(`^[0-9]{1,78}(\.[0-9]{1,78})?$`)
+
+	// Default validates the requests of the gateway
+	Default = New()
+)
+
+// Validator validates the bound request params against their validate tags, it is the
+// echo.Validator of the server
+type Validator struct {
+	validate *validator.Validate
+}
+
+// New returns a Validator with the gateway rules:
+//
+//	evm_address  a 0x prefixed hex address of 20 bytes
+//	misesid      a mises account, with or without the did:mises: prefix
+//	chain_id     a chain id between 1 and MaxChainID
+//	decimal      a non negative decimal number, e.g. an amount in wei
+//	page_size    a page size no larger than MaxPageSize, 0 picks the default
+func New() *Validator {
+	v := validator.New()
+	v.RegisterTagNameFunc(fieldName)
+	_ = v.RegisterValidation("evm_address", matches(evmAddress))
+	_ = v.RegisterValidation("misesid", matches(misesID))
+	_ = v.RegisterValidation("decimal", matches(decimal))
+	_ = v.RegisterValidation("chain_id", func(fl validator.FieldLevel) bool {
+		id, ok := unsigned(fl.Field())
+		return ok && id >= 1 && id <= MaxChainID
+	})
+	_ = v.RegisterValidation("page_size", func(fl validator.FieldLevel) bool {
+		size, ok := unsigned(fl.Field())
+		return ok && size <= MaxPageSize
+	})
+	return &Validator{validate: v}
+}
+
+// RegisterStructValidation registers a check across the fields of the types, it reports the
+// failed fields with StructLevel.ReportError
+func (v *Validator) RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	v.validate.RegisterStructValidation(fn, types...)
+}
+
+// Validate checks i, the failed fields are returned as a codes.FieldsError named as the
+// client sent them
+func (v *Validator) Validate(i interface{}) error {
+	err := v.validate.Struct(i)
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	root := reflect.TypeOf(i)
+	for root.Kind() == reflect.Ptr {
+		root = root.Elem()
+	}
+	fields := make([]codes.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		field := path(root.Name(), fe.Namespace())
+		fields = append(fields, codes.FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: message(field, fe),
+		})
+	}
+	return codes.InvalidFields(fields...)
+}
+
+// fieldName names a field as it is bound, by its json tag then its query, form or path param
+func fieldName(field reflect.StructField) string {
+	if field.Anonymous {
+		return embedded
+	}
+	for _, key := range []string{"json", "query", "form", "param"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// path drops the root type and the embedded structs from the namespace of a failed field
+func path(root, namespace string) string {
+	if root != "" {
+		namespace = strings.TrimPrefix(namespace, root+".")
+	}
+	return strings.ReplaceAll(namespace, embedded+".", "")
+}
+
+func message(field string, fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "required_if":
+		return fmt.Sprintf("%s is required when %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s%s", field, fe.Param(), unit(fe.Kind()))
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s%s", field, fe.Param(), unit(fe.Kind()))
+	case "len":
+		return fmt.Sprintf("%s must be %s%s long", field, fe.Param(), unit(fe.Kind()))
+	case "email", "url":
+		return fmt.Sprintf("%s must be a valid %s", field, fe.Tag())
+	case "evm_address":
+		return fmt.Sprintf("%s must be a 0x prefixed hex address of 20 bytes", field)
+	case "misesid":
+		return fmt.Sprintf("%s must be a mises id", field)
+	case "chain_id":
+		return fmt.Sprintf("%s must be a chain id between 1 and %d", field, uint64(MaxChainID))
+	case "decimal":
+		return fmt.Sprintf("%s must be a non negative decimal number", field)
+	case "page_size":
+		return fmt.Sprintf("%s must be at most %d", field, MaxPageSize)
+	}
+	return fmt.Sprintf("%s is invalid (%s)", field, fe.Tag())
+}
+
+// unit is the unit of the length rules
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	}
+	return ""
+}
+
+func matches(re *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return re.MatchString(fl.Field().String())
+	}
+}
+
+// unsigned reads an integer field, or a string holding one, a negative value is invalid
+func unsigned(field reflect.Value) (uint64, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Int() < 0 {
+			return 0, false
+		}
+		return uint64(field.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint(), true
+	case reflect.String:
+		n, ok := new(big.Int).SetString(field.String(), 10)
+		if !ok || n.Sign() < 0 || !n.IsUint64() {
+			return 0, false
+		}
+		return n.Uint64(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,161 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const envelopeProto = `// Response is the envelope of every protobuf response, data and pagination
+// hold one of the messages below.
+message Response {
+  int32 code = 1;
+  string message = 2;
+  google.protobuf.Any data = 3;
+  string request_id = 4;
+  google.protobuf.Any pagination = 5;
+}
+`
+
+// Service is an rpc service of the descriptor, the messages of its methods are the ones of Go types
+type Service struct {
+	Name    string
+	Methods []ServiceMethod
+}
+
+// ServiceMethod is a unary method of a Service
+type ServiceMethod struct {
+	Name     string
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// WriteProto writes the .proto descriptor of the envelope and of every message
+// reachable from types, in the same layout MarshalProto encodes them
+func WriteProto(w io.Writer, types ...reflect.Type) error {
+	return WriteServiceProto(w, nil, types...)
+}
+
+// WriteServiceProto is WriteProto followed by services, with the messages of their methods
+func WriteServiceProto(w io.Writer, services []Service, types ...reflect.Type) error {
+	c := &collector{messages: map[string]string{}}
+	messages := c.messages
+	for _, service := range services {
+		for _, method := range service.Methods {
+			types = append(types, method.Request, method.Response)
+		}
+	}
+	for _, t := range types {
+		if isMessageList(t) {
+			elem := indirect(indirect(t).Elem())
+			messages[elem.Name()+"List"] = fmt.Sprintf("message %sList {\n  repeated %s items = 1;\n}\n", elem.Name(), elem.Name())
+			t = elem
+		}
+		if isMessage(t) {
+			c.collect(indirect(t))
+		}
+	}
+	if c.err != nil {
+		return c.err
+	}
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := &strings.Builder{}
+	builder.WriteString("// Code generated by cmd/protogen. DO NOT EDIT.\n\n")
+	builder.WriteString("syntax = \"proto3\";\n\n")
+	builder.WriteString("package " + ProtoPackage + ";\n\n")
+	builder.WriteString("import \"google/protobuf/any.proto\";\n")
+	builder.WriteString("import \"google/protobuf/struct.proto\";\n")
+	builder.WriteString("import \"google/protobuf/timestamp.proto\";\n\n")
+	builder.WriteString(envelopeProto)
+	for _, name := range names {
+		builder.WriteString("\n" + messages[name])
+	}
+	for _, service := range services {
+		builder.WriteString("\nservice " + service.Name + " {\n")
+		for _, method := range service.Methods {
+			builder.WriteString(fmt.Sprintf("  rpc %s(%s) returns (%s);\n", method.Name, indirect(method.Request).Name(), indirect(method.Response).Name()))
+		}
+		builder.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// collector writes the messages reachable from a type, err is the first field number used twice
+type collector struct {
+	messages map[string]string
+	err      error
+}
+
+func (c *collector) collect(t reflect.Type) {
+	if _, ok := c.messages[t.Name()]; ok {
+		return
+	}
+	// reserve the name first, message types may refer to themselves
+	c.messages[t.Name()] = ""
+	builder := &strings.Builder{}
+	builder.WriteString("message " + t.Name() + " {\n")
+	numbers := map[protowire.Number]string{}
+	for _, field := range protoFields(t) {
+		if other, ok := numbers[field.number]; ok && c.err == nil {
+			c.err = fmt.Errorf("%s: fields %s and %s are both numbered %d", t.Name(), other, field.name, field.number)
+		}
+		numbers[field.number] = field.name
+		fieldType := t.FieldByIndex(field.index).Type
+		builder.WriteString(fmt.Sprintf("  %s %s = %d;\n", c.protoType(fieldType), field.name, field.number))
+	}
+	builder.WriteString("}\n")
+	c.messages[t.Name()] = builder.String()
+}
+
+func (c *collector) protoType(t reflect.Type) string {
+	if isValue(t) {
+		return valueTypeName
+	}
+	t = indirect(t)
+	switch {
+	case t == timeType:
+		return "google.protobuf.Timestamp"
+	case t.Kind() == reflect.Struct:
+		c.collect(t)
+		return t.Name()
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "bytes"
+	case t.Kind() == reflect.Slice:
+		return "repeated " + c.protoType(t.Elem())
+	case t.Kind() == reflect.Map:
+		return "map<string, " + c.protoType(t.Elem()) + ">"
+	}
+	return scalarProtoType(t.Kind())
+}
+
+func scalarProtoType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return "int32"
+	case reflect.Int, reflect.Int64:
+		return "int64"
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "uint32"
+	case reflect.Uint, reflect.Uint64:
+		return "uint64"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	}
+	return valueTypeName
+}
@@ -0,0 +1,394 @@
+package wire
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Protobuf encoding of plain Go response structs.
+//
+// A struct maps to a message whose fields are numbered 1, 2, 3... in declaration order,
+// named after their json tag. Fields of embedded structs are inlined in place.
+// A proto tag pins the number of a field, e.g. `proto:"7"`, the fields after it are numbered
+// from it, so a field inserted or removed keeps the numbers of the others with tags. New fields
+// are otherwise appended at the end of a response struct, the descriptor test fails when the
+// numbers of proto/gateway/v1/gateway.proto change.
+// time.Time maps to google.protobuf.Timestamp, and anything without a direct protobuf
+// equivalent (interface values, nested maps or slices) to google.protobuf.Value.
+
+const (
+	TypeURLPrefix = "type.googleapis.com/"
+	ProtoPackage  = "mises.gateway.v1"
+	valueTypeName = "google.protobuf.Value"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type protoField struct {
+	index  []int
+	number protowire.Number
+	name   string
+}
+
+// protoFields returns the message fields of struct type t
+func protoFields(t reflect.Type) []protoField {
+	fields := make([]protoField, 0, t.NumField())
+	next := protowire.Number(1)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name := strings.Split(tag, ",")[0]
+			fieldIndex := append(append([]int{}, index...), i)
+			if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+				walk(indirect(field.Type), fieldIndex)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			number := next
+			if n, err := strconv.ParseInt(field.Tag.Get("proto"), 10, 32); err == nil && n > 0 {
+				number = protowire.Number(n)
+			}
+			if number >= next {
+				next = number + 1
+			}
+			fields = append(fields, protoField{
+				index:  fieldIndex,
+				number: number,
+				name:   name,
+			})
+		}
+	}
+	walk(t, nil)
+	return fields
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isScalar(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isMessage reports whether t is encoded as a message of its own
+func isMessage(t reflect.Type) bool {
+	t = indirect(t)
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// isValue reports whether t has no direct protobuf equivalent
+func isValue(t reflect.Type) bool {
+	t = indirect(t)
+	switch {
+	case t == timeType, isScalar(t.Kind()), t.Kind() == reflect.Struct:
+		return false
+	case t.Kind() == reflect.Slice:
+		elem := indirect(t.Elem())
+		return elem.Kind() != reflect.Uint8 && !isScalar(elem.Kind()) && !isMessage(elem) && elem != timeType
+	case t.Kind() == reflect.Map:
+		return t.Key().Kind() != reflect.String || !isScalar(indirect(t.Elem()).Kind())
+	}
+	return true
+}
+
+// MarshalProto encodes a struct, or a pointer to a struct, as a protobuf message
+func MarshalProto(v interface{}) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	return appendMessage(nil, value)
+}
+
+func appendMessage(b []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	for _, field := range protoFields(v.Type()) {
+		fieldValue, ok := fieldByIndex(v, field.index)
+		if !ok {
+			continue
+		}
+		if b, err = appendField(b, field.number, fieldValue); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex without panics on nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func appendField(b []byte, number protowire.Number, v reflect.Value) ([]byte, error) {
+	t := v.Type()
+	if isValue(t) {
+		if isZero(v) {
+			return b, nil
+		}
+		value, err := marshalValue(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, value), nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return b, nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		tm := v.Interface().(time.Time)
+		if tm.IsZero() {
+			return b, nil
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, appendTimestamp(nil, tm)), nil
+	case v.Kind() == reflect.Struct:
+		message, err := appendMessage(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, message), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Len() == 0 {
+			return b, nil
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, v.Bytes()), nil
+	case v.Kind() == reflect.Slice:
+		return appendRepeated(b, number, v)
+	case v.Kind() == reflect.Map:
+		return appendMap(b, number, v)
+	default:
+		if isZero(v) {
+			return b, nil
+		}
+		return appendScalar(b, number, v), nil
+	}
+}
+
+func appendRepeated(b []byte, number protowire.Number, v reflect.Value) ([]byte, error) {
+	if v.Len() == 0 {
+		return b, nil
+	}
+	elemKind := v.Type().Elem().Kind()
+	if isScalar(elemKind) && elemKind != reflect.String {
+		var packed []byte
+		for i := 0; i < v.Len(); i++ {
+			packed = appendScalarValue(packed, v.Index(i))
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, packed), nil
+	}
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Ptr && item.IsNil() {
+			// repeated fields cannot hold null, keep the position with an empty message
+			b = protowire.AppendTag(b, number, protowire.BytesType)
+			b = protowire.AppendBytes(b, nil)
+			continue
+		}
+		item = reflect.Indirect(item)
+		if isScalar(item.Kind()) {
+			b = appendScalar(b, number, item)
+			continue
+		}
+		if b, err = appendElement(b, number, item); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendElement appends a message element of a repeated field, zero values included
+func appendElement(b []byte, number protowire.Number, v reflect.Value) ([]byte, error) {
+	v = reflect.Indirect(v)
+	var message []byte
+	var err error
+	if v.Type() == timeType {
+		message = appendTimestamp(nil, v.Interface().(time.Time))
+	} else if message, err = appendMessage(nil, v); err != nil {
+		return nil, err
+	}
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message), nil
+}
+
+func appendMap(b []byte, number protowire.Number, v reflect.Value) ([]byte, error) {
+	iter := v.MapRange()
+	for iter.Next() {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, iter.Key().String())
+		value := reflect.Indirect(iter.Value())
+		if value.IsValid() {
+			entry = appendScalar(entry, 2, value)
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b, nil
+}
+
+func appendScalar(b []byte, number protowire.Number, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.String:
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendString(b, v.String())
+	case reflect.Float32:
+		b = protowire.AppendTag(b, number, protowire.Fixed32Type)
+	case reflect.Float64:
+		b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	default:
+		b = protowire.AppendTag(b, number, protowire.VarintType)
+	}
+	return appendScalarValue(b, v)
+}
+
+func appendScalarValue(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return protowire.AppendVarint(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return protowire.AppendVarint(b, v.Uint())
+	case reflect.Float32:
+		return protowire.AppendFixed32(b, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	}
+	return b
+}
+
+func appendTimestamp(b []byte, t time.Time) []byte {
+	if seconds := t.Unix(); seconds != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(seconds))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(nanos))
+	}
+	return b
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil() || (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Len() == 0)
+	}
+	return v.IsZero()
+}
+
+// marshalValue encodes v as a google.protobuf.Value through its JSON encoding
+func marshalValue(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err = json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	value, err := structpb.NewValue(generic)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(value)
+}
+
+// isMessageList reports whether t is a slice of messages
+func isMessageList(t reflect.Type) bool {
+	t = indirect(t)
+	return t.Kind() == reflect.Slice && isMessage(t.Elem())
+}
+
+// MessageName returns the protobuf message name used for values of type t,
+// slices of messages are wrapped into a <Name>List message
+func MessageName(t reflect.Type) string {
+	switch {
+	case t == nil:
+		return valueTypeName
+	case isMessageList(t):
+		return ProtoPackage + "." + indirect(indirect(t).Elem()).Name() + "List"
+	case isMessage(t):
+		return ProtoPackage + "." + indirect(t).Name()
+	}
+	return valueTypeName
+}
+
+// MarshalAny encodes v as a google.protobuf.Any
+func MarshalAny(v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	var (
+		value []byte
+		err   error
+	)
+	switch {
+	case t == nil:
+		value, err = marshalValue(v)
+	case isMessageList(t):
+		// <Name>List has a single repeated field numbered 1
+		value, err = appendRepeated(nil, 1, reflect.Indirect(reflect.ValueOf(v)))
+	case isMessage(t):
+		value, err = MarshalProto(v)
+	default:
+		value, err = marshalValue(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, TypeURLPrefix+MessageName(t))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendBytes(b, value), nil
+}
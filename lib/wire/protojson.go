@@ -0,0 +1,150 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalProtoJSON decodes the json message b into the struct v points to with the protojson
+// rules, a field is named after its json tag or its lowerCamel json_name, e.g. to_user_id or
+// toUserId, and an integer or a float may be quoted like protojson writes the 64 bit ones
+func UnmarshalProtoJSON(b []byte, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("wire: UnmarshalProtoJSON needs a pointer to a struct")
+	}
+	normalized, err := normalizeJSON(value.Elem().Type(), b)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, v)
+}
+
+// normalizeJSON rewrites raw, a value of type t, to the encoding/json form of t
+func normalizeJSON(t reflect.Type, raw json.RawMessage) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if string(raw) == "null" {
+		return raw, nil
+	}
+	if isValue(t) {
+		return raw, nil
+	}
+	t = indirect(t)
+	switch {
+	case t == timeType:
+		return raw, nil
+	case t.Kind() == reflect.Struct:
+		return normalizeMessage(t, raw)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return raw, nil
+	case t.Kind() == reflect.Slice:
+		items := []json.RawMessage{}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			normalized, err := normalizeJSON(t.Elem(), item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = normalized
+		}
+		return json.Marshal(items)
+	case t.Kind() == reflect.Map:
+		entries := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, err
+		}
+		for key, entry := range entries {
+			normalized, err := normalizeJSON(t.Elem(), entry)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = normalized
+		}
+		return json.Marshal(entries)
+	}
+	return normalizeScalar(t.Kind(), raw)
+}
+
+func normalizeMessage(t reflect.Type, raw json.RawMessage) (json.RawMessage, error) {
+	entries := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	fields := map[string]protoField{}
+	for _, field := range protoFields(t) {
+		fields[field.name] = field
+		fields[jsonName(field.name)] = field
+	}
+	message := map[string]json.RawMessage{}
+	for key, entry := range entries {
+		field, ok := fields[key]
+		if !ok {
+			// an unknown field is skipped like encoding/json does
+			continue
+		}
+		normalized, err := normalizeJSON(t.FieldByIndex(field.index).Type, entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		message[field.name] = normalized
+	}
+	return json.Marshal(message)
+}
+
+// normalizeScalar unquotes a quoted number, the other values are decoded as they are
+func normalizeScalar(kind reflect.Kind, raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || raw[0] != '"' {
+		return raw, nil
+	}
+	var err error
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var quoted string
+		if err = json.Unmarshal(raw, &quoted); err == nil {
+			_, err = strconv.ParseInt(quoted, 10, 64)
+			raw = json.RawMessage(quoted)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var quoted string
+		if err = json.Unmarshal(raw, &quoted); err == nil {
+			_, err = strconv.ParseUint(quoted, 10, 64)
+			raw = json.RawMessage(quoted)
+		}
+	case reflect.Float32, reflect.Float64:
+		var quoted string
+		if err = json.Unmarshal(raw, &quoted); err == nil {
+			_, err = strconv.ParseFloat(quoted, 64)
+			raw = json.RawMessage(quoted)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", raw)
+	}
+	return raw, nil
+}
+
+// jsonName is the lowerCamel json_name protoc derives from a field name, to_user_id is toUserId
+func jsonName(name string) string {
+	builder := strings.Builder{}
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			builder.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			builder.WriteRune(r)
+			upper = false
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,89 @@
+package wire
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/slowlog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	MIMEApplicationMsgpack  = "application/msgpack"
+	MIMEApplicationProtobuf = "application/x-protobuf"
+)
+
+// Negotiate returns the response content type the client asked for in its Accept header
+func Negotiate(c echo.Context) string {
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0])
+		switch mediaType {
+		case MIMEApplicationMsgpack, "application/x-msgpack":
+			return MIMEApplicationMsgpack
+		case MIMEApplicationProtobuf, "application/protobuf":
+			return MIMEApplicationProtobuf
+		case echo.MIMEApplicationJSON:
+			return echo.MIMEApplicationJSON
+		}
+	}
+	return echo.MIMEApplicationJSON
+}
+
+// Render writes the response envelope m in the negotiated wire format,
+// m holds the code, message, data, request_id and pagination keys of a JSON response
+func Render(c echo.Context, status int, m echo.Map) error {
+	defer slowlog.Observe(c.Request().Context(), "serialize", time.Now())
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	switch Negotiate(c) {
+	case MIMEApplicationMsgpack:
+		body, err := MarshalMsgpack(m)
+		if err != nil {
+			return err
+		}
+		return c.Blob(status, MIMEApplicationMsgpack, body)
+	case MIMEApplicationProtobuf:
+		body, err := marshalEnvelope(m)
+		if err != nil {
+			return err
+		}
+		return c.Blob(status, MIMEApplicationProtobuf, body)
+	}
+	return c.JSON(status, m)
+}
+
+// marshalEnvelope encodes m as the Response message of the generated descriptor
+func marshalEnvelope(m echo.Map) ([]byte, error) {
+	var b []byte
+	if code := reflect.ValueOf(m["code"]); code.IsValid() && code.CanInt() && code.Int() != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(code.Int()))
+	}
+	if message, ok := m["message"].(string); ok && message != "" {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, message)
+	}
+	if data, ok := m["data"]; ok && data != nil {
+		value, err := MarshalAny(data)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, value)
+	}
+	if requestID, ok := m["request_id"].(string); ok && requestID != "" {
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendString(b, requestID)
+	}
+	if pagination, ok := m["pagination"]; ok && pagination != nil {
+		value, err := MarshalAny(pagination)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,284 @@
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// UnmarshalProto decodes the protobuf message b into the struct v points to, in the layout
+// MarshalProto encodes it, the unknown fields are skipped
+func UnmarshalProto(b []byte, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("wire: UnmarshalProto needs a pointer to a struct")
+	}
+	return consumeMessage(b, value.Elem())
+}
+
+func consumeMessage(b []byte, v reflect.Value) error {
+	fields := map[protowire.Number]protoField{}
+	for _, field := range protoFields(v.Type()) {
+		fields[field.number] = field
+	}
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		field, ok := fields[number]
+		if !ok {
+			if n = protowire.ConsumeFieldValue(number, wireType, b); n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		fieldValue := fieldByIndexAlloc(v, field.index)
+		n, err := consumeField(b, wireType, fieldValue)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.name, err)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// fieldByIndexAlloc is reflect.Value.FieldByIndex allocating the nil embedded pointers
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// consumeField decodes the value of a field of type wireType into v, a repeated field
+// appends it, and returns the bytes read
+func consumeField(b []byte, wireType protowire.Type, v reflect.Value) (int, error) {
+	if isValue(v.Type()) {
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		return n, unmarshalValue(raw, v)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		tm, err := consumeTimestamp(raw)
+		if err != nil {
+			return 0, err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return n, nil
+	case v.Kind() == reflect.Struct:
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		return n, consumeMessage(raw, v)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetBytes(append([]byte{}, raw...))
+		return n, nil
+	case v.Kind() == reflect.Slice:
+		return consumeRepeated(b, wireType, v)
+	case v.Kind() == reflect.Map:
+		return consumeMapEntry(b, v)
+	}
+	return consumeScalar(b, wireType, v)
+}
+
+func consumeRepeated(b []byte, wireType protowire.Type, v reflect.Value) (int, error) {
+	elemType := v.Type().Elem()
+	elemKind := elemType.Kind()
+	// a packed run of scalars
+	if wireType == protowire.BytesType && isScalar(elemKind) && elemKind != reflect.String {
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		scalarType := protowire.VarintType
+		switch elemKind {
+		case reflect.Float32:
+			scalarType = protowire.Fixed32Type
+		case reflect.Float64:
+			scalarType = protowire.Fixed64Type
+		}
+		for len(raw) > 0 {
+			elem := reflect.New(elemType).Elem()
+			m, err := consumeScalar(raw, scalarType, elem)
+			if err != nil {
+				return 0, err
+			}
+			v.Set(reflect.Append(v, elem))
+			raw = raw[m:]
+		}
+		return n, nil
+	}
+	elem := reflect.New(elemType).Elem()
+	n, err := consumeField(b, wireType, elem)
+	if err != nil {
+		return 0, err
+	}
+	v.Set(reflect.Append(v, elem))
+	return n, nil
+}
+
+func consumeMapEntry(b []byte, v reflect.Value) (int, error) {
+	raw, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	key := reflect.New(v.Type().Key()).Elem()
+	value := reflect.New(v.Type().Elem()).Elem()
+	for len(raw) > 0 {
+		number, wireType, m := protowire.ConsumeTag(raw)
+		if m < 0 {
+			return 0, protowire.ParseError(m)
+		}
+		raw = raw[m:]
+		target := value
+		switch number {
+		case 1:
+			target = key
+		case 2:
+		default:
+			if m = protowire.ConsumeFieldValue(number, wireType, raw); m < 0 {
+				return 0, protowire.ParseError(m)
+			}
+			raw = raw[m:]
+			continue
+		}
+		m, err := consumeField(raw, wireType, target)
+		if err != nil {
+			return 0, err
+		}
+		raw = raw[m:]
+	}
+	v.SetMapIndex(key, value)
+	return n, nil
+}
+
+func consumeScalar(b []byte, wireType protowire.Type, v reflect.Value) (int, error) {
+	switch wireType {
+	case protowire.BytesType:
+		if v.Kind() != reflect.String {
+			return 0, fmt.Errorf("bytes do not decode to %s", v.Kind())
+		}
+		s, n := protowire.ConsumeString(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetString(s)
+		return n, nil
+	case protowire.Fixed32Type:
+		x, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		if v.Kind() != reflect.Float32 {
+			return 0, fmt.Errorf("fixed32 does not decode to %s", v.Kind())
+		}
+		v.SetFloat(float64(math.Float32frombits(x)))
+		return n, nil
+	case protowire.Fixed64Type:
+		x, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		if v.Kind() != reflect.Float64 {
+			return 0, fmt.Errorf("fixed64 does not decode to %s", v.Kind())
+		}
+		v.SetFloat(math.Float64frombits(x))
+		return n, nil
+	case protowire.VarintType:
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(protowire.DecodeBool(x))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(x))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.SetUint(x)
+		default:
+			return 0, fmt.Errorf("varint does not decode to %s", v.Kind())
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("unsupported wire type %d", wireType)
+}
+
+func consumeTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return time.Time{}, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if wireType != protowire.VarintType || (number != 1 && number != 2) {
+			if n = protowire.ConsumeFieldValue(number, wireType, b); n < 0 {
+				return time.Time{}, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return time.Time{}, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if number == 1 {
+			seconds = int64(x)
+		} else {
+			nanos = int64(int32(x))
+		}
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// unmarshalValue decodes a google.protobuf.Value into v through its JSON encoding
+func unmarshalValue(b []byte, v reflect.Value) error {
+	value := &structpb.Value{}
+	if err := proto.Unmarshal(b, value); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(value.AsInterface())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v.Addr().Interface())
+}
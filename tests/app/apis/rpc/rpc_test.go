@@ -0,0 +1,31 @@
+//go:build tests
+// +build tests
+
+package rpc
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mises-id/sns-apigateway/app/apis/rpc"
+	"github.com/stretchr/testify/suite"
+)
+
+type RPCSuite struct {
+	suite.Suite
+}
+
+// TestProtoUpToDate fails when a response struct changed the descriptor, a field moved or removed
+// renumbers the fields after it, pin them with proto tags, or regenerate it with go run ./cmd/protogen
+func (suite *RPCSuite) TestProtoUpToDate() {
+	builder := &strings.Builder{}
+	suite.Require().NoError(rpc.WriteProto(builder))
+	committed, err := os.ReadFile("../../../../proto/gateway/v1/gateway.proto")
+	suite.Require().NoError(err)
+	suite.Equal(string(committed), builder.String())
+}
+
+func TestRPCSuite(t *testing.T) {
+	suite.Run(t, &RPCSuite{})
+}
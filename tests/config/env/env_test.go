@@ -0,0 +1,180 @@
+//go:build tests
+// +build tests
+
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mises-id/sns-apigateway/config/env"
+	"github.com/stretchr/testify/suite"
+)
+
+type EnvSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *EnvSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	os.Unsetenv("PORT")
+	os.Unsetenv("LOG_LEVEL")
+}
+
+func (suite *EnvSuite) writeFile(name, content string) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (suite *EnvSuite) TestLayers() {
+	file := suite.writeFile("config.yaml", "port: 9000\nlog_level: debug\nallow_origins:\n  - https://a.mises.site\n  - https://b.mises.site\n")
+	os.Setenv("LOG_LEVEL", "warn")
+	defer os.Unsetenv("LOG_LEVEL")
+
+	cfg, err := env.Load(env.LoadOptions{File: file, Overrides: map[string]string{"PORT": "9001"}})
+	suite.NoError(err)
+	suite.Equal(9001, cfg.Port)
+	suite.Equal("warn", cfg.LogLevel)
+	suite.Equal("https://a.mises.site,https://b.mises.site", cfg.AllowOrigins)
+	suite.Equal(10000, cfg.CacheSize)
+}
+
+func (suite *EnvSuite) TestTOML() {
+	file := suite.writeFile("config.toml", "port = 9002\ntoken_duration = \"1h\"\n")
+	cfg, err := env.Load(env.LoadOptions{File: file})
+	suite.NoError(err)
+	suite.Equal(9002, cfg.Port)
+	suite.Equal("1h0m0s", cfg.TokenDuration.String())
+}
+
+func (suite *EnvSuite) TestAggregatedErrors() {
+	file := suite.writeFile("config.yaml", "port: 0\nlog_level: loud\ncache_provider: disk\nmises_nodes: e1.mises.site\ncache_size: many\ntypo: 1\n")
+	_, err := env.Load(env.LoadOptions{File: file, Overrides: map[string]string{"NOPE": "1"}})
+	suite.Require().Error(err)
+	errs, ok := err.(env.Errors)
+	suite.Require().True(ok)
+	suite.Len(errs, 7)
+	suite.Contains(err.Error(), "PORT: value 0 does not satisfy min=1")
+	suite.Contains(err.Error(), "LOG_LEVEL: value loud does not satisfy loglevel")
+	suite.Contains(err.Error(), "CACHE_PROVIDER: value disk does not satisfy oneof=memory redis")
+	suite.Contains(err.Error(), "MISES_NODES: value e1.mises.site does not satisfy urls")
+	suite.Contains(err.Error(), "CACHE_SIZE: invalid value \"many\"")
+	suite.Contains(err.Error(), "typo: unknown key")
+	suite.Contains(err.Error(), "NOPE: unknown setting")
+}
+
+func (suite *EnvSuite) TestServerSettings() {
+	cfg, err := env.Load(env.LoadOptions{Overrides: map[string]string{"UNIX_SOCKET": "run/gateway.sock", "ROOT_PATH": "/srv"}})
+	suite.NoError(err)
+	suite.Equal("/srv/run/gateway.sock", cfg.Path(cfg.UnixSocket))
+	suite.Equal("5m0s", cfg.WriteTimeout.String())
+
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"TLS_CERT_FILE": "cert.pem", "H2C": "true", "METRICS_PORT": "8080"}})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "TLS_KEY_FILE")
+	suite.Contains(err.Error(), "H2C")
+	suite.Contains(err.Error(), "METRICS_PORT")
+}
+
+func (suite *EnvSuite) TestAdminToken() {
+	_, err := env.Load(env.LoadOptions{Overrides: map[string]string{"ADMIN_PORT": "8370"}})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "ADMIN_TOKEN")
+
+	// unset while the admin server is off, even in production
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"APP_ENV": "production", "JWT_SECRET": strings.Repeat("k", 32)}})
+	suite.NoError(err)
+}
+
+func (suite *EnvSuite) TestReload() {
+	file := suite.writeFile("config.yaml", "port: 9003\nlog_level: info\n")
+	suite.Require().NoError(env.Setup(env.LoadOptions{File: file}))
+	reloaded := make(chan *env.Env, 1)
+	env.OnReload(func(cfg *env.Env) {
+		reloaded <- cfg
+	})
+
+	suite.writeFile("config.yaml", "port: 9004\nlog_level: debug\n")
+	suite.NoError(env.Reload())
+	cfg := <-reloaded
+	suite.Equal("debug", cfg.LogLevel)
+	// the port needs a restart
+	suite.Equal(9003, cfg.Port)
+	suite.Equal(cfg, env.Current())
+	suite.Equal("info", env.Envs.LogLevel)
+
+	// an invalid file is rejected and the live config is kept
+	suite.writeFile("config.yaml", "log_level: loud\n")
+	suite.Error(env.Reload())
+	suite.Equal("debug", env.Current().LogLevel)
+}
+
+func (suite *EnvSuite) TestSecretFile() {
+	secretFile := suite.writeFile("jwt", "file-secret-0123456789-0123456789\n")
+	cfg, err := env.Load(env.LoadOptions{Overrides: map[string]string{"JWT_SECRET_FILE": secretFile}})
+	suite.NoError(err)
+	suite.Equal("file-secret-0123456789-0123456789", cfg.JWTSecret)
+
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"JWT_SECRET_FILE": secretFile, "JWT_SECRET": "x"}})
+	suite.ErrorContains(err, "JWT_SECRET: set only one of JWT_SECRET and JWT_SECRET_FILE in flag")
+}
+
+func (suite *EnvSuite) TestSecretProvider() {
+	suite.writeFile("jwt", "dir-secret-0123456789-0123456789")
+	env.RegisterSecretProvider("static", func(cfg *env.Env) (env.SecretProvider, error) {
+		return staticProvider{"apigateway/jwt": "static-secret-0123456789-0123456789"}, nil
+	})
+
+	cfg, err := env.Load(env.LoadOptions{Overrides: map[string]string{"SECRET_DIR": suite.dir, "JWT_SECRET": "secret:jwt"}})
+	suite.NoError(err)
+	suite.Equal("dir-secret-0123456789-0123456789", cfg.JWTSecret)
+
+	cfg, err = env.Load(env.LoadOptions{Overrides: map[string]string{"SECRET_PROVIDER": "static", "JWT_SECRET": "secret:apigateway/jwt"}})
+	suite.NoError(err)
+	suite.Equal("static-secret-0123456789-0123456789", cfg.JWTSecret)
+
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"SECRET_PROVIDER": "vault", "JWT_SECRET": "secret:jwt"}})
+	suite.ErrorContains(err, "unknown SECRET_PROVIDER \"vault\"")
+}
+
+func (suite *EnvSuite) TestWeakSecretInProduction() {
+	_, err := env.Load(env.LoadOptions{Overrides: map[string]string{"APP_ENV": "production"}})
+	suite.ErrorContains(err, "JWT_SECRET: the default secret is not allowed in production")
+
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"APP_ENV": "production", "JWT_SECRET": "changeme"}})
+	suite.ErrorContains(err, "JWT_SECRET: weak secret")
+
+	_, err = env.Load(env.LoadOptions{Overrides: map[string]string{"APP_ENV": "production", "JWT_SECRET": "0123456789-0123456789-0123456789"}})
+	suite.NoError(err)
+}
+
+func (suite *EnvSuite) TestRedact() {
+	cfg, err := env.Load(env.LoadOptions{Overrides: map[string]string{"JWT_SECRET": "plain-secret-value", "REDIS_URI": "redis://:pass@redis:6379/0"}})
+	suite.Require().NoError(err)
+	raw, err := json.Marshal(cfg)
+	suite.NoError(err)
+	for _, dump := range []string{string(raw), fmt.Sprint(cfg), fmt.Sprintf("%+v", *cfg), fmt.Sprintf("%#v", cfg)} {
+		suite.NotContains(dump, "plain-secret-value")
+		suite.NotContains(dump, "pass@")
+		suite.Contains(dump, env.Redacted)
+	}
+	suite.Equal(env.Redacted, cfg.Redact()["JWT_SECRET"])
+	suite.Equal("24h0m0s", cfg.Redact()["TOKEN_DURATION"])
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Secret(ctx context.Context, ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestEnvSuite(t *testing.T) {
+	suite.Run(t, &EnvSuite{})
+}
@@ -0,0 +1,97 @@
+//go:build tests
+// +build tests
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/config/route"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+	"github.com/mises-id/sns-apigateway/lib/sdkgen"
+	"github.com/stretchr/testify/suite"
+)
+
+// OpenAPISuite keeps the openapi document from drifting from the routes, a route added
+// without its operation, or an operation left after its route was removed, fails it
+type OpenAPISuite struct {
+	suite.Suite
+	e *echo.Echo
+}
+
+func (suite *OpenAPISuite) SetupSuite() {
+	suite.e = echo.New()
+	route.SetRoutes(suite.e)
+}
+
+func (suite *OpenAPISuite) TestDocumentMatchesRoutes() {
+	suite.NoError(route.CheckOpenAPI())
+}
+
+func (suite *OpenAPISuite) TestEveryRouteDocumented() {
+	doc := route.OpenAPI()
+	template := regexp.MustCompile(`:([a-z_]+)`)
+	for _, r := range route.Routes() {
+		item, ok := doc.Paths[template.ReplaceAllString(r.Path, "{$1}")]
+		suite.Require().True(ok, r.Path)
+		op := (*item)[strings.ToLower(r.Method)]
+		suite.Require().NotNil(op, "%s %s", r.Method, r.Path)
+		suite.Equal(r.Handler, op.Handler)
+		suite.Equal(r.RatePolicy, op.RateLimit)
+	}
+}
+
+func (suite *OpenAPISuite) TestPolicies() {
+	doc := route.OpenAPI()
+	me := (*doc.Paths["/api/v1/user/me"])["get"]
+	suite.Require().NotNil(me)
+	suite.Equal([]openapi.SecurityRequirement{{openapi.BearerAuth: {}}}, me.Security)
+	suite.Contains(me.Responses, "401")
+
+	quote := (*doc.Paths["/api/v1/swap/quote"])["get"]
+	suite.Require().NotNil(quote)
+	suite.Len(quote.RateLimit, 2)
+	suite.Contains(quote.Responses, "429")
+	suite.Equal(openapi.SchemaRefPrefix+"SwapQuoteResponse", quote.Responses["200"].Content["application/json"].Schema.Properties["data"].Ref)
+
+	user := (*doc.Paths["/api/v1/user/{uid}"])["get"]
+	suite.Require().NotNil(user)
+	suite.Equal("integer", user.Parameters[0].Schema.Type)
+	suite.Len(user.Security, 2, "the auth is optional")
+
+	estimate := (*doc.Paths["/api/v1/ad_mining/estimate_bonus"])["get"]
+	suite.Require().NotNil(estimate)
+	suite.Contains(estimate.Responses, "404", "a disabled flag hides the route")
+}
+
+func (suite *OpenAPISuite) TestServed() {
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route.OpenAPIPath, nil))
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	doc := &openapi.Document{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), doc))
+	suite.Equal(openapi.Version, doc.OpenAPI)
+	suite.Contains(doc.Paths, "/api/v1/swap/trade")
+
+	rec = httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Contains(rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML)
+}
+
+// TestSDKUpToDate fails when the api changed without the clients of sdk/ being regenerated
+func (suite *OpenAPISuite) TestSDKUpToDate() {
+	files, err := sdkgen.Generate(route.OpenAPI())
+	suite.Require().NoError(err)
+	suite.NoError(sdkgen.Check("../../../sdk", files), "regenerate them with /bin/mises sdk")
+}
+
+func TestOpenAPISuite(t *testing.T) {
+	suite.Run(t, &OpenAPISuite{})
+}
@@ -0,0 +1,180 @@
+//go:build tests
+// +build tests
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/audit"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *AuditSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *AuditSuite) path() string {
+	return filepath.Join(suite.dir, "audit", "audit.log")
+}
+
+func (suite *AuditSuite) writeRecords(logger *audit.Logger, n int) {
+	for i := 0; i < n; i++ {
+		_, err := logger.Write(audit.Record{Action: "blacklist.create", Actor: "uid:1", Target: "uid:2", Outcome: audit.OutcomeOK})
+		suite.Require().NoError(err)
+	}
+}
+
+func (suite *AuditSuite) verify(names ...string) (audit.Verification, error) {
+	verifier := &audit.Verifier{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		suite.Require().NoError(err)
+		if err := verifier.Verify(name, bytes.NewReader(data)); err != nil {
+			return verifier.Result(), err
+		}
+	}
+	return verifier.Result(), nil
+}
+
+func (suite *AuditSuite) TestChainResumesAndRotates() {
+	sink, err := audit.NewFileSink(suite.path(), 600, 2)
+	suite.Require().NoError(err)
+	suite.writeRecords(audit.New(sink, nil), 2)
+	suite.Require().NoError(sink.Close())
+
+	// a restart continues the chain of the file
+	sink, err = audit.NewFileSink(suite.path(), 600, 2)
+	suite.Require().NoError(err)
+	last, err := sink.Last()
+	suite.Require().NoError(err)
+	suite.Require().NotNil(last)
+	suite.Equal(uint64(2), last.Seq)
+	suite.writeRecords(audit.New(sink, last), 3)
+	suite.Require().NoError(sink.Close())
+
+	_, err = os.Stat(suite.path() + ".1")
+	suite.Require().NoError(err, "the file was rotated")
+	names := []string{suite.path() + ".2", suite.path() + ".1", suite.path()}
+	if _, err := os.Stat(names[0]); os.IsNotExist(err) {
+		names = names[1:]
+	}
+	result, err := suite.verify(names...)
+	suite.Require().NoError(err)
+	suite.Equal(5, result.Records)
+	suite.Equal(uint64(5), result.Last.Seq)
+	suite.True(result.Anchored)
+
+	// without the older files the chain is valid but not anchored
+	result, err = suite.verify(suite.path())
+	suite.Require().NoError(err)
+	suite.False(result.Anchored)
+}
+
+func (suite *AuditSuite) TestTamperingDetected() {
+	sink, err := audit.NewFileSink(suite.path(), 0, 0)
+	suite.Require().NoError(err)
+	suite.writeRecords(audit.New(sink, nil), 3)
+	suite.Require().NoError(sink.Close())
+	data, err := os.ReadFile(suite.path())
+	suite.Require().NoError(err)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	modified := strings.Replace(string(data), `"target":"uid:2"`, `"target":"uid:3"`, 1)
+	suite.Require().NoError(os.WriteFile(suite.path(), []byte(modified), 0o640))
+	_, err = suite.verify(suite.path())
+	suite.ErrorContains(err, ":1: seq 1: hash")
+
+	removed := lines[0] + lines[2]
+	suite.Require().NoError(os.WriteFile(suite.path(), []byte(removed), 0o640))
+	_, err = suite.verify(suite.path())
+	suite.ErrorContains(err, ":2: seq 3: previous hash")
+}
+
+type failingSink struct {
+	fail bool
+}
+
+func (s *failingSink) Write(line []byte) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+func (suite *AuditSuite) TestFailedWriteKeepsChain() {
+	sink := &failingSink{}
+	logger := audit.New(sink, nil)
+	first, err := logger.Write(audit.Record{Action: "user.sign_in"})
+	suite.Require().NoError(err)
+	sink.fail = true
+	_, err = logger.Write(audit.Record{Action: "user.sign_in"})
+	suite.Error(err)
+	sink.fail = false
+	second, err := logger.Write(audit.Record{Action: "user.sign_in"})
+	suite.Require().NoError(err)
+	suite.Equal(first.Seq+1, second.Seq)
+	suite.Equal(first.Hash, second.PrevHash)
+}
+
+func (suite *AuditSuite) TestFailedRotationKeepsFile() {
+	sink, err := audit.NewFileSink(suite.path(), 300, 1)
+	suite.Require().NoError(err)
+	logger := audit.New(sink, nil)
+	suite.writeRecords(logger, 1)
+
+	// a directory in place of the backup fails the rotation
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.path()+".1", "busy"), 0o750))
+	_, err = logger.Write(audit.Record{Action: "user.sign_in"})
+	suite.Error(err)
+
+	suite.Require().NoError(os.RemoveAll(suite.path() + ".1"))
+	suite.writeRecords(logger, 1)
+	suite.Require().NoError(sink.Close())
+	result, err := suite.verify(suite.path()+".1", suite.path())
+	suite.Require().NoError(err)
+	suite.Equal(2, result.Records)
+	suite.True(result.Anchored)
+}
+
+func (suite *AuditSuite) TestLogFromRequest() {
+	out := &bytes.Buffer{}
+	audit.SetDefault(audit.New(audit.WriterSink{Writer: out}, nil))
+	defer audit.SetDefault(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/blacklist", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	req.Header.Set("mises-device-id", "device-1")
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	audit.Log(c, "blacklist.create", "uid:1", "uid:2", errors.New("not found"))
+
+	line := out.String()
+	suite.Contains(line, `"ip":"10.0.0.1"`)
+	suite.Contains(line, `"device_id":"device-1"`)
+	suite.Contains(line, `"request_id":"req-1"`)
+	suite.Contains(line, `"outcome":"failed","error":"not found"`)
+	result := &audit.Verifier{}
+	suite.NoError(result.Verify("stdout", strings.NewReader(line)))
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, &AuditSuite{})
+}
@@ -0,0 +1,197 @@
+//go:build tests
+// +build tests
+
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/mises-id/sns-apigateway/lib/batch"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchSuite struct {
+	suite.Suite
+	e        *echo.Echo
+	inFlight int32
+	peak     int32
+	// overlapped tells if a mutation ran while another request was served
+	overlapped int32
+}
+
+func (suite *BatchSuite) SetupTest() {
+	suite.inFlight, suite.peak, suite.overlapped = 0, 0, 0
+	suite.e = echo.New()
+	suite.e.Use(middleware.RequestID())
+	config := batch.Config{
+		Prefix:           "/api/v1/",
+		Concurrency:      2,
+		Mutations:        map[string]bool{"POST /api/v1/item/:id": true},
+		Streaming:        map[string]bool{"GET /api/v1/item/:id/export": true},
+		MaxResponseBytes: 1024,
+	}
+	suite.e.POST("/api/v1/batch", func(c echo.Context) error {
+		requests := []batch.Request{}
+		if err := c.Bind(&requests); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, batch.Run(c, requests, config))
+	})
+	suite.e.GET("/api/v1/item/:id", func(c echo.Context) error {
+		defer suite.track()()
+		time.Sleep(20 * time.Millisecond)
+		return c.JSON(http.StatusOK, echo.Map{
+			"id":         c.Param("id"),
+			"limit":      c.QueryParam("limit"),
+			"auth":       c.Request().Header.Get(echo.HeaderAuthorization),
+			"request_id": c.Request().Header.Get(echo.HeaderXRequestID),
+		})
+	})
+	suite.e.POST("/api/v1/item/:id", func(c echo.Context) error {
+		if atomic.LoadInt32(&suite.inFlight) > 0 {
+			atomic.StoreInt32(&suite.overlapped, 1)
+		}
+		defer suite.track()()
+		time.Sleep(20 * time.Millisecond)
+		body := echo.Map{}
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, body)
+	})
+	suite.e.DELETE("/api/v1/item/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	suite.e.GET("/api/v1/text", func(c echo.Context) error {
+		return c.String(http.StatusOK, "plain")
+	})
+	suite.e.GET("/api/v1/limited", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, middleware.RateLimiter(middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate: 0.001, Burst: 1, ExpiresIn: time.Minute,
+	})))
+	suite.e.GET("/api/v1/item/:id/export", func(c echo.Context) error {
+		return c.String(http.StatusOK, "streamed")
+	})
+	suite.e.GET("/api/v1/large", func(c echo.Context) error {
+		return c.String(http.StatusOK, strings.Repeat("x", 2048))
+	})
+	suite.e.GET("/api/v1/failing", func(c echo.Context) error {
+		return errors.New("backend down")
+	})
+	suite.e.GET("/admin/item", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+}
+
+// track counts the request until the returned func is called
+func (suite *BatchSuite) track() func() {
+	n := atomic.AddInt32(&suite.inFlight, 1)
+	for {
+		peak := atomic.LoadInt32(&suite.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&suite.peak, peak, n) {
+			break
+		}
+	}
+	return func() { atomic.AddInt32(&suite.inFlight, -1) }
+}
+
+func (suite *BatchSuite) run(requests string) []batch.Response {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(requests))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	suite.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+	responses := []batch.Response{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &responses))
+	return responses
+}
+
+func (suite *BatchSuite) TestSubRequests() {
+	responses := suite.run(`[
+		{"method": "GET", "path": "/api/v1/item/1?limit=5"},
+		{"method": "GET", "path": "/api/v1/item/2", "query": {"limit": "10"}},
+		{"method": "GET", "path": "/api/v1/text"},
+		{"method": "GET", "path": "/api/v1/unknown"}
+	]`)
+	suite.Require().Len(responses, 4)
+	item := map[string]string{}
+	suite.Require().NoError(json.Unmarshal(responses[0].Body, &item))
+	suite.Equal(http.StatusOK, responses[0].Status)
+	suite.Equal("1", item["id"])
+	suite.Equal("5", item["limit"])
+	// the sub requests are sent with the auth of the batch, and logged with its request id
+	suite.Equal("Bearer token", item["auth"])
+	suite.Regexp(`^\w+-0$`, item["request_id"])
+	suite.Require().NoError(json.Unmarshal(responses[1].Body, &item))
+	suite.Equal("10", item["limit"])
+	suite.JSONEq(`"plain"`, string(responses[2].Body))
+	suite.Equal(http.StatusNotFound, responses[3].Status)
+	// the reads run concurrently, within the concurrency
+	suite.Equal(int32(2), suite.peak)
+}
+
+func (suite *BatchSuite) TestMutations() {
+	responses := suite.run(`[
+		{"method": "GET", "path": "/api/v1/item/1"},
+		{"method": "GET", "path": "/api/v1/item/2"},
+		{"method": "POST", "path": "/api/v1/item/3", "body": {"name": "three"}},
+		{"method": "GET", "path": "/api/v1/item/4"},
+		{"method": "DELETE", "path": "/api/v1/item/5"}
+	]`)
+	suite.Equal(http.StatusCreated, responses[2].Status)
+	suite.JSONEq(`{"id": "3", "name": "three"}`, string(responses[2].Body))
+	suite.Zero(atomic.LoadInt32(&suite.overlapped))
+
+	suite.Equal(http.StatusForbidden, responses[4].Status)
+	suite.JSONEq(`{"code": 403000, "message": "DELETE /api/v1/item/5 may not be sent in a batch"}`, string(responses[4].Body))
+}
+
+func (suite *BatchSuite) TestRejected() {
+	responses := suite.run(`[
+		{"method": "GET", "path": "/admin/item"},
+		{"method": "GET", "path": "/api/v1/../../admin/item"},
+		{"method": "POST", "path": "/api/v1/batch", "body": []},
+		{"method": "GET", "path": "http://example.com/api/v1/item/1"}
+	]`)
+	for _, resp := range responses[:3] {
+		suite.Equal(codes.ErrForbidden.HTTPStatus, resp.Status)
+	}
+	suite.Contains(string(responses[2].Body), "a batch may not contain a batch")
+	suite.Equal(codes.ErrInvalidArgument.HTTPStatus, responses[3].Status)
+}
+
+func (suite *BatchSuite) TestStreamingAndLargeResponses() {
+	responses := suite.run(`[
+		{"method": "GET", "path": "/api/v1/item/1/export"},
+		{"method": "GET", "path": "/api/v1/large"}
+	]`)
+	suite.Equal(codes.ErrForbidden.HTTPStatus, responses[0].Status)
+	suite.Contains(string(responses[0].Body), "streams its response")
+	suite.Equal(codes.ErrRequestEntityTooLarge.HTTPStatus, responses[1].Status)
+	suite.Contains(string(responses[1].Body), "1024 bytes")
+}
+
+func (suite *BatchSuite) TestRateLimitPerSubRequest() {
+	responses := suite.run(`[
+		{"method": "GET", "path": "/api/v1/limited"},
+		{"method": "GET", "path": "/api/v1/limited"}
+	]`)
+	statuses := []int{responses[0].Status, responses[1].Status}
+	suite.ElementsMatch([]int{http.StatusOK, http.StatusTooManyRequests}, statuses)
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, &BatchSuite{})
+}
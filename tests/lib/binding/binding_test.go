@@ -0,0 +1,209 @@
+//go:build tests
+// +build tests
+
+package binding
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/binding"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/validation"
+	"github.com/stretchr/testify/suite"
+)
+
+type PageParams struct {
+	PageNum  int64 `json:"page_num" query:"page_num"`
+	PageSize int64 `json:"page_size" query:"page_size"`
+}
+
+type quoteRequest struct {
+	PageParams
+	ChainID          uint64 `json:"chain_id" query:"chain_id"`
+	FromTokenAddress string `json:"from_token_address" query:"from_token_address"`
+	ToTokenAddress   string `json:"to_token_address" query:"to_token_address"`
+}
+
+type profile struct {
+	Email string `json:"email"`
+}
+
+type updateRequest struct {
+	By      string   `json:"by"`
+	Profile *profile `json:"profile"`
+}
+
+type tradeRequest struct {
+	Slippage float64 `json:"slippage" query:"slippage" validate:"lte=50"`
+}
+
+type uploadInput struct {
+	FileType string `form:"file_type"`
+}
+
+type errorBody struct {
+	Code    int                `json:"code"`
+	Message string             `json:"message"`
+	Fields  []codes.FieldError `json:"fields"`
+}
+
+type BindingSuite struct {
+	suite.Suite
+	e *echo.Echo
+}
+
+func bind(params func() interface{}) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := params()
+		if err := c.Bind(p); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, p)
+	}
+}
+
+func (suite *BindingSuite) SetupTest() {
+	suite.e = echo.New()
+	suite.e.Binder = binding.Binder{Binder: suite.e.Binder}
+	suite.e.Validator = validation.New()
+	strict := suite.e.Group("/strict", mw.ErrorResponseMiddleware, binding.Strict(binding.Config{IgnoreQuery: []string{"fields"}}))
+	strict.GET("/quote", bind(func() interface{} { return &quoteRequest{} }))
+	strict.POST("/quote", bind(func() interface{} { return &quoteRequest{} }))
+	strict.PATCH("/user/me", bind(func() interface{} { return &updateRequest{} }))
+	strict.POST("/upload", bind(func() interface{} { return &uploadInput{} }), binding.Strict(binding.Config{
+		ContentTypes: map[string][]string{http.MethodPost: {echo.MIMEMultipartForm, echo.MIMEApplicationForm}},
+	}))
+	suite.e.GET("/loose/quote", bind(func() interface{} { return &quoteRequest{} }), mw.ErrorResponseMiddleware)
+	suite.e.GET("/loose/trade", bind(func() interface{} { return &tradeRequest{} }), mw.ErrorResponseMiddleware)
+}
+
+func (suite *BindingSuite) serve(method, target, contentType, body string) (*httptest.ResponseRecorder, *errorBody) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	resp := &errorBody{}
+	if rec.Code != http.StatusOK {
+		suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), resp))
+	}
+	return rec, resp
+}
+
+func (suite *BindingSuite) TestQuery() {
+	rec, _ := suite.serve(http.MethodGet, "/strict/quote?chain_id=56&page_size=10&fields=chain_id", "", "")
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	suite.JSONEq(`{"chain_id":56,"page_num":0,"page_size":10,"from_token_address":"","to_token_address":""}`, rec.Body.String())
+
+	rec, resp := suite.serve(http.MethodGet, "/strict/quote?chain_id=56&to_tokn_address=0x1", "", "")
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal(codes.InvalidArgumentCode, resp.Code)
+	suite.Equal([]codes.FieldError{{Field: "to_tokn_address", Rule: "unknown", In: "query", Message: "to_tokn_address is not a query field"}}, resp.Fields)
+
+	rec, _ = suite.serve(http.MethodGet, "/loose/quote?chain_id=56&to_tokn_address=0x1", "", "")
+	suite.Equal(http.StatusOK, rec.Code, "the routes without Strict bind as before")
+}
+
+func (suite *BindingSuite) TestBody() {
+	rec, _ := suite.serve(http.MethodPost, "/strict/quote", echo.MIMEApplicationJSON, `{"chain_id":56,"page_size":10}`)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	rec, resp := suite.serve(http.MethodPatch, "/strict/user/me", echo.MIMEApplicationJSON, `{"by":"profile","profile":{"eamil":"a@b.c"}}`)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal([]codes.FieldError{{Field: "eamil", Rule: "unknown", In: "body", Message: "eamil is not a body field"}}, resp.Fields)
+
+	rec, resp = suite.serve(http.MethodPost, "/strict/quote?chain_id=56", echo.MIMEApplicationJSON, `{"page_size":10}`)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal("chain_id is expected in the body, not the query", resp.Fields[0].Message)
+
+	rec, resp = suite.serve(http.MethodPost, "/strict/quote", echo.MIMEApplicationJSON, `{"chain_id":"56"}`)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal([]codes.FieldError{{Field: "chain_id", Rule: "type", In: "body", Message: "chain_id must be a number"}}, resp.Fields)
+
+	rec, resp = suite.serve(http.MethodPost, "/strict/quote", echo.MIMEApplicationJSON, `{"chain_id":`)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Contains(resp.Message, "invalid body")
+}
+
+func (suite *BindingSuite) TestContentType() {
+	rec, resp := suite.serve(http.MethodPost, "/strict/quote", echo.MIMEApplicationForm, "chain_id=56")
+	suite.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	suite.Equal(codes.UnsupportedMediaType, resp.Code)
+	suite.Equal(`POST requests take application/json bodies, not "application/x-www-form-urlencoded"`, resp.Message)
+
+	rec, resp = suite.serve(http.MethodGet, "/strict/quote", echo.MIMEApplicationJSON, `{"chain_id":56}`)
+	suite.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	suite.Equal("GET requests have no body", resp.Message)
+
+	rec, _ = suite.serve(http.MethodPost, "/strict/upload", echo.MIMEApplicationForm, "file_type=image")
+	suite.Equal(http.StatusOK, rec.Code, "the route Strict replaces the group one")
+
+	rec, resp = suite.serve(http.MethodPost, "/strict/upload", echo.MIMEApplicationForm, "file_type=image&filetype=image")
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal("filetype is not a body field", resp.Fields[0].Message)
+}
+
+func (suite *BindingSuite) TestValidates() {
+	rec, resp := suite.serve(http.MethodGet, "/loose/trade?slippage=80", "", "")
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal([]codes.FieldError{{Field: "slippage", Rule: "lte", Message: "slippage must be at most 50"}}, resp.Fields)
+
+	rec, _ = suite.serve(http.MethodGet, "/loose/trade?slippage=1", "", "")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	rec, resp = suite.serve(http.MethodGet, "/loose/trade?slippage=high", "", "")
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Equal("invalid query params", resp.Message, "the errors of the echo binder are answered ErrInvalidParams")
+}
+
+func (suite *BindingSuite) TestBodyLimit() {
+	e := echo.New()
+	e.Binder = binding.Binder{Binder: e.Binder}
+	e.Use(mw.BodyLimit(16, func(c echo.Context) bool { return c.Path() == "/upload" }))
+	g := e.Group("", mw.ErrorResponseMiddleware, binding.Strict(binding.Config{}))
+	g.POST("/quote", bind(func() interface{} { return &quoteRequest{} }))
+	g.POST("/upload", bind(func() interface{} { return &quoteRequest{} }))
+	e.POST("/loose/quote", bind(func() interface{} { return &quoteRequest{} }), mw.ErrorResponseMiddleware)
+
+	body := `{"chain_id":56,"page_size":10}`
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/quote", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	e.ServeHTTP(rec, req)
+	suite.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	suite.Contains(rec.Body.String(), "413000")
+
+	// a body of unknown length is cut while it is read
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/quote", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.ContentLength = -1
+	e.ServeHTTP(rec, req)
+	suite.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+
+	// so is the body of a route without Strict
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/loose/quote", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.ContentLength = -1
+	e.ServeHTTP(rec, req)
+	suite.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	suite.Contains(rec.Body.String(), "413000")
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	e.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code, "a route with its own limit is skipped")
+}
+
+func TestBindingSuite(t *testing.T) {
+	suite.Run(t, &BindingSuite{})
+}
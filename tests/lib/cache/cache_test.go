@@ -0,0 +1,121 @@
+//go:build tests
+// +build tests
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/lib/cache"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *CacheSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *CacheSuite) TestMemoryStoreLRU() {
+	store := cache.NewMemoryStore(2)
+	suite.NoError(store.Set(suite.ctx, "a", []byte("1"), time.Minute))
+	suite.NoError(store.Set(suite.ctx, "b", []byte("2"), time.Minute))
+	_, ok, _ := store.Get(suite.ctx, "a")
+	suite.True(ok)
+	suite.NoError(store.Set(suite.ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = store.Get(suite.ctx, "b")
+	suite.False(ok)
+	value, ok, _ := store.Get(suite.ctx, "a")
+	suite.True(ok)
+	suite.Equal("1", string(value))
+	suite.Equal(2, store.Len())
+
+	suite.NoError(store.Flush(suite.ctx))
+	suite.Equal(0, store.Len())
+}
+
+func (suite *CacheSuite) TestMemoryStoreTTL() {
+	store := cache.NewMemoryStore(10)
+	suite.NoError(store.Set(suite.ctx, "a", []byte("1"), -time.Second))
+	_, ok, _ := store.Get(suite.ctx, "a")
+	suite.False(ok)
+	suite.Equal(0, store.Len())
+}
+
+func (suite *CacheSuite) TestLoaderCollapsesMisses() {
+	loader := cache.NewLoader(cache.NewMemoryStore(10))
+	var calls int32
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := loader.Load(suite.ctx, "quote", time.Minute, false, func() ([]byte, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return []byte("quote"), nil
+			})
+			suite.NoError(err)
+			suite.Equal("quote", string(value))
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	suite.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	value, err := loader.Load(suite.ctx, "quote", time.Minute, false, func() ([]byte, error) {
+		return nil, errors.New("should be cached")
+	})
+	suite.NoError(err)
+	suite.Equal("quote", string(value))
+}
+
+func (suite *CacheSuite) TestLoaderRefreshAndErrors() {
+	loader := cache.NewLoader(cache.NewMemoryStore(10))
+	_, err := loader.Load(suite.ctx, "news", time.Minute, false, func() ([]byte, error) {
+		return nil, errors.New("backend down")
+	})
+	suite.Error(err)
+	_, ok, _ := loader.Store().Get(suite.ctx, "news")
+	suite.False(ok)
+
+	_, err = loader.Load(suite.ctx, "news", time.Minute, false, func() ([]byte, error) {
+		return []byte("v1"), nil
+	})
+	suite.NoError(err)
+	value, err := loader.Load(suite.ctx, "news", time.Minute, true, func() ([]byte, error) {
+		return []byte("v2"), nil
+	})
+	suite.NoError(err)
+	suite.Equal("v2", string(value))
+}
+
+func (suite *CacheSuite) TestScope() {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/news", nil)
+	scope, ok := cache.Scope(req, true)
+	suite.True(ok)
+	suite.Equal(cache.ScopeAnonymous, scope)
+
+	req.Header.Set("Authorization", "Bearer token")
+	_, ok = cache.Scope(req, true)
+	suite.False(ok)
+	scope, ok = cache.Scope(req, false)
+	suite.True(ok)
+	suite.Equal(cache.ScopePublic, scope)
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, &CacheSuite{})
+}
@@ -0,0 +1,89 @@
+//go:build tests
+// +build tests
+
+package certreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/lib/certreload"
+	"github.com/stretchr/testify/suite"
+)
+
+type CertReloadSuite struct {
+	suite.Suite
+	certFile string
+	keyFile  string
+}
+
+func (suite *CertReloadSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	suite.certFile = filepath.Join(dir, "tls.crt")
+	suite.keyFile = filepath.Join(dir, "tls.key")
+}
+
+// writeKeyPair writes a self signed key pair for name
+func (suite *CertReloadSuite) writeKeyPair(name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	suite.Require().NoError(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.WriteFile(suite.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	suite.Require().NoError(os.WriteFile(suite.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func (suite *CertReloadSuite) commonName(r *certreload.Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	suite.Require().NoError(err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	suite.Require().NoError(err)
+	return leaf.Subject.CommonName
+}
+
+func (suite *CertReloadSuite) TestReload() {
+	suite.writeKeyPair("old.mises.site")
+	r, err := certreload.New(suite.certFile, suite.keyFile)
+	suite.Require().NoError(err)
+	suite.Equal("old.mises.site", suite.commonName(r))
+
+	suite.writeKeyPair("new.mises.site")
+	suite.NoError(r.Reload())
+	suite.Equal("new.mises.site", suite.commonName(r))
+}
+
+func (suite *CertReloadSuite) TestKeepsPairOnBadReload() {
+	suite.writeKeyPair("old.mises.site")
+	r, err := certreload.New(suite.certFile, suite.keyFile)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(os.WriteFile(suite.keyFile, []byte("truncated"), 0600))
+	suite.Error(r.Reload())
+	suite.Equal("old.mises.site", suite.commonName(r))
+}
+
+func (suite *CertReloadSuite) TestMissingFiles() {
+	_, err := certreload.New(suite.certFile, suite.keyFile)
+	suite.Error(err)
+}
+
+func TestCertReloadSuite(t *testing.T) {
+	suite.Run(t, &CertReloadSuite{})
+}
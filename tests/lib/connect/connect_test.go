@@ -0,0 +1,254 @@
+//go:build tests
+// +build tests
+
+package connect
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/connect"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	"github.com/stretchr/testify/suite"
+)
+
+type userRequest struct {
+	UID uint64 `json:"uid" param:"uid"`
+}
+
+type userResp struct {
+	UID      uint64 `json:"uid"`
+	Username string `json:"username"`
+}
+
+type followParams struct {
+	ToUserID uint64 `json:"to_user_id" query:"to_user_id"`
+}
+
+type pageParams struct {
+	Limit  int64  `json:"limit" query:"limit"`
+	NextID string `json:"last_id" query:"last_id"`
+}
+
+type timelineParams struct {
+	pageParams
+}
+
+type timelinePage struct {
+	Data       []*userResp `json:"data"`
+	Pagination pageParams  `json:"pagination"`
+}
+
+var users = connect.Service{
+	Name: "test.v1.Users",
+	Methods: []connect.Method{
+		{Name: "GetUser", Route: "GET /api/v1/user/:uid", Request: userRequest{}, Response: userResp{}},
+		{Name: "Follow", Route: "POST /api/v1/user/follow", Request: followParams{}, Response: connect.Empty{}},
+		{Name: "ListTimeline", Route: "GET /api/v1/timeline/me", Request: timelineParams{}, Response: timelinePage{}, Page: true},
+		{Name: "Slow", Route: "GET /api/v1/slow", Request: connect.Empty{}, Response: connect.Empty{}},
+		{Name: "Plain", Route: "GET /api/v1/plain", Request: connect.Empty{}, Response: connect.Empty{}},
+	},
+}
+
+type ConnectSuite struct {
+	suite.Suite
+	e        *echo.Echo
+	followed uint64
+	query    string
+}
+
+func (suite *ConnectSuite) SetupTest() {
+	suite.followed, suite.query = 0, ""
+	suite.e = echo.New()
+	suite.e.POST("/"+users.Name+"/:method", connect.Handler(users))
+	suite.e.GET("/api/v1/user/:uid", func(c echo.Context) error {
+		if c.Param("uid") == "404" {
+			return c.JSON(http.StatusNotFound, codes.ErrNotFound)
+		}
+		uid, _ := strconv.ParseUint(c.Param("uid"), 10, 64)
+		return c.JSON(http.StatusOK, echo.Map{"code": 0, "data": userResp{UID: uid, Username: "alice"}})
+	})
+	suite.e.POST("/api/v1/user/follow", func(c echo.Context) error {
+		params := &followParams{}
+		if err := c.Bind(params); err != nil {
+			return err
+		}
+		suite.followed = params.ToUserID
+		return c.JSON(http.StatusOK, echo.Map{"code": 0, "data": nil})
+	})
+	suite.e.GET("/api/v1/timeline/me", func(c echo.Context) error {
+		suite.query = c.QueryString()
+		return c.JSON(http.StatusOK, echo.Map{
+			"code":       0,
+			"data":       []userResp{{UID: 1, Username: "alice"}},
+			"pagination": echo.Map{"limit": c.QueryParam("limit"), "last_id": "next"},
+		})
+	})
+	suite.e.GET("/api/v1/plain", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	suite.e.GET("/api/v1/slow", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.JSON(http.StatusInternalServerError, codes.ErrInternal)
+	})
+}
+
+func (suite *ConnectSuite) call(method, contentType string, body []byte, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/"+users.Name+"/"+method, bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// frames splits a gRPC-Web body into its frames, the trailer frame is parsed
+func (suite *ConnectSuite) frames(body []byte) ([][]byte, map[string]string) {
+	messages := [][]byte{}
+	trailer := map[string]string{}
+	for len(body) > 0 {
+		suite.Require().True(len(body) >= 5)
+		size := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+size]
+		if body[0]&0x80 == 0 {
+			messages = append(messages, payload)
+		} else {
+			for _, line := range strings.Split(strings.TrimSpace(string(payload)), "\r\n") {
+				name, value, _ := strings.Cut(line, ": ")
+				trailer[name] = value
+			}
+		}
+		body = body[5+size:]
+	}
+	return messages, trailer
+}
+
+func frame(message []byte) []byte {
+	b := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(message)))
+	return append(b, message...)
+}
+
+func (suite *ConnectSuite) TestConnectProto() {
+	body, err := wire.MarshalProto(userRequest{UID: 7})
+	suite.Require().NoError(err)
+	rec := suite.call("GetUser", "application/proto", body)
+	suite.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+	suite.Equal("application/proto", rec.Header().Get(echo.HeaderContentType))
+	user := userResp{}
+	suite.Require().NoError(wire.UnmarshalProto(rec.Body.Bytes(), &user))
+	suite.Equal(userResp{UID: 7, Username: "alice"}, user)
+}
+
+func (suite *ConnectSuite) TestConnectJSON() {
+	rec := suite.call("GetUser", "application/json", []byte(`{"uid": 7}`))
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	suite.JSONEq(`{"uid": 7, "username": "alice"}`, rec.Body.String())
+
+	rec = suite.call("Follow", "application/json", []byte(`{"to_user_id": 9}`))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.JSONEq(`{}`, rec.Body.String())
+	suite.Equal(uint64(9), suite.followed)
+
+	rec = suite.call("ListTimeline", "application/json", []byte(`{"limit": 5}`))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.JSONEq(`{"data": [{"uid": 1, "username": "alice"}], "pagination": {"limit": "5", "last_id": "next"}}`, rec.Body.String())
+
+	// the protojson names and quoted 64 bit integers
+	rec = suite.call("Follow", "application/json", []byte(`{"toUserId":"10"}`))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal(uint64(10), suite.followed)
+	rec = suite.call("Follow", "application/json", []byte(`{"toUserId":"ten"}`))
+	suite.Equal(http.StatusBadRequest, rec.Code)
+
+	// the zero values are sent to the route
+	rec = suite.call("ListTimeline", "application/json", []byte(`{}`))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("last_id=&limit=0", suite.query)
+}
+
+func (suite *ConnectSuite) TestConnectErrors() {
+	rec := suite.call("GetUser", "application/json", []byte(`{"uid": 404}`))
+	suite.Equal(http.StatusNotFound, rec.Code)
+	suite.JSONEq(`{"code": "not_found", "message": "not found"}`, rec.Body.String())
+	suite.Equal("404000", rec.Header().Get("Mises-Code"))
+
+	rec = suite.call("GetUser", "application/json", []byte(`{}`))
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.JSONEq(`{"code": "invalid_argument", "message": "uid is required"}`, rec.Body.String())
+
+	rec = suite.call("Plain", "application/json", []byte(`{}`))
+	suite.Equal(http.StatusInternalServerError, rec.Code)
+	suite.Contains(rec.Body.String(), `"internal"`)
+
+	rec = suite.call("DeleteUser", "application/json", []byte(`{}`))
+	suite.Equal(http.StatusNotImplemented, rec.Code)
+	suite.Contains(rec.Body.String(), `"unimplemented"`)
+
+	rec = suite.call("GetUser", "text/plain", []byte(`{}`))
+	suite.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	suite.Contains(rec.Header().Get("Accept-Post"), "application/grpc-web")
+}
+
+func (suite *ConnectSuite) TestGRPCWeb() {
+	body, err := wire.MarshalProto(userRequest{UID: 7})
+	suite.Require().NoError(err)
+	rec := suite.call("GetUser", "application/grpc-web+proto", frame(body), "X-Grpc-Web", "1")
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	suite.Equal("application/grpc-web+proto", rec.Header().Get(echo.HeaderContentType))
+	messages, trailer := suite.frames(rec.Body.Bytes())
+	suite.Require().Len(messages, 1)
+	user := userResp{}
+	suite.Require().NoError(wire.UnmarshalProto(messages[0], &user))
+	suite.Equal(uint64(7), user.UID)
+	suite.Equal("0", trailer["grpc-status"])
+
+	rec = suite.call("ListTimeline", "application/grpc-web+json", frame([]byte(`{"limit": "5", "lastId": "n1"}`)))
+	suite.Equal("last_id=n1&limit=5", suite.query)
+	messages, _ = suite.frames(rec.Body.Bytes())
+	suite.Require().Len(messages, 1)
+	suite.JSONEq(`{"data": [{"uid": 1, "username": "alice"}], "pagination": {"limit": "5", "last_id": "next"}}`, string(messages[0]))
+}
+
+func (suite *ConnectSuite) TestGRPCWebTextError() {
+	body, err := wire.MarshalProto(userRequest{UID: 404})
+	suite.Require().NoError(err)
+	rec := suite.call("GetUser", "application/grpc-web-text", []byte(base64.StdEncoding.EncodeToString(frame(body))))
+	// the status of a gRPC-Web error is in its trailer
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	raw, err := base64.StdEncoding.DecodeString(rec.Body.String())
+	suite.Require().NoError(err)
+	messages, trailer := suite.frames(raw)
+	suite.Empty(messages)
+	suite.Equal("5", trailer["grpc-status"])
+	suite.Equal("not found", trailer["grpc-message"])
+	suite.Equal("404000", trailer["mises-code"])
+
+	rec = suite.call("GetUser", "application/grpc-web", []byte{0, 0, 0})
+	_, trailer = suite.frames(rec.Body.Bytes())
+	suite.Equal("3", trailer["grpc-status"])
+}
+
+func (suite *ConnectSuite) TestTimeout() {
+	rec := suite.call("Slow", "application/json", []byte(`{}`), "Connect-Timeout-Ms", "20")
+	suite.Equal(http.StatusGatewayTimeout, rec.Code)
+	suite.Contains(rec.Body.String(), `"deadline_exceeded"`)
+
+	rec = suite.call("Slow", "application/grpc-web", frame(nil), "Grpc-Timeout", "20m")
+	_, trailer := suite.frames(rec.Body.Bytes())
+	suite.Equal("4", trailer["grpc-status"])
+}
+
+func TestConnectSuite(t *testing.T) {
+	suite.Run(t, &ConnectSuite{})
+}
@@ -0,0 +1,135 @@
+//go:build tests
+// +build tests
+
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/dataloader"
+	"github.com/stretchr/testify/suite"
+)
+
+type DataloaderSuite struct {
+	suite.Suite
+	mutex   sync.Mutex
+	batches [][]int
+}
+
+func (suite *DataloaderSuite) SetupTest() {
+	suite.batches = nil
+}
+
+// squares answers the square of the positive keys
+func (suite *DataloaderSuite) squares(ctx context.Context, keys []int) (map[int]int, error) {
+	suite.mutex.Lock()
+	batch := append([]int{}, keys...)
+	sort.Ints(batch)
+	suite.batches = append(suite.batches, batch)
+	suite.mutex.Unlock()
+	values := map[int]int{}
+	for _, key := range keys {
+		if key > 0 {
+			values[key] = key * key
+		}
+	}
+	return values, nil
+}
+
+func (suite *DataloaderSuite) TestConcurrentLoadsBatch() {
+	ctx := context.Background()
+	loader := dataloader.New(ctx, suite.squares, dataloader.Config{Wait: 10 * time.Millisecond})
+	values := make([]int, 4)
+	errs := make([]error, 4)
+	wg := sync.WaitGroup{}
+	for i, key := range []int{1, 2, 3, 2} {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			values[i], errs[i] = loader.Load(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
+	suite.Equal([]int{1, 4, 9, 4}, values)
+	suite.Equal([]error{nil, nil, nil, nil}, errs)
+	suite.Equal([][]int{{1, 2, 3}}, suite.batches)
+
+	// a loaded key is not loaded again
+	value, err := loader.Load(ctx, 3)
+	suite.NoError(err)
+	suite.Equal(9, value)
+	suite.Len(suite.batches, 1)
+}
+
+func (suite *DataloaderSuite) TestLoadMany() {
+	ctx := context.Background()
+	loader := dataloader.New(ctx, suite.squares, dataloader.Config{})
+	values, errs := loader.LoadMany(ctx, []int{3, -1, 2})
+	suite.Equal([]int{9, 0, 4}, values)
+	suite.NoError(errs[0])
+	suite.True(codes.ErrNotFound.Equal(errs[1]))
+	suite.NoError(errs[2])
+	suite.Equal([][]int{{-1, 2, 3}}, suite.batches)
+}
+
+func (suite *DataloaderSuite) TestMaxBatch() {
+	ctx := context.Background()
+	loader := dataloader.New(ctx, suite.squares, dataloader.Config{Wait: time.Second, MaxBatch: 2})
+	values, _ := loader.LoadMany(ctx, []int{1, 2, 3, 4})
+	suite.Equal([]int{1, 4, 9, 16}, values)
+	suite.Len(suite.batches, 2)
+	for _, batch := range suite.batches {
+		suite.Len(batch, 2)
+	}
+}
+
+func (suite *DataloaderSuite) TestPrime() {
+	ctx := context.Background()
+	loader := dataloader.New(ctx, suite.squares, dataloader.Config{})
+	loader.Prime(5, 100)
+	value, err := loader.Load(ctx, 5)
+	suite.NoError(err)
+	suite.Equal(100, value)
+	suite.Empty(suite.batches)
+
+	// a loaded key keeps its value
+	_, err = loader.Load(ctx, 6)
+	suite.NoError(err)
+	loader.Prime(6, 0)
+	value, _ = loader.Load(ctx, 6)
+	suite.Equal(36, value)
+}
+
+func (suite *DataloaderSuite) TestBatchErrors() {
+	ctx := context.Background()
+	failed := errors.New("backend down")
+	loader := dataloader.New(ctx, func(ctx context.Context, keys []int) (map[int]int, error) {
+		return nil, failed
+	}, dataloader.Config{})
+	_, errs := loader.LoadMany(ctx, []int{1, 2})
+	suite.Equal([]error{failed, failed}, errs)
+
+	loader = dataloader.New(ctx, func(ctx context.Context, keys []int) (map[int]int, error) {
+		panic("boom")
+	}, dataloader.Config{})
+	_, err := loader.Load(ctx, 1)
+	suite.True(codes.ErrInternal.Equal(err))
+}
+
+func (suite *DataloaderSuite) TestCanceledLoad() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	loader := dataloader.New(context.Background(), suite.squares, dataloader.Config{Wait: time.Second})
+	_, err := loader.Load(ctx, 1)
+	suite.ErrorIs(err, context.Canceled)
+}
+
+func TestDataloaderSuite(t *testing.T) {
+	suite.Run(t, &DataloaderSuite{})
+}
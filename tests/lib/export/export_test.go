@@ -0,0 +1,82 @@
+//go:build tests
+// +build tests
+
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/export"
+	"github.com/stretchr/testify/suite"
+)
+
+type userResp struct {
+	UID      uint64   `json:"uid"`
+	Username string   `json:"username"`
+	Tags     []string `json:"tags"`
+	Verified bool     `json:"verified"`
+}
+
+type ExportSuite struct {
+	suite.Suite
+	users []*userResp
+}
+
+func (suite *ExportSuite) SetupTest() {
+	suite.users = []*userResp{
+		{UID: 1001, Username: "alice", Tags: []string{"a", "b"}, Verified: true},
+		{UID: 1002, Username: "bob, jr"},
+	}
+}
+
+func (suite *ExportSuite) TestNDJSON() {
+	buf := &bytes.Buffer{}
+	writer := export.NewWriter("", buf)
+	suite.Equal(export.MIMEApplicationNDJSON, writer.ContentType())
+	for _, user := range suite.users {
+		suite.NoError(writer.WriteItem(user))
+	}
+	suite.NoError(writer.WriteCursor("next-1"))
+	suite.NoError(writer.WriteTruncated())
+	suite.NoError(writer.WriteError(codes.ErrInternal))
+	suite.NoError(writer.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	suite.Equal([]string{
+		`{"item":{"uid":1001,"username":"alice","tags":["a","b"],"verified":true}}`,
+		`{"item":{"uid":1002,"username":"bob, jr","tags":null,"verified":false}}`,
+		`{"cursor":"next-1"}`,
+		`{"truncated":true}`,
+		`{"error":{"code":500000,"message":"Unknown error"}}`,
+	}, lines)
+}
+
+func (suite *ExportSuite) TestCSV() {
+	buf := &bytes.Buffer{}
+	writer := export.NewWriter("CSV", buf)
+	suite.Equal(export.MIMETextCSV, writer.ContentType())
+	for _, user := range suite.users {
+		suite.NoError(writer.WriteItem(user))
+	}
+	suite.NoError(writer.WriteCursor("next-1"))
+	suite.NoError(writer.Flush())
+
+	suite.Equal("tags,uid,username,verified\n"+
+		"\"[\"\"a\"\",\"\"b\"\"]\",1001,alice,true\n"+
+		",1002,\"bob, jr\",false\n", buf.String())
+
+	// an export which did not end is closed by the cursor to resume it from
+	suite.NoError(writer.WriteTruncated())
+	suite.NoError(writer.Flush())
+	suite.True(strings.HasSuffix(buf.String(), "false\ncursor,next-1\n"), buf.String())
+	suite.NoError(writer.WriteError(codes.ErrInternal))
+	suite.NoError(writer.Flush())
+	suite.True(strings.HasSuffix(buf.String(), "cursor,next-1\nerror,Unknown error\ncursor,next-1\n"), buf.String())
+}
+
+func TestExportSuite(t *testing.T) {
+	suite.Run(t, &ExportSuite{})
+}
@@ -0,0 +1,116 @@
+//go:build tests
+// +build tests
+
+package fields
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/fields"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	"github.com/stretchr/testify/suite"
+)
+
+type userResp struct {
+	UID      uint64 `json:"uid"`
+	Username string `json:"username"`
+	Avatar   *struct {
+		Small string `json:"small"`
+	} `json:"avatar"`
+}
+
+type statusResp struct {
+	ID           string      `json:"id"`
+	Content      string      `json:"content"`
+	User         *userResp   `json:"user"`
+	ParentStatus *statusResp `json:"parent_status"`
+	Images       []string    `json:"images"`
+}
+
+type FieldsSuite struct {
+	suite.Suite
+	statuses []*statusResp
+}
+
+func (suite *FieldsSuite) SetupTest() {
+	suite.statuses = []*statusResp{
+		{
+			ID:      "1",
+			Content: "hello",
+			User:    &userResp{UID: 1001, Username: "alice"},
+			ParentStatus: &statusResp{
+				ID:   "0",
+				User: &userResp{UID: 1002, Username: "bob"},
+			},
+			Images: []string{"a.jpg"},
+		},
+	}
+}
+
+func (suite *FieldsSuite) TestNoSelection() {
+	suite.Nil(fields.Parse("", " , "))
+	data, err := fields.Parse("", "").Apply(suite.statuses)
+	suite.NoError(err)
+	suite.Equal(suite.statuses, data)
+}
+
+func (suite *FieldsSuite) TestFields() {
+	data, err := fields.Parse("id,user.username", "").Apply(suite.statuses)
+	suite.NoError(err)
+	item := data.([]interface{})[0].(map[string]interface{})
+	suite.Len(item, 2)
+	suite.Equal("1", item["id"])
+	suite.Equal(map[string]interface{}{"username": "alice"}, item["user"])
+}
+
+func (suite *FieldsSuite) TestExpand() {
+	data, err := fields.Parse("", "user").Apply(suite.statuses[0])
+	suite.NoError(err)
+	item := data.(map[string]interface{})
+	suite.Contains(item, "content")
+	suite.Contains(item, "images")
+	suite.Contains(item, "user")
+	suite.NotContains(item, "parent_status")
+
+	data, err = fields.Parse("id", "parent_status.user").Apply(suite.statuses[0])
+	suite.NoError(err)
+	item = data.(map[string]interface{})
+	suite.Len(item, 2)
+	parent := item["parent_status"].(map[string]interface{})
+	suite.Equal("0", parent["id"])
+	suite.Contains(parent, "user")
+}
+
+func (suite *FieldsSuite) TestInvalidField() {
+	_, err := fields.Parse("id,eamil", "").Apply(suite.statuses)
+	suite.True(codes.ErrInvalidArgument.Equal(err))
+	_, err = fields.Parse("", "user.wallet").Apply(suite.statuses)
+	suite.True(codes.ErrInvalidArgument.Equal(err))
+}
+
+func (suite *FieldsSuite) TestProtobufSelection() {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/status/list?fields=id", nil)
+	req.Header.Set(echo.HeaderAccept, wire.MIMEApplicationProtobuf)
+	_, err := fields.FromRequest(e.NewContext(req, httptest.NewRecorder()))
+	suite.True(codes.ErrInvalidArgument.Equal(err))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/status/list", nil)
+	req.Header.Set(echo.HeaderAccept, wire.MIMEApplicationProtobuf)
+	selector, err := fields.FromRequest(e.NewContext(req, httptest.NewRecorder()))
+	suite.NoError(err)
+	suite.Nil(selector)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/status/list?fields=id", nil)
+	selector, err = fields.FromRequest(e.NewContext(req, httptest.NewRecorder()))
+	suite.NoError(err)
+	suite.Equal("fields=id&expand=", selector.String())
+}
+
+func TestFieldsSuite(t *testing.T) {
+	suite.Run(t, &FieldsSuite{})
+}
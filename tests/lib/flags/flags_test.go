@@ -0,0 +1,77 @@
+//go:build tests
+// +build tests
+
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mises-id/sns-apigateway/lib/flags"
+	"github.com/stretchr/testify/suite"
+)
+
+type FlagsSuite struct {
+	suite.Suite
+}
+
+func (suite *FlagsSuite) TestEnabled() {
+	set := flags.Set{
+		"off":     {Enabled: false, Percentage: 100, UIDs: []uint64{1}},
+		"all":     {Enabled: true, Percentage: 100},
+		"listed":  {Enabled: true, UIDs: []uint64{1}, MisesIDs: []string{"did:mises:2"}, DeviceIDs: []string{"d3"}},
+		"android": {Enabled: true, Percentage: 100, Platforms: []string{"Android"}},
+	}
+	suite.False(set.Enabled("off", flags.Subject{UID: 1}))
+	suite.False(set.Enabled("unknown", flags.Subject{UID: 1}))
+	suite.True(set.Enabled("all", flags.Subject{}))
+	suite.True(set.Enabled("listed", flags.Subject{UID: 1}))
+	suite.True(set.Enabled("listed", flags.Subject{MisesID: "did:mises:2"}))
+	suite.True(set.Enabled("listed", flags.Subject{DeviceID: "d3"}))
+	suite.False(set.Enabled("listed", flags.Subject{UID: 4}))
+	suite.True(set.Enabled("android", flags.Subject{Platform: "android"}))
+	suite.False(set.Enabled("android", flags.Subject{Platform: "iPhone"}))
+}
+
+func (suite *FlagsSuite) TestPercentage() {
+	set := flags.Set{"half": {Enabled: true, Percentage: 50}}
+	enabled := 0
+	for uid := uint64(1); uid <= 2000; uid++ {
+		subject := flags.Subject{UID: uid}
+		on := set.Enabled("half", subject)
+		// a subject keeps its bucket
+		suite.Equal(on, set.Enabled("half", subject))
+		if on {
+			enabled++
+		}
+	}
+	suite.InDelta(1000, enabled, 100)
+	// anonymous requests without a device are out of a partial rollout
+	suite.False(set.Enabled("half", flags.Subject{}))
+}
+
+func (suite *FlagsSuite) TestStore() {
+	path := filepath.Join(suite.T().TempDir(), "flags.yaml")
+	suite.Require().NoError(os.WriteFile(path, []byte("nft_avatar:\n  enabled: false\nnew_feed:\n  enabled: true\n  percentage: 100\n"), 0600))
+	store := flags.NewStore(flags.Set{
+		"nft_avatar": {Enabled: true, Percentage: 100},
+		"ad_mining":  {Enabled: true, Percentage: 100},
+	})
+	before := store.Current()
+
+	set, err := flags.Load(path)
+	suite.Require().NoError(err)
+	store.Replace(set)
+	suite.Equal(map[string]bool{"nft_avatar": false, "ad_mining": true, "new_feed": true}, store.Current().Evaluate(flags.Subject{}))
+	// a set taken before the reload is unchanged
+	suite.True(before.Enabled("nft_avatar", flags.Subject{}))
+
+	suite.Require().NoError(os.WriteFile(path, []byte("broken:\n  percentage: 150\n"), 0600))
+	_, err = flags.Load(path)
+	suite.Error(err)
+}
+
+func TestFlagsSuite(t *testing.T) {
+	suite.Run(t, &FlagsSuite{})
+}
@@ -0,0 +1,67 @@
+//go:build tests
+// +build tests
+
+package graphql
+
+import (
+	"testing"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	gql "github.com/mises-id/sns-apigateway/lib/graphql"
+	"github.com/stretchr/testify/suite"
+)
+
+type ComplexitySuite struct {
+	suite.Suite
+	schema *graphqlgo.Schema
+}
+
+func (suite *ComplexitySuite) SetupSuite() {
+	schema, err := graphqlgo.ParseSchema(sdl, nil)
+	suite.Require().NoError(err)
+	suite.schema = schema
+}
+
+func (suite *ComplexitySuite) complexity(query string, variables map[string]interface{}) int {
+	return gql.Complexity(suite.schema.ASTSchema(), query, "", variables, 10)
+}
+
+func (suite *ComplexitySuite) TestFields() {
+	suite.Equal(3, suite.complexity(`{ user(uid: 1) { uid name } }`, nil))
+	// a list of scalars costs like a scalar
+	suite.Equal(3, suite.complexity(`{ user(uid: 1) { uid tags } }`, nil))
+	// the default list size, the limit argument, its default and a variable
+	suite.Equal(1+1+10*1, suite.complexity(`{ user(uid: 1) { friends { uid } } }`, nil))
+	suite.Equal(1+3*2, suite.complexity(`{ users(limit: 3) { uid name } }`, nil))
+	suite.Equal(1+5*2, suite.complexity(`{ users { uid name } }`, nil))
+	suite.Equal(1+7*2, suite.complexity(`query($n: Int) { users(limit: $n) { uid name } }`, map[string]interface{}{"n": 7}))
+	// the uids listed
+	suite.Equal(1+3*2, suite.complexity(`{ known(uids: [1, 2, 3]) { uid name } }`, nil))
+	suite.Equal(1+4*2, suite.complexity(`query($uids: [Uint64!]!) { known(uids: $uids) { uid name } }`, map[string]interface{}{"uids": []interface{}{"1", "2", "3", "4"}}))
+	suite.Zero(suite.complexity(`{ known(uids: [1, 2`, nil))
+	// nested lists multiply
+	suite.Equal(1+2*(1+1+3*1), suite.complexity(`{ users(limit: 2) { uid friends(first: 3) { uid } } }`, nil))
+}
+
+func (suite *ComplexitySuite) TestPage() {
+	// the limit of the page bounds its list
+	suite.Equal(1+1+4*1, suite.complexity(`{ page(limit: 4) { users { uid } } }`, nil))
+	suite.Equal(1+1+10*1, suite.complexity(`{ page { users { uid } } }`, nil))
+}
+
+func (suite *ComplexitySuite) TestFragments() {
+	query := `
+query Named {
+  users(limit: 2) { ...fields ... on User { name } }
+}
+query Other { user(uid: 1) { uid } }
+fragment fields on User { uid tags @include(if: true) }`
+	suite.Equal(1+2*3, gql.Complexity(suite.schema.ASTSchema(), query, "Named", nil, 10))
+	suite.Equal(2, gql.Complexity(suite.schema.ASTSchema(), query, "Other", nil, 10))
+	suite.Zero(gql.Complexity(suite.schema.ASTSchema(), query, "", nil, 10))
+	suite.Zero(suite.complexity(`{ users {`, nil))
+}
+
+func TestComplexitySuite(t *testing.T) {
+	suite.Run(t, &ComplexitySuite{})
+}
@@ -0,0 +1,307 @@
+//go:build tests
+// +build tests
+
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/cache"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	gql "github.com/mises-id/sns-apigateway/lib/graphql"
+	"github.com/stretchr/testify/suite"
+)
+
+const sdl = `
+schema {
+  query: Query
+}
+
+scalar Uint64
+
+type Query {
+  user(uid: Uint64!): User
+  users(limit: Int = 5): [User!]!
+  known(uids: [Uint64!]!): [User!]!
+  page(limit: Int): UserPage!
+  failure(code: Int!): String
+}
+
+type User {
+  uid: Uint64!
+  name: String!
+  friends(first: Int): [User!]!
+  tags: [String!]!
+}
+
+type UserPage {
+  users: [User!]!
+}
+`
+
+type resolver struct {
+	contexts int32
+}
+
+type user struct {
+	UID  gql.Uint64
+	Name string
+	Tags []string
+}
+
+func (u *user) Friends(args struct{ First *int32 }) []*user {
+	return []*user{{UID: u.UID + 1, Name: "friend", Tags: []string{}}}
+}
+
+type userPage struct {
+	Users []*user
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ UID gql.Uint64 }) *user {
+	if args.UID == 0 {
+		return nil
+	}
+	return &user{UID: args.UID, Name: gql.EchoContext(ctx).Request().Header.Get("X-Name"), Tags: []string{}}
+}
+
+func (r *resolver) Users(args struct{ Limit int32 }) []*user {
+	users := []*user{}
+	for i := int32(1); i <= args.Limit; i++ {
+		users = append(users, &user{UID: gql.Uint64(i), Tags: []string{}})
+	}
+	return users
+}
+
+func (r *resolver) Known(args struct{ UIDs []gql.Uint64 }) []*user {
+	users := []*user{}
+	for _, uid := range args.UIDs {
+		users = append(users, &user{UID: uid, Tags: []string{}})
+	}
+	return users
+}
+
+func (r *resolver) Page(args struct{ Limit *int32 }) *userPage {
+	return &userPage{Users: []*user{}}
+}
+
+func (r *resolver) Failure(args struct{ Code int32 }) (*string, error) {
+	switch args.Code {
+	case 404:
+		return nil, codes.ErrNotFound
+	case 500:
+		return nil, errors.New("secret details")
+	}
+	return nil, codes.InvalidFields(codes.FieldError{Field: "code", Rule: "oneof", Message: "code is not a known code"})
+}
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type GraphQLSuite struct {
+	suite.Suite
+	resolver *resolver
+	config   gql.Config
+}
+
+func (suite *GraphQLSuite) SetupTest() {
+	suite.resolver = &resolver{}
+	suite.config = gql.Config{
+		MaxDepth:          4,
+		MaxComplexity:     100,
+		ListSize:          10,
+		MaxBatch:          2,
+		PersistedQueries:  cache.NewMemoryStore(10),
+		PersistedQueryTTL: time.Minute,
+		Context: func(ctx context.Context, c echo.Context) context.Context {
+			atomic.AddInt32(&suite.resolver.contexts, 1)
+			return ctx
+		},
+	}
+}
+
+func (suite *GraphQLSuite) serve(method, target, body string) (*httptest.ResponseRecorder, error) {
+	handler, err := gql.New(sdl, suite.resolver, suite.config)
+	suite.Require().NoError(err)
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Name", "alice")
+	rec := httptest.NewRecorder()
+	return rec, handler.Serve(echo.New().NewContext(req, rec))
+}
+
+func (suite *GraphQLSuite) post(body string) *response {
+	rec, err := suite.serve(http.MethodPost, "/graphql", body)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, rec.Code)
+	resp := &response{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), resp))
+	return resp
+}
+
+func (suite *GraphQLSuite) request(query string, variables map[string]interface{}) string {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	suite.Require().NoError(err)
+	return string(body)
+}
+
+func (suite *GraphQLSuite) TestQuery() {
+	// a uid above the float precision is read from a string and written as a number
+	rec, err := suite.serve(http.MethodPost, "/graphql", suite.request(`query($uid: Uint64!) { user(uid: $uid) { uid name } }`,
+		map[string]interface{}{"uid": "18446744073709551615"}))
+	suite.Require().NoError(err)
+	suite.Equal(`{"data":{"user":{"uid":18446744073709551615,"name":"alice"}}}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func (suite *GraphQLSuite) TestGet() {
+	params := url.Values{}
+	params.Set("query", `query($uid: Uint64!) { user(uid: $uid) { name } }`)
+	params.Set("variables", `{"uid": 3}`)
+	rec, err := suite.serve(http.MethodGet, "/graphql?"+params.Encode(), "")
+	suite.Require().NoError(err)
+	suite.JSONEq(`{"data":{"user":{"name":"alice"}}}`, rec.Body.String())
+
+	params.Set("variables", `[`)
+	suite.requestError(http.MethodGet, "/graphql?"+params.Encode(), "", codes.ErrInvalidArgument)
+}
+
+// requestError checks the request is answered the graphql error of code
+func (suite *GraphQLSuite) requestError(method, target, body string, code codes.Code) *response {
+	rec, err := suite.serve(method, target, body)
+	suite.Require().NoError(err)
+	suite.Equal(code.HTTPStatus, rec.Code)
+	resp := &response{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), resp))
+	suite.Nil(resp.Data)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal(float64(code.Code), resp.Errors[0].Extensions["code"])
+	return resp
+}
+
+func (suite *GraphQLSuite) TestBatch() {
+	rec, err := suite.serve(http.MethodPost, "/graphql",
+		`[{"query":"{ user(uid: 1) { uid } }"},{"query":"{ user(uid: 0) { uid } }"}]`)
+	suite.Require().NoError(err)
+	suite.JSONEq(`[{"data":{"user":{"uid":1}}},{"data":{"user":null}}]`, rec.Body.String())
+	// the operations of a batch share the context of the request
+	suite.Equal(int32(1), suite.resolver.contexts)
+
+	resp := suite.requestError(http.MethodPost, "/graphql", `[{"query":"{ users { uid } }"},{"query":"{ users { uid } }"},{"query":"{ users { uid } }"}]`, codes.ErrInvalidArgument)
+	suite.Equal("a batch has between 1 and 2 operations", resp.Errors[0].Message)
+	suite.requestError(http.MethodPost, "/graphql", `[]`, codes.ErrInvalidArgument)
+	suite.requestError(http.MethodPost, "/graphql", `{`, codes.ErrInvalidArgument)
+}
+
+func (suite *GraphQLSuite) TestBodyLimit() {
+	handler, err := gql.New(sdl, suite.resolver, suite.config)
+	suite.Require().NoError(err)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(suite.request(`{ users { uid } }`, nil)))
+	req.Body = http.MaxBytesReader(rec, req.Body, 4)
+	suite.Require().NoError(handler.Serve(echo.New().NewContext(req, rec)))
+	suite.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	suite.Contains(rec.Body.String(), `"errors"`)
+}
+
+func (suite *GraphQLSuite) TestErrors() {
+	resp := suite.post(`{"query":"{ a: failure(code: 404) b: failure(code: 500) c: failure(code: 400) }"}`)
+	suite.Require().Len(resp.Errors, 3)
+	byPath := map[string]int{}
+	for i, queryErr := range resp.Errors {
+		byPath[queryErr.Path[0].(string)] = i
+	}
+	notFound := resp.Errors[byPath["a"]]
+	suite.Equal(codes.ErrNotFound.Msg, notFound.Message)
+	suite.Equal(float64(codes.ErrNotFound.Code), notFound.Extensions["code"])
+	internal := resp.Errors[byPath["b"]]
+	suite.Equal(codes.ErrInternal.Msg, internal.Message)
+	suite.NotContains(internal.Message, "secret")
+	invalid := resp.Errors[byPath["c"]]
+	suite.Equal("code is not a known code", invalid.Message)
+	suite.Equal(float64(codes.ErrInvalidArgument.Code), invalid.Extensions["code"])
+	suite.Equal([]interface{}{map[string]interface{}{"field": "code", "rule": "oneof", "message": "code is not a known code"}}, invalid.Extensions["fields"])
+
+	resp = suite.post(`{"query":"{ unknown }"}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal(float64(codes.ErrInvalidArgument.Code), resp.Errors[0].Extensions["code"])
+}
+
+func (suite *GraphQLSuite) TestDepth() {
+	suite.config.MaxComplexity = 0
+	resp := suite.post(`{"query":"{ user(uid: 1) { friends { friends { friends { uid } } } } }"}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Contains(resp.Errors[0].Message, "depth")
+	suite.Nil(resp.Data)
+}
+
+func (suite *GraphQLSuite) TestComplexity() {
+	resp := suite.post(`{"query":"{ users(limit: 20) { uid name tags } }"}`)
+	suite.Empty(resp.Errors)
+
+	resp = suite.post(`{"query":"{ users(limit: 50) { uid name tags } }"}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal("query has complexity 151 that exceeds max complexity 100", resp.Errors[0].Message)
+	suite.Equal(float64(codes.ErrInvalidArgument.Code), resp.Errors[0].Extensions["code"])
+}
+
+func (suite *GraphQLSuite) TestPersistedQuery() {
+	query := `{ user(uid: 2) { uid } }`
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	extensions := `"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}`
+
+	resp := suite.post(`{` + extensions + `}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal(gql.PersistedQueryNotFound, resp.Errors[0].Message)
+
+	body, _ := json.Marshal(query)
+	resp = suite.post(`{"query":` + string(body) + `,` + extensions + `}`)
+	suite.Empty(resp.Errors)
+	// the store of the gateway prefixes its keys with graphql:
+	stored, ok, err := suite.config.PersistedQueries.Get(context.Background(), "persisted:"+hash)
+	suite.Require().NoError(err)
+	suite.True(ok)
+	suite.Equal(query, string(stored))
+
+	resp = suite.post(`{` + extensions + `}`)
+	suite.Empty(resp.Errors)
+	suite.Equal(map[string]interface{}{"user": map[string]interface{}{"uid": float64(2)}}, resp.Data)
+
+	resp = suite.post(`{"query":"{ users { uid } }",` + extensions + `}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal("provided sha does not match query", resp.Errors[0].Message)
+
+	suite.config.PersistedQueries = nil
+	resp = suite.post(`{` + extensions + `}`)
+	suite.Require().Len(resp.Errors, 1)
+	suite.Equal(gql.PersistedQueryNotSupported, resp.Errors[0].Message)
+}
+
+func (suite *GraphQLSuite) TestIntrospection() {
+	resp := suite.post(`{"query":"{ __schema { queryType { name } } }"}`)
+	suite.Empty(resp.Errors)
+
+	suite.config.DisableIntrospection = true
+	resp = suite.post(`{"query":"{ __schema { queryType { name } } }"}`)
+	suite.Nil(resp.Data["__schema"])
+}
+
+func TestGraphQLSuite(t *testing.T) {
+	suite.Run(t, &GraphQLSuite{})
+}
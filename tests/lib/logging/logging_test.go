@@ -0,0 +1,137 @@
+//go:build tests
+// +build tests
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/logging"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggingSuite struct {
+	suite.Suite
+	out *bytes.Buffer
+	e   *echo.Echo
+}
+
+func (suite *LoggingSuite) SetupTest() {
+	suite.out = &bytes.Buffer{}
+	logging.Setup(logging.FormatJSON, "mises-sns")
+	logrus.SetOutput(suite.out)
+	logging.SetAccessSampleRatio(1)
+
+	suite.e = echo.New()
+	suite.e.Use(middleware.RequestID())
+	suite.e.Use(logging.Middleware(nil))
+	suite.e.GET("/api/v1/user/:uid", func(c echo.Context) error {
+		c.Set("CurrentUID", uint64(1001))
+		logging.FromContext(c).Info("login of someone@example.com")
+		return c.NoContent(http.StatusOK)
+	})
+	suite.e.GET("/api/v1/missing", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+	suite.e.GET("/api/v1/broken", func(c echo.Context) error {
+		return codes.ErrInternal
+	}, mw.ErrorResponseMiddleware)
+}
+
+func (suite *LoggingSuite) TearDownTest() {
+	logrus.SetOutput(os.Stderr)
+	logging.SetAccessSampleRatio(1)
+}
+
+func (suite *LoggingSuite) get(path string) []map[string]interface{} {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(logging.DeviceIDHeader, "device-1")
+	suite.e.ServeHTTP(httptest.NewRecorder(), req)
+	lines := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(suite.out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+	suite.out.Reset()
+	return lines
+}
+
+func (suite *LoggingSuite) TestRequestFields() {
+	lines := suite.get("/api/v1/user/1001?token=abc")
+	suite.Require().Len(lines, 2)
+	for _, line := range lines {
+		suite.NotEmpty(line["request_id"])
+		suite.Equal("/api/v1/user/:uid", line["route"])
+		suite.Equal("device-1", line["device_id"])
+		suite.Equal(float64(1001), line["uid"])
+		suite.Equal(map[string]interface{}{"service": "mises-sns"}, line["serviceContext"])
+	}
+	suite.Equal("login of ***@example.com", lines[0]["message"])
+	access := lines[1]
+	suite.Equal("INFO", access["severity"])
+	suite.Equal(float64(http.StatusOK), access["status"])
+	suite.Equal("/api/v1/user/1001?token=[redacted]", access["uri"])
+}
+
+func (suite *LoggingSuite) TestAccessSampling() {
+	logging.SetAccessSampleRatio(0)
+	suite.Len(suite.get("/api/v1/user/1001"), 1)
+
+	lines := suite.get("/api/v1/missing")
+	suite.Require().Len(lines, 1)
+	suite.Equal("WARNING", lines[0]["severity"])
+	suite.Equal(float64(http.StatusNotFound), lines[0]["status"])
+	suite.NotEmpty(lines[0]["error"])
+}
+
+func (suite *LoggingSuite) TestAnsweredErrorLoggedOnce() {
+	lines := suite.get("/api/v1/broken")
+	suite.Require().Len(lines, 1)
+	suite.Equal("ERROR", lines[0]["severity"])
+	suite.Equal(float64(http.StatusInternalServerError), lines[0]["status"])
+	suite.Equal(codes.ErrInternal.Error(), lines[0]["error"])
+}
+
+func (suite *LoggingSuite) TestRedact() {
+	cases := map[string]string{
+		"wallet 0x52908400098527886E0F7030069857D2E4169EE7 connected":    "wallet 0x5290...9EE7 connected",
+		"misesid did:mises:mises1y53kz80x5gm2w0ype8x7a3w6sstztxxg7qkl5n": "misesid did:mises:mises1...kl5n",
+		"mail to Jane.Doe+news@mises.site":                               "mail to ***@mises.site",
+		"call +86 13800138000 or 13912345678":                            "call ***8000 or ***5678",
+		"Authorization: Bearer abc.def-ghi":                              "Authorization: Bearer [redacted]",
+		"jwt eyJhbGciOiJIUzI1NiJ9.eyJ1aWQiOjF9.c2ln":                     "jwt [redacted]",
+		"/callback?sig=a1b2&user_id=7":                                   "/callback?sig=[redacted]&user_id=7",
+		// ids and timestamps are not mobile numbers
+		"uid 1001 at 1697712345678 took 0.0013812345678s": "uid 1001 at 1697712345678 took 0.0013812345678s",
+	}
+	for in, expected := range cases {
+		suite.Equal(expected, logging.Redact(in), in)
+	}
+}
+
+func (suite *LoggingSuite) TestSensitiveFields() {
+	logrus.WithFields(logrus.Fields{"token": "plain", "Authorization": "Basic dXNlcg=="}).Info("fields")
+	line := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(suite.out.Bytes(), &line))
+	suite.Equal("[redacted]", line["token"])
+	suite.Equal("[redacted]", line["Authorization"])
+}
+
+func TestLoggingSuite(t *testing.T) {
+	suite.Run(t, &LoggingSuite{})
+}
@@ -0,0 +1,128 @@
+//go:build tests
+// +build tests
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mises-id/sns-apigateway/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+}
+
+func (suite *MetricsSuite) TestInterceptor() {
+	interceptor := metrics.UnaryClientInterceptor("swapsvc")
+	var inFlight float64
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		inFlight = gaugeValue("apigateway_grpc_client_in_flight", "swapsvc", method)
+		return status.Error(grpccodes.Unavailable, "down")
+	}
+	err := interceptor(context.Background(), "/swapsvc.Swapsvc/SwapQuote", nil, nil, nil, invoker)
+	suite.Error(err)
+	suite.Equal(float64(1), inFlight)
+	suite.Equal(float64(0), gaugeValue("apigateway_grpc_client_in_flight", "swapsvc", "/swapsvc.Swapsvc/SwapQuote"))
+
+	// gathered labels are sorted by name: backend, code, method
+	suite.Equal(uint64(1), metric("apigateway_grpc_client_handling_seconds", "swapsvc", "Unavailable", "/swapsvc.Swapsvc/SwapQuote").GetHistogram().GetSampleCount())
+}
+
+func (suite *MetricsSuite) TestBoundedLabel() {
+	label := metrics.NewBoundedLabel(2)
+	suite.Equal("56", label.Value("56"))
+	suite.Equal("1", label.Value("1"))
+	suite.Equal(metrics.LabelOther, label.Value("137"))
+	suite.Equal("56", label.Value("56"))
+	suite.Equal(metrics.LabelUnknown, label.Value(""))
+}
+
+func (suite *MetricsSuite) TestAllowedLabel() {
+	label := metrics.NewAllowedLabel(2, "56")
+	suite.Equal("56", label.Value("56"))
+	suite.Equal(metrics.LabelOther, label.Value("ETH/BTC"))
+	label.Allow("ETH/BTC")
+	suite.Equal("eth/btc", label.Value("eth/btc"))
+	label.Allow("eth/usdt")
+	suite.Equal(metrics.LabelOther, label.Value("eth/usdt"))
+	suite.Equal(metrics.LabelUnknown, label.Value(""))
+}
+
+func (suite *MetricsSuite) TestBusinessCounters() {
+	metrics.CountSwapQuote(10, "1inch", metrics.ItemOutcome("insufficient liquidity"))
+	metrics.CountSwapQuote(10, "1inch", metrics.ItemOutcome(""))
+	metrics.ObserveSwapQuoteAggregators(10, 1)
+	metrics.CountSwapQuote(10, "unlisted", metrics.ItemOutcome(""))
+	metrics.CountSwapTrade(999999, metrics.Outcome(nil))
+	metrics.CountBridgeTransaction("fixed", "eth/btc", metrics.Outcome(status.Error(grpccodes.Internal, "down")))
+	metrics.CountBridgeTransaction("fixed", "eth/usdt", metrics.Outcome(nil))
+	metrics.CountBridgeTransaction("fixed", "eth/usdt", metrics.Outcome(status.Error(grpccodes.Internal, "down")))
+	metrics.CountAdCallback("mintegral", metrics.Outcome(nil))
+
+	// labels are sorted by name
+	suite.Equal(float64(1), counterValue("apigateway_swap_quotes_total", "1inch", "10", metrics.OutcomeError))
+	suite.Equal(float64(1), counterValue("apigateway_swap_quotes_total", "1inch", "10", metrics.OutcomeOK))
+	suite.Equal(uint64(1), metric("apigateway_swap_quote_aggregators", "10").GetHistogram().GetSampleCount())
+	suite.Equal(float64(1), counterValue("apigateway_swap_quotes_total", metrics.LabelOther, "10", metrics.OutcomeOK))
+	suite.Equal(float64(1), counterValue("apigateway_swap_trades_total", metrics.LabelOther, metrics.OutcomeOK))
+	// a pair is a label once the backend created a transaction for it
+	suite.Equal(float64(1), counterValue("apigateway_bridge_transactions_total", metrics.OutcomeFailed, metrics.LabelOther, "fixed"))
+	suite.Equal(float64(1), counterValue("apigateway_bridge_transactions_total", metrics.OutcomeOK, "eth/usdt", "fixed"))
+	suite.Equal(float64(1), counterValue("apigateway_bridge_transactions_total", metrics.OutcomeFailed, "eth/usdt", "fixed"))
+	suite.Equal(float64(1), counterValue("apigateway_ad_callbacks_total", "mintegral", metrics.OutcomeOK))
+}
+
+func counterValue(name string, labels ...string) float64 {
+	return metric(name, labels...).GetCounter().GetValue()
+}
+
+func gaugeValue(name string, labels ...string) float64 {
+	return metric(name, labels...).GetGauge().GetValue()
+}
+
+// metric returns the sample of the default registry with the label values, nil if there is none
+func metric(name string, labels ...string) *dto.Metric {
+	families, _ := prometheus.DefaultGatherer.Gather()
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.Metric {
+			values := []string{}
+			for _, label := range metric.Label {
+				values = append(values, label.GetValue())
+			}
+			if strings.Join(values, ",") == strings.Join(labels, ",") {
+				return metric
+			}
+		}
+	}
+	return nil
+}
+
+// TestDashboardUpToDate fails when the metric definitions changed without regenerating the dashboard
+// with `sns-apigateway dashboard > dashboards/grpc_client.json`
+func (suite *MetricsSuite) TestDashboardUpToDate() {
+	generated, err := metrics.Dashboard()
+	suite.Require().NoError(err)
+	committed, err := os.ReadFile("../../../dashboards/grpc_client.json")
+	suite.Require().NoError(err)
+	suite.Equal(strings.TrimSpace(string(committed)), string(generated))
+	for _, definition := range metrics.Definitions {
+		suite.Contains(string(generated), definition.FullName())
+	}
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, &MetricsSuite{})
+}
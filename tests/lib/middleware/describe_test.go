@@ -0,0 +1,65 @@
+//go:build tests
+// +build tests
+
+package middleware
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/stretchr/testify/suite"
+)
+
+type DescribeSuite struct {
+	suite.Suite
+}
+
+func limiter(limit int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if limit == 0 {
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
+func (suite *DescribeSuite) TestClosuresOfTheSameLiteral() {
+	first := mw.Describe("rate_limit 1/s", limiter(1))
+	second := mw.Describe("rate_limit 2/s", limiter(2))
+	suite.Equal("rate_limit 1/s", mw.Description(first))
+	suite.Equal("rate_limit 2/s", mw.Description(second))
+}
+
+func (suite *DescribeSuite) TestDescribedMiddlewareServes() {
+	served := false
+	h := mw.Describe("rate_limit 1/s", limiter(1))(func(c echo.Context) error {
+		served = true
+		return nil
+	})
+	suite.NoError(h(echo.New().NewContext(nil, nil)))
+	suite.True(served)
+}
+
+func passthrough(next echo.HandlerFunc) echo.HandlerFunc {
+	return next
+}
+
+func described(next echo.HandlerFunc) echo.HandlerFunc {
+	return next
+}
+
+func (suite *DescribeSuite) TestDescribedFunction() {
+	mw.Describe("described", described)
+	suite.Equal("described", mw.Description(described))
+}
+
+func (suite *DescribeSuite) TestFallsBackToFunctionName() {
+	suite.Equal("middleware.passthrough", mw.Description(passthrough))
+}
+
+func TestDescribeSuite(t *testing.T) {
+	suite.Run(t, &DescribeSuite{})
+}
@@ -0,0 +1,99 @@
+//go:build tests
+// +build tests
+
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/stretchr/testify/suite"
+)
+
+type ETagSuite struct {
+	suite.Suite
+	e *echo.Echo
+}
+
+func (suite *ETagSuite) SetupTest() {
+	suite.e = echo.New()
+	suite.e.GET("/tokens", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{"code": 0, "data": []string{"MIS", "ETH"}})
+	}, middleware.Gzip(), mw.ETag("public, max-age=300"))
+	suite.e.GET("/news", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderLastModified, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+		return c.JSON(http.StatusOK, echo.Map{"code": 0})
+	}, mw.ETag(""))
+	suite.e.GET("/missing", func(c echo.Context) error {
+		return c.JSON(http.StatusNotFound, echo.Map{"code": 404000})
+	}, mw.ETag("public"))
+}
+
+func (suite *ETagSuite) serve(path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *ETagSuite) TestIfNoneMatch() {
+	first := suite.serve("/tokens", nil)
+	suite.Equal(http.StatusOK, first.Code)
+	etag := first.Header().Get(mw.HeaderETag)
+	suite.NotEmpty(etag)
+	suite.Equal("public, max-age=300", first.Header().Get(mw.HeaderCacheControl))
+
+	second := suite.serve("/tokens", map[string]string{mw.HeaderIfNoneMatch: `"other", ` + etag})
+	suite.Equal(http.StatusNotModified, second.Code)
+	suite.Empty(second.Body.Bytes())
+
+	changed := suite.serve("/tokens", map[string]string{mw.HeaderIfNoneMatch: `"other"`})
+	suite.Equal(http.StatusOK, changed.Code)
+}
+
+func (suite *ETagSuite) TestGzip() {
+	rec := suite.serve("/tokens", map[string]string{echo.HeaderAcceptEncoding: "gzip"})
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	reader, err := gzip.NewReader(rec.Body)
+	suite.Require().NoError(err)
+	body, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	suite.JSONEq(`{"code":0,"data":["MIS","ETH"]}`, string(body))
+
+	notModified := suite.serve("/tokens", map[string]string{
+		echo.HeaderAcceptEncoding: "gzip",
+		mw.HeaderIfNoneMatch:      rec.Header().Get(mw.HeaderETag),
+	})
+	suite.Equal(http.StatusNotModified, notModified.Code)
+	suite.Empty(notModified.Header().Get(echo.HeaderContentEncoding))
+	suite.Empty(notModified.Body.Bytes())
+}
+
+func (suite *ETagSuite) TestIfModifiedSince() {
+	rec := suite.serve("/news", map[string]string{echo.HeaderIfModifiedSince: "Sat, 01 Jan 2022 00:00:00 GMT"})
+	suite.Equal(http.StatusNotModified, rec.Code)
+	rec = suite.serve("/news", map[string]string{echo.HeaderIfModifiedSince: "Fri, 31 Dec 2021 00:00:00 GMT"})
+	suite.Equal(http.StatusOK, rec.Code)
+}
+
+func (suite *ETagSuite) TestErrorResponse() {
+	rec := suite.serve("/missing", nil)
+	suite.Equal(http.StatusNotFound, rec.Code)
+	suite.Empty(rec.Header().Get(mw.HeaderETag))
+	suite.JSONEq(`{"code":404000}`, rec.Body.String())
+}
+
+func TestETagSuite(t *testing.T) {
+	suite.Run(t, &ETagSuite{})
+}
@@ -0,0 +1,59 @@
+//go:build tests
+// +build tests
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/stretchr/testify/suite"
+)
+
+type InFlightSuite struct {
+	suite.Suite
+	e        *echo.Echo
+	inFlight *mw.InFlight
+	release  chan struct{}
+	started  chan struct{}
+}
+
+func (suite *InFlightSuite) SetupTest() {
+	suite.inFlight = &mw.InFlight{}
+	suite.release = make(chan struct{})
+	suite.started = make(chan struct{})
+	suite.e = echo.New()
+	suite.e.Use(suite.inFlight.Middleware())
+	suite.e.GET("/slow", func(c echo.Context) error {
+		close(suite.started)
+		<-suite.release
+		return c.NoContent(http.StatusOK)
+	})
+}
+
+func (suite *InFlightSuite) TestWaitsForRequests() {
+	done := make(chan struct{})
+	go func() {
+		suite.e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+	<-suite.started
+	suite.Equal(int64(1), suite.inFlight.Count())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	suite.Equal(int64(1), suite.inFlight.Wait(ctx))
+
+	close(suite.release)
+	<-done
+	suite.Equal(int64(0), suite.inFlight.Wait(context.Background()))
+}
+
+func TestInFlightSuite(t *testing.T) {
+	suite.Run(t, &InFlightSuite{})
+}
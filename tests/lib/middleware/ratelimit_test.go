@@ -0,0 +1,73 @@
+//go:build tests
+// +build tests
+
+package middleware
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4/middleware"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitSuite struct {
+	suite.Suite
+}
+
+func (suite *RateLimitSuite) allowed(store *mw.RateLimiterStore, identifier string) bool {
+	allowed, err := store.Allow(identifier)
+	suite.Require().NoError(err)
+	return allowed
+}
+
+func (suite *RateLimitSuite) TestBurstFollowsRate() {
+	store := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: 2})
+	suite.True(suite.allowed(store, "1.1.1.1"))
+	suite.True(suite.allowed(store, "1.1.1.1"))
+	suite.False(suite.allowed(store, "1.1.1.1"))
+
+	store.SetRate(3)
+	for i := 0; i < 3; i++ {
+		suite.True(suite.allowed(store, "1.1.1.1"))
+	}
+	suite.False(suite.allowed(store, "1.1.1.1"))
+}
+
+func (suite *RateLimitSuite) TestBurstBelowOnePerSecond() {
+	store := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: 0.5})
+	suite.True(suite.allowed(store, "1.1.1.1"))
+	suite.False(suite.allowed(store, "1.1.1.1"))
+	suite.Equal("0.5/s burst 1", store.Policy())
+}
+
+func (suite *RateLimitSuite) TestResetByKey() {
+	store := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: 0.0001, Burst: 1})
+	suite.True(suite.allowed(store, "0xabc/api/v1/swap/quote"))
+	suite.True(suite.allowed(store, "0xabc/api/v1/swap/trade"))
+	suite.True(suite.allowed(store, "0xabcd/api/v1/swap/quote"))
+	suite.True(suite.allowed(store, "0xdef/api/v1/swap/quote"))
+	suite.True(suite.allowed(store, "10.0.0.1"))
+	suite.True(suite.allowed(store, "10.0.0.12"))
+	suite.False(suite.allowed(store, "0xabc/api/v1/swap/quote"))
+
+	suite.Equal(2, mw.ResetRateLimits("0xabc"))
+	suite.True(suite.allowed(store, "0xabc/api/v1/swap/quote"))
+	suite.False(suite.allowed(store, "0xabcd/api/v1/swap/quote"), "a key is not a prefix of the other keys")
+	suite.False(suite.allowed(store, "0xdef/api/v1/swap/quote"))
+
+	suite.Equal(1, mw.ResetRateLimits("10.0.0.1"))
+	suite.True(suite.allowed(store, "10.0.0.1"))
+	suite.False(suite.allowed(store, "10.0.0.12"))
+}
+
+func (suite *RateLimitSuite) TestPolicy() {
+	store := mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: 0.0001, Burst: 20})
+	suite.Equal("0.0001/s burst 20", store.Policy())
+	store = mw.NewRateLimiterStore(middleware.RateLimiterMemoryStoreConfig{Rate: 4})
+	suite.Equal("4/s burst 4", store.Policy())
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, &RateLimitSuite{})
+}
@@ -0,0 +1,197 @@
+//go:build tests
+// +build tests
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+	"github.com/stretchr/testify/suite"
+)
+
+type pageQuickParams struct {
+	Limit  int64  `json:"limit" query:"limit"`
+	NextID string `json:"last_id" query:"last_id"`
+}
+
+type listCommentParams struct {
+	pageQuickParams
+	StatusID string `json:"status_id" query:"status_id"`
+	TopicID  string `query:"topic_id"`
+}
+
+type commentResp struct {
+	ID        string         `json:"id"`
+	Content   string         `json:"content,omitempty"`
+	Likes     uint64         `json:"likes_count"`
+	Comments  []*commentResp `json:"comments"`
+	User      *userResp      `json:"user"`
+	CreatedAt time.Time      `json:"created_at"`
+	Extra     interface{}    `json:"extra"`
+	Amount    int64          `json:"amount,string"`
+	Ignored   string         `json:"-"`
+	hidden    string
+}
+
+type userResp struct {
+	UID uint64 `json:"uid"`
+}
+
+type uploadInput struct {
+	FileType string `form:"file_type"`
+}
+
+type pathParams struct {
+	UID uint64 `param:"uid"`
+}
+
+type OpenAPISuite struct {
+	suite.Suite
+	builder *openapi.Builder
+}
+
+func (suite *OpenAPISuite) SetupTest() {
+	suite.builder = openapi.NewBuilder(openapi.Info{Title: "test", Version: "v1"})
+}
+
+func (suite *OpenAPISuite) TestSchema() {
+	gen := openapi.NewGenerator()
+	schema := gen.Schema(reflect.TypeOf([]*commentResp{}))
+	suite.Equal("array", schema.Type)
+	suite.Equal(openapi.SchemaRefPrefix+"commentResp", schema.Items.Ref)
+
+	comment := gen.Schemas()["commentResp"]
+	suite.Require().NotNil(comment)
+	suite.ElementsMatch([]string{"id", "content", "likes_count", "comments", "user", "created_at", "extra", "amount"}, keys(comment.Properties))
+	suite.Equal(openapi.SchemaRefPrefix+"commentResp", comment.Properties["comments"].Items.Ref, "a recursive type refers to itself")
+	suite.Equal(openapi.SchemaRefPrefix+"userResp", comment.Properties["user"].Ref)
+	suite.Equal("date-time", comment.Properties["created_at"].Format)
+	suite.Equal("uint64", comment.Properties["likes_count"].Format)
+	suite.Equal("string", comment.Properties["amount"].Type)
+	suite.Equal(&openapi.Schema{}, comment.Properties["extra"])
+
+	params := gen.Parameters(reflect.TypeOf(listCommentParams{}), "query", "query")
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.Name)
+		suite.False(param.Required)
+	}
+	suite.Equal([]string{"limit", "last_id", "status_id", "topic_id"}, names)
+}
+
+func (suite *OpenAPISuite) TestBuilder() {
+	suite.builder.Add(openapi.Endpoint{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/user/:uid/comment",
+		Handler:    "v1.ListComment",
+		Tags:       []string{"comment"},
+		Auth:       openapi.AuthOptional,
+		RateLimit:  []string{"10/s burst 10 per ip and path"},
+		Request:    listCommentParams{},
+		PathParams: pathParams{},
+		Response:   []*commentResp{},
+		Pagination: pageQuickParams{},
+	})
+	suite.builder.Add(openapi.Endpoint{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/upload",
+		Handler: "v1.UploadFile",
+		Auth:    openapi.AuthRequired,
+		Request: uploadInput{},
+		Files:   []string{"file"},
+		Errors:  []codes.Code{codes.ErrInvalidArgument.New("receive file failed")},
+	})
+	doc := suite.builder.Document()
+	suite.Require().NoError(openapi.Validate(doc))
+
+	list := (*doc.Paths["/api/v1/user/{uid}/comment"])["get"]
+	suite.Require().NotNil(list)
+	suite.Equal("ListComment", list.OperationID)
+	suite.Equal("uid", list.Parameters[0].Name)
+	suite.Equal("path", list.Parameters[0].In)
+	suite.True(list.Parameters[0].Required)
+	suite.Equal("integer", list.Parameters[0].Schema.Type)
+	suite.Equal([]openapi.SecurityRequirement{{openapi.BearerAuth: {}}, {}}, list.Security)
+	suite.Equal([]string{"10/s burst 10 per ip and path"}, list.RateLimit)
+	envelope := list.Responses["200"].Content["application/json"].Schema
+	suite.Equal([]string{"code", "data", "pagination"}, envelope.Required)
+	suite.Equal(openapi.SchemaRefPrefix+"pageQuickParams", envelope.Properties["pagination"].Ref)
+	suite.Contains(list.Responses, "429")
+	suite.Contains(list.Responses, "500")
+	suite.Contains(list.Responses["400"].Content["application/json"].Examples, "400003")
+
+	upload := (*doc.Paths["/api/v1/upload"])["post"]
+	suite.Require().NotNil(upload)
+	form := upload.RequestBody.Content["multipart/form-data"].Schema
+	suite.Equal("binary", form.Properties["file"].Format)
+	suite.Equal("string", form.Properties["file_type"].Type)
+	suite.Equal([]openapi.SecurityRequirement{{openapi.BearerAuth: {}}}, upload.Security)
+	suite.Equal("null", upload.Responses["200"].Content["application/json"].Schema.Properties["data"].Type)
+	suite.Contains(upload.Responses["401"].Description, "401000 unauthorized")
+	// the handler error shares the code of the binder error
+	suite.Len(upload.Responses["400"].Content["application/json"].Examples, 4)
+	suite.Equal([]openapi.Tag{{Name: "comment"}}, doc.Tags)
+}
+
+func (suite *OpenAPISuite) TestValidate() {
+	suite.builder.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/a/:id", Handler: "v1.Get"})
+	suite.builder.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/b", Handler: "v1.Get"})
+	doc := suite.builder.Document()
+	suite.NoError(openapi.Validate(doc))
+	suite.Equal("Get2", (*doc.Paths["/b"])["get"].OperationID)
+
+	(*doc.Paths["/b"])["get"].OperationID = "Get"
+	(*doc.Paths["/a/{id}"])["get"].Parameters = nil
+	doc.Components.Schemas["broken"] = &openapi.Schema{Ref: openapi.SchemaRefPrefix + "missing"}
+	err := openapi.Validate(doc)
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "schema broken: unresolved #/components/schemas/missing")
+	suite.Contains(err.Error(), "get /a/{id}: path param id is not declared")
+	suite.Contains(err.Error(), "operationId Get is used by")
+}
+
+func (suite *OpenAPISuite) TestHandlers() {
+	builds := 0
+	e := echo.New()
+	e.GET("/api/v1/openapi.json", openapi.SpecHandler(func() *openapi.Document {
+		builds++
+		return suite.builder.Document()
+	}))
+	e.GET("/api/v1/docs", openapi.DocsHandler("Mises API gateway", "/api/v1/openapi.json"))
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+		suite.Equal(http.StatusOK, rec.Code)
+		doc := &openapi.Document{}
+		suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), doc))
+		suite.Equal(openapi.Version, doc.OpenAPI)
+	}
+	suite.Equal(1, builds)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Contains(rec.Body.String(), `var specURL = "/api/v1/openapi.json";`)
+	suite.NotContains(rec.Body.String(), "<script src=", "the page is bundled")
+}
+
+func keys(m map[string]*openapi.Schema) []string {
+	names := []string{}
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestOpenAPISuite(t *testing.T) {
+	suite.Run(t, &OpenAPISuite{})
+}
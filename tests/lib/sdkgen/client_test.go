@@ -0,0 +1,184 @@
+//go:build tests
+// +build tests
+
+package sdkgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/tests/lib/sdkgen/misesapi"
+	"github.com/stretchr/testify/suite"
+)
+
+// ClientSuite calls the generated fixture client, misesapi, against a fake gateway
+type ClientSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	client   *misesapi.Client
+	handler  http.HandlerFunc
+	requests []*http.Request
+	bodies   []string
+}
+
+func (suite *ClientSuite) SetupTest() {
+	suite.requests, suite.bodies = nil, nil
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		suite.requests = append(suite.requests, r)
+		suite.bodies = append(suite.bodies, string(body))
+		suite.handler(w, r)
+	}))
+	suite.client = misesapi.NewClient(suite.server.URL + "/")
+	suite.client.RetryDelay = time.Millisecond
+	suite.client.Token = func(context.Context) (string, error) { return "token", nil }
+}
+
+func (suite *ClientSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (suite *ClientSuite) TestAuthAndParams() {
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			respond(w, http.StatusOK, map[string]interface{}{"code": 0, "data": map[string]interface{}{"id": "s1"}})
+			return
+		}
+		respond(w, http.StatusOK, map[string]interface{}{"code": 0, "data": []map[string]interface{}{{"id": "c1", "user": map[string]interface{}{"uid": 7}}}})
+	}
+	resp, err := suite.client.ListComment(context.Background(), misesapi.ListCommentParams{UID: 7, StatusID: "s1", Limit: 10})
+	suite.Require().NoError(err)
+	suite.Equal("c1", resp.Data[0].ID)
+	suite.Equal(uint64(7), resp.Data[0].User.UID)
+	suite.Equal("/api/v1/user/7/comment", suite.requests[0].URL.Path)
+	suite.Equal("limit=10&status_id=s1", suite.requests[0].URL.RawQuery, "the zero params are not sent")
+	suite.Equal("Bearer token", suite.requests[0].Header.Get("Authorization"))
+
+	_, err = suite.client.PageWebsite(context.Background(), misesapi.PageWebsiteParams{})
+	suite.Require().NoError(err)
+	suite.Empty(suite.requests[1].Header.Get("Authorization"), "a route without auth gets no token")
+
+	_, err = suite.client.CreateStatus(context.Background(), misesapi.CreateStatusCallParams{}, &misesapi.CreateStatusParams{Content: "hi"})
+	suite.Require().NoError(err)
+	suite.Equal("application/json", suite.requests[2].Header.Get("Content-Type"))
+	suite.JSONEq(`{"content":"hi"}`, suite.bodies[2])
+}
+
+func (suite *ClientSuite) TestError() {
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		respond(w, http.StatusBadRequest, map[string]interface{}{"code": 400000, "message": "content is required",
+			"fields": []map[string]interface{}{{"field": "content", "rule": "required", "message": "content is required"}}})
+	}
+	_, err := suite.client.CreateStatus(context.Background(), misesapi.CreateStatusCallParams{}, &misesapi.CreateStatusParams{})
+	apiErr := &misesapi.Error{}
+	suite.Require().True(errors.As(err, &apiErr))
+	suite.Equal(http.StatusBadRequest, apiErr.Status)
+	suite.Equal(misesapi.ErrorCodeInvalidParams, apiErr.Code)
+	suite.Equal("invalid params", apiErr.Code.String())
+	suite.Equal([]misesapi.FieldError{{Field: "content", Rule: "required", Message: "content is required"}}, apiErr.Fields)
+	suite.Equal("req-1", apiErr.RequestID)
+	suite.Len(suite.requests, 1, "a 400 is not retried")
+}
+
+func (suite *ClientSuite) TestRetries() {
+	calls := 0
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			respond(w, http.StatusServiceUnavailable, map[string]interface{}{"code": 500000, "message": "Unknown error"})
+			return
+		}
+		respond(w, http.StatusOK, map[string]interface{}{"code": 0, "data": []interface{}{}})
+	}
+	_, err := suite.client.PageWebsite(context.Background(), misesapi.PageWebsiteParams{})
+	suite.Require().NoError(err)
+	suite.Equal(2, calls, "a GET is retried on 503")
+
+	calls = 0
+	_, err = suite.client.CreateStatus(context.Background(), misesapi.CreateStatusCallParams{}, &misesapi.CreateStatusParams{Content: "hi"})
+	suite.Error(err)
+	suite.Equal(1, calls, "a POST is not retried on 503")
+
+	calls = 0
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 3 {
+			w.Header().Set("Retry-After", "0")
+			respond(w, http.StatusTooManyRequests, map[string]interface{}{"code": 429001, "message": "too many requests"})
+			return
+		}
+		respond(w, http.StatusOK, map[string]interface{}{"code": 0, "data": map[string]interface{}{"id": "s1"}})
+	}
+	_, err = suite.client.CreateStatus(context.Background(), misesapi.CreateStatusCallParams{}, &misesapi.CreateStatusParams{Content: "hi"})
+	apiErr := &misesapi.Error{}
+	suite.Require().True(errors.As(err, &apiErr))
+	suite.Equal(misesapi.ErrorCodeTooManyRequests, apiErr.Code)
+	suite.Equal(3, calls, "a 429 is retried twice")
+	suite.Equal(`{"content":"hi"}`, suite.bodies[len(suite.bodies)-1], "the body is sent again")
+}
+
+func (suite *ClientSuite) TestEach() {
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		next := map[string]string{"": "c2", "c2": "c3", "c3": ""}[r.URL.Query().Get("last_id")]
+		respond(w, http.StatusOK, map[string]interface{}{
+			"code":       0,
+			"data":       []map[string]interface{}{{"id": "after " + r.URL.Query().Get("last_id")}},
+			"pagination": map[string]interface{}{"last_id": next, "limit": 1},
+		})
+	}
+	ids := []string{}
+	err := suite.client.ListCommentEach(context.Background(), misesapi.ListCommentParams{UID: 1}, func(comment *misesapi.CommentResp) error {
+		ids = append(ids, comment.ID)
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Equal([]string{"after ", "after c2", "after c3"}, ids)
+
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page_num"))
+		respond(w, http.StatusOK, map[string]interface{}{
+			"code":       0,
+			"data":       []map[string]interface{}{{"id": strconv.Itoa(page)}},
+			"pagination": map[string]interface{}{"page_num": page, "total_page": 2},
+		})
+	}
+	ids = []string{}
+	err = suite.client.PageWebsiteEach(context.Background(), misesapi.PageWebsiteParams{}, func(website *misesapi.WebsiteResp) error {
+		ids = append(ids, website.ID)
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Equal([]string{"1", "2"}, ids)
+}
+
+func (suite *ClientSuite) TestUpload() {
+	suite.handler = func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{"code": 0, "data": map[string]interface{}{"path": "a.png"}})
+	}
+	resp, err := suite.client.UploadFile(context.Background(), misesapi.UploadFileParams{}, &misesapi.UploadFileForm{
+		File: &misesapi.File{Name: "a.png", Data: []byte("png")}, FileType: "image",
+	})
+	suite.Require().NoError(err)
+	suite.Equal("a.png", resp.Data.Path)
+	suite.Contains(suite.requests[0].Header.Get("Content-Type"), "multipart/form-data; boundary=")
+	suite.Contains(suite.bodies[0], `name="file"; filename="a.png"`)
+	suite.Contains(suite.bodies[0], `name="file_type"`)
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, &ClientSuite{})
+}
@@ -0,0 +1,570 @@
+// Code generated by mises sdk. DO NOT EDIT.
+
+// Package misesapi is the go client of the Mises API gateway.
+//
+// Not generated as they do not answer json: ExportStatus (GET /api/v1/status/export).
+package misesapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorCode identifies the error of a failed call
+type ErrorCode int
+
+const (
+	// ErrorCodeInvalidParams is answered with http 400
+	ErrorCodeInvalidParams ErrorCode = 400000
+	// ErrorCodeInvalidAuthParams is answered with http 400
+	ErrorCodeInvalidAuthParams ErrorCode = 400001
+	// ErrorCodeInvalidAuthMethod is answered with http 400
+	ErrorCodeInvalidAuthMethod ErrorCode = 400002
+	// ErrorCodeInvalidAuthToken is answered with http 400
+	ErrorCodeInvalidAuthToken ErrorCode = 400003
+	// ErrorCodeUnauthorized is answered with http 401
+	ErrorCodeUnauthorized ErrorCode = 401000
+	// ErrorCodeAuthorizeFailed is answered with http 401
+	ErrorCodeAuthorizeFailed ErrorCode = 401001
+	// ErrorCodeForbidden is answered with http 403
+	ErrorCodeForbidden ErrorCode = 403000
+	// ErrorCodeAuthorizationExpired is answered with http 403
+	ErrorCodeAuthorizationExpired ErrorCode = 403002
+	// ErrorCodeNotFound is answered with http 404
+	ErrorCodeNotFound ErrorCode = 404000
+	// ErrorCodeRequestTimedOut is answered with http 408
+	ErrorCodeRequestTimedOut ErrorCode = 408000
+	// ErrorCodeRequestTimeout is answered with http 408
+	ErrorCodeRequestTimeout ErrorCode = 408001
+	// ErrorCodeRequestBodyTooLarge is answered with http 413
+	ErrorCodeRequestBodyTooLarge ErrorCode = 413000
+	// ErrorCodeUnsupportedMediaType is answered with http 415
+	ErrorCodeUnsupportedMediaType ErrorCode = 415000
+	// ErrorCodeUnprocessableEntity is answered with http 422
+	ErrorCodeUnprocessableEntity ErrorCode = 422000
+	// ErrorCodeUsernameHadExisted is answered with http 422
+	ErrorCodeUsernameHadExisted ErrorCode = 403001
+	// ErrorCodeUsernameDuplicate is answered with http 422
+	ErrorCodeUsernameDuplicate ErrorCode = 422001
+	// ErrorCodeTooManyRequests is answered with http 429
+	ErrorCodeTooManyRequests ErrorCode = 429001
+	// ErrorCodeUnknownError is answered with http 500
+	ErrorCodeUnknownError ErrorCode = 500000
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case ErrorCodeInvalidParams:
+		return "invalid params"
+	case ErrorCodeInvalidAuthParams:
+		return "invalid auth params"
+	case ErrorCodeInvalidAuthMethod:
+		return "invalid auth method"
+	case ErrorCodeInvalidAuthToken:
+		return "invalid auth token"
+	case ErrorCodeUnauthorized:
+		return "unauthorized"
+	case ErrorCodeAuthorizeFailed:
+		return "authorize failed"
+	case ErrorCodeForbidden:
+		return "forbidden"
+	case ErrorCodeAuthorizationExpired:
+		return "authorization expired"
+	case ErrorCodeNotFound:
+		return "not found"
+	case ErrorCodeRequestTimedOut:
+		return "request timed out"
+	case ErrorCodeRequestTimeout:
+		return "request timeout"
+	case ErrorCodeRequestBodyTooLarge:
+		return "request body too large"
+	case ErrorCodeUnsupportedMediaType:
+		return "unsupported media type"
+	case ErrorCodeUnprocessableEntity:
+		return "unprocessable entity"
+	case ErrorCodeUsernameHadExisted:
+		return "username had existed"
+	case ErrorCodeUsernameDuplicate:
+		return "username duplicate"
+	case ErrorCodeTooManyRequests:
+		return "too many requests"
+	case ErrorCodeUnknownError:
+		return "Unknown error"
+	}
+	return strconv.Itoa(int(c))
+}
+
+// FieldError is a request field that failed validation, In is where it was sent when the
+// gateway did not expect it there
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	In      string `json:"in,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is a call answered with an error code
+type Error struct {
+	// Status is the http status of the response
+	Status  int       `json:"-"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// Fields are the request fields that failed validation
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mises api: %d %s", int(e.Code), e.Message)
+}
+
+// Response is the envelope of a successful call
+type Response[T any] struct {
+	Code       ErrorCode   `json:"code"`
+	Data       T           `json:"data"`
+	RequestID  string      `json:"request_id,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Client calls the gateway, NewClient returns one with the default retries
+type Client struct {
+	// BaseURL is the scheme and host of the gateway, e.g. https://api.mises.site
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Token returns the session token of the sign in, no token is sent when it is nil or empty
+	Token func(ctx context.Context) (string, error)
+	// Retries is the number of retries of a failed call, RetryDelay the delay of the first one,
+	// doubled after each unless the response has a Retry-After. GET, HEAD, PUT and DELETE are
+	// retried on network errors, 429, 502, 503 and 504, the other methods only on 429
+	Retries    int
+	RetryDelay time.Duration
+	// Header is sent with every request
+	Header http.Header
+}
+
+// NewClient returns a client of the gateway at baseURL, retrying twice from 200ms
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Retries: 2, RetryDelay: 200 * time.Millisecond}
+}
+
+type auth int
+
+const (
+	authNone auth = iota
+	authOptional
+	authRequired
+)
+
+// encoder is a request body which is not json
+type encoder interface {
+	encode() (body []byte, contentType string, err error)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, auth auth, out interface{}) error {
+	var payload []byte
+	contentType := ""
+	switch body := body.(type) {
+	case nil:
+	case encoder:
+		var err error
+		if payload, contentType, err = body.encode(); err != nil {
+			return err
+		}
+	default:
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	token := ""
+	if auth != authNone && c.Token != nil {
+		var err error
+		if token, err = c.Token(ctx); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		for key, values := range c.Header {
+			req.Header[key] = append([]string(nil), values...)
+		}
+		req.Header.Set("Accept", "application/json")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.httpClient().Do(req)
+		if delay, ok := c.retry(ctx, method, attempt, resp, err); ok {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decode(resp, out)
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// retry returns the delay before the next attempt, false when the call is not retried
+func (c *Client) retry(ctx context.Context, method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= c.Retries || ctx.Err() != nil {
+		return 0, false
+	}
+	idempotent := method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+	switch {
+	case err != nil:
+		if !idempotent {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case idempotent && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable ||
+		resp.StatusCode == http.StatusGatewayTimeout):
+	default:
+		return 0, false
+	}
+	delay := c.RetryDelay << attempt
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	return delay, true
+}
+
+// decode reads the envelope of resp into out, or returns its *Error
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	apiErr := &Error{Status: resp.StatusCode, RequestID: resp.Header.Get("X-Request-Id")}
+	if err := json.Unmarshal(data, apiErr); err != nil || resp.StatusCode >= http.StatusBadRequest || apiErr.Code != 0 {
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	return json.Unmarshal(data, out)
+}
+
+// setQuery sets the query param key, unless value is its zero value
+func setQuery[T comparable](query url.Values, key string, value T) {
+	var zero T
+	if value != zero {
+		query.Set(key, fmt.Sprint(value))
+	}
+}
+
+func addQuery[T any](query url.Values, key string, values []T) {
+	for _, value := range values {
+		query.Add(key, fmt.Sprint(value))
+	}
+}
+
+func pathValue(value interface{}) string {
+	return url.PathEscape(fmt.Sprint(value))
+}
+
+// File is a file field of a multipart form
+type File struct {
+	Name string
+	Data []byte
+}
+
+func writeField[T comparable](form *multipart.Writer, name string, value T) error {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return form.WriteField(name, fmt.Sprint(value))
+}
+
+func writeFile(form *multipart.Writer, name string, file *File) error {
+	if file == nil {
+		return nil
+	}
+	part, err := form.CreateFormFile(name, file.Name)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(file.Data)
+	return err
+}
+
+// Pagination is the pagination of the paged responses, the last_id of the quick pages or the
+// page_num of the numbered ones
+type Pagination struct {
+	LastID    string `json:"last_id,omitempty"`
+	Limit     int64  `json:"limit,omitempty"`
+	PageNum   int64  `json:"page_num,omitempty"`
+	PageSize  int64  `json:"page_size,omitempty"`
+	TotalPage int64  `json:"total_page,omitempty"`
+}
+
+type CommentResp struct {
+	Comments  []*CommentResp `json:"comments,omitempty"`
+	Content   string         `json:"content,omitempty"`
+	CreatedAt time.Time      `json:"created_at,omitempty"`
+	ID        string         `json:"id,omitempty"`
+	User      *UserResp      `json:"user,omitempty"`
+}
+
+type CreateStatusParams struct {
+	Content  string            `json:"content,omitempty"`
+	Images   []string          `json:"images,omitempty"`
+	IsPublic bool              `json:"is_public,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+type PageParams struct {
+	PageNum   int64 `json:"page_num,omitempty"`
+	PageSize  int64 `json:"page_size,omitempty"`
+	TotalPage int64 `json:"total_page,omitempty"`
+}
+
+type PageQuickParams struct {
+	LastID string `json:"last_id,omitempty"`
+	Limit  int64  `json:"limit,omitempty"`
+}
+
+type StatusResp struct {
+	ID   string    `json:"id,omitempty"`
+	User *UserResp `json:"user,omitempty"`
+}
+
+type UserResp struct {
+	UID      uint64 `json:"uid,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type WebsiteResp struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// CreateStatusCallParams are the path and query params of CreateStatus
+type CreateStatusCallParams struct {
+	// comma separated fields of data to return
+	Fields string
+	// comma separated nested objects of data to return
+	Expand string
+}
+
+// CreateStatus calls POST /api/v1/status: it needs the token
+func (c *Client) CreateStatus(ctx context.Context, params CreateStatusCallParams, body *CreateStatusParams) (*Response[*StatusResp], error) {
+	query := url.Values{}
+	setQuery(query, "fields", params.Fields)
+	setQuery(query, "expand", params.Expand)
+	var payload interface{}
+	if body != nil {
+		payload = body
+	}
+	resp := &Response[*StatusResp]{}
+	if err := c.do(ctx, "POST", "/api/v1/status", query, payload, authRequired, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteStatusParams are the path and query params of DeleteStatus
+type DeleteStatusParams struct {
+	ID string
+	// comma separated fields of data to return
+	Fields string
+	// comma separated nested objects of data to return
+	Expand string
+}
+
+// DeleteStatus calls DELETE /api/v1/status/{id}: it needs the token
+func (c *Client) DeleteStatus(ctx context.Context, params DeleteStatusParams) (*Response[interface{}], error) {
+	query := url.Values{}
+	setQuery(query, "fields", params.Fields)
+	setQuery(query, "expand", params.Expand)
+	resp := &Response[interface{}]{}
+	if err := c.do(ctx, "DELETE", "/api/v1/status/"+pathValue(params.ID), query, nil, authRequired, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListCommentParams are the path and query params of ListComment
+type ListCommentParams struct {
+	UID      uint64
+	Limit    int64
+	LastID   string
+	StatusID string
+	// comma separated fields of data to return
+	Fields string
+	// comma separated nested objects of data to return
+	Expand string
+}
+
+// ListComment calls GET /api/v1/user/{uid}/comment: Comments of a user, it sends the token when there is one
+func (c *Client) ListComment(ctx context.Context, params ListCommentParams) (*Response[[]*CommentResp], error) {
+	query := url.Values{}
+	setQuery(query, "limit", params.Limit)
+	setQuery(query, "last_id", params.LastID)
+	setQuery(query, "status_id", params.StatusID)
+	setQuery(query, "fields", params.Fields)
+	setQuery(query, "expand", params.Expand)
+	resp := &Response[[]*CommentResp]{}
+	if err := c.do(ctx, "GET", "/api/v1/user/"+pathValue(params.UID)+"/comment", query, nil, authOptional, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListCommentEach calls fn with the items of ListComment, page after page from the page of params
+func (c *Client) ListCommentEach(ctx context.Context, params ListCommentParams, fn func(*CommentResp) error) error {
+	for {
+		resp, err := c.ListComment(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, item := range resp.Data {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(resp.Data) == 0 || resp.Pagination == nil || resp.Pagination.LastID == "" || resp.Pagination.LastID == params.LastID {
+			return nil
+		}
+		params.LastID = resp.Pagination.LastID
+	}
+}
+
+// PageWebsiteParams are the path and query params of PageWebsite
+type PageWebsiteParams struct {
+	PageNum  int64
+	PageSize int64
+	Keywords string
+	// comma separated fields of data to return
+	Fields string
+	// comma separated nested objects of data to return
+	Expand string
+}
+
+// PageWebsite calls GET /api/v1/website/page
+func (c *Client) PageWebsite(ctx context.Context, params PageWebsiteParams) (*Response[[]*WebsiteResp], error) {
+	query := url.Values{}
+	setQuery(query, "page_num", params.PageNum)
+	setQuery(query, "page_size", params.PageSize)
+	setQuery(query, "keywords", params.Keywords)
+	setQuery(query, "fields", params.Fields)
+	setQuery(query, "expand", params.Expand)
+	resp := &Response[[]*WebsiteResp]{}
+	if err := c.do(ctx, "GET", "/api/v1/website/page", query, nil, authNone, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PageWebsiteEach calls fn with the items of PageWebsite, page after page from the page of params
+func (c *Client) PageWebsiteEach(ctx context.Context, params PageWebsiteParams, fn func(*WebsiteResp) error) error {
+	if params.PageNum < 1 {
+		params.PageNum = 1
+	}
+	for {
+		resp, err := c.PageWebsite(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, item := range resp.Data {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(resp.Data) == 0 || resp.Pagination == nil || int64(params.PageNum) >= int64(resp.Pagination.TotalPage) {
+			return nil
+		}
+		params.PageNum++
+	}
+}
+
+// UploadFileParams are the path and query params of UploadFile
+type UploadFileParams struct {
+	// comma separated fields of data to return
+	Fields string
+	// comma separated nested objects of data to return
+	Expand string
+}
+
+// UploadFileForm is the multipart form of UploadFile
+type UploadFileForm struct {
+	File     *File
+	FileType string
+}
+
+func (f *UploadFileForm) encode() ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	form := multipart.NewWriter(buf)
+	if err := writeFile(form, "file", f.File); err != nil {
+		return nil, "", err
+	}
+	if err := writeField(form, "file_type", f.FileType); err != nil {
+		return nil, "", err
+	}
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), form.FormDataContentType(), nil
+}
+
+// UploadFileData is the data of UploadFile
+type UploadFileData struct {
+	Path string `json:"path,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// UploadFile calls POST /api/v1/upload: it needs the token
+func (c *Client) UploadFile(ctx context.Context, params UploadFileParams, body *UploadFileForm) (*Response[*UploadFileData], error) {
+	query := url.Values{}
+	setQuery(query, "fields", params.Fields)
+	setQuery(query, "expand", params.Expand)
+	var payload interface{}
+	if body != nil {
+		payload = body
+	}
+	resp := &Response[*UploadFileData]{}
+	if err := c.do(ctx, "POST", "/api/v1/upload", query, payload, authRequired, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,210 @@
+//go:build tests
+// +build tests
+
+package sdkgen
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	"github.com/mises-id/sns-apigateway/lib/openapi"
+	"github.com/mises-id/sns-apigateway/lib/sdkgen"
+	"github.com/stretchr/testify/suite"
+)
+
+// update rewrites the golden files, go test -tags tests ./tests/lib/sdkgen/ -update
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// golden are the files of the fixture clients: the go one is a package the client tests call
+var golden = map[string]string{
+	sdkgen.GoFile:         "misesapi/client.go",
+	sdkgen.TypeScriptFile: "testdata/index.ts",
+}
+
+type PageQuickParams struct {
+	Limit  int64  `json:"limit" query:"limit"`
+	NextID string `json:"last_id" query:"last_id"`
+}
+
+type PageParams struct {
+	PageNum   int64 `json:"page_num" query:"page_num"`
+	PageSize  int64 `json:"page_size" query:"page_size"`
+	TotalPage int64 `json:"total_page"`
+}
+
+type ListCommentParams struct {
+	PageQuickParams
+	StatusID string `query:"status_id"`
+}
+
+type CommentResp struct {
+	ID        string         `json:"id"`
+	Content   string         `json:"content"`
+	Comments  []*CommentResp `json:"comments"`
+	User      *UserResp      `json:"user"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type UserResp struct {
+	UID      uint64 `json:"uid"`
+	Username string `json:"username"`
+}
+
+type WebsiteParams struct {
+	PageParams
+	Keywords string `query:"keywords"`
+}
+
+type WebsiteResp struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type CreateStatusParams struct {
+	Content  string            `json:"content"`
+	Images   []string          `json:"images"`
+	Meta     map[string]string `json:"meta"`
+	IsPublic bool              `json:"is_public"`
+}
+
+type StatusResp struct {
+	ID   string    `json:"id"`
+	User *UserResp `json:"user"`
+}
+
+type UploadInput struct {
+	FileType string `form:"file_type"`
+}
+
+type pathParams struct {
+	UID uint64 `param:"uid"`
+}
+
+type SdkgenSuite struct {
+	suite.Suite
+	files map[string][]byte
+}
+
+// fixture documents a route of every kind the clients handle
+func fixture() *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{Title: "Mises API gateway", Version: "v1"})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodGet, Path: "/api/v1/user/:uid/comment", Handler: "v1.ListComment", Summary: "Comments of a user",
+		Auth: openapi.AuthOptional, Request: ListCommentParams{}, PathParams: pathParams{},
+		Response: []*CommentResp{}, Pagination: PageQuickParams{},
+	})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodGet, Path: "/api/v1/website/page", Handler: "v1.PageWebsite",
+		Request: WebsiteParams{}, Response: []*WebsiteResp{}, Pagination: PageParams{},
+	})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodPost, Path: "/api/v1/status", Handler: "v1.CreateStatus", Auth: openapi.AuthRequired,
+		Request: CreateStatusParams{}, Response: StatusResp{}, Errors: []codes.Code{codes.ErrForbidden},
+	})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodDelete, Path: "/api/v1/status/:id", Handler: "v1.DeleteStatus", Auth: openapi.AuthRequired,
+	})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodPost, Path: "/api/v1/upload", Handler: "v1.UploadFile", Auth: openapi.AuthRequired,
+		Request: UploadInput{}, Files: []string{"file"}, Response: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+			"path": {Type: "string"}, "url": {Type: "string"},
+		}},
+	})
+	b.Add(openapi.Endpoint{
+		Method: http.MethodGet, Path: "/api/v1/status/export", Handler: "v1.ExportStatus", Export: StatusResp{},
+	})
+	return b.Document()
+}
+
+func (suite *SdkgenSuite) SetupSuite() {
+	files, err := sdkgen.Generate(fixture())
+	suite.Require().NoError(err)
+	suite.files = files
+	if *update {
+		for name, path := range golden {
+			suite.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o755))
+			suite.Require().NoError(os.WriteFile(path, files[name], 0o644))
+		}
+	}
+}
+
+func (suite *SdkgenSuite) TestGolden() {
+	suite.Len(suite.files, len(golden))
+	for name, path := range golden {
+		expected, err := os.ReadFile(path)
+		suite.Require().NoError(err)
+		suite.Equal(string(expected), string(suite.files[name]), "%s changed, review it and run the tests with -update", name)
+	}
+}
+
+func (suite *SdkgenSuite) TestDeterministic() {
+	for i := 0; i < 3; i++ {
+		files, err := sdkgen.Generate(fixture())
+		suite.Require().NoError(err)
+		suite.Equal(suite.files, files)
+	}
+}
+
+func (suite *SdkgenSuite) TestGoClientTypeChecks() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client.go", suite.files[sdkgen.GoFile], parser.ParseComments)
+	suite.Require().NoError(err)
+	suite.Equal("misesapi", file.Name.Name)
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("misesapi", fset, []*ast.File{file}, nil)
+	suite.Require().NoError(err)
+
+	for _, name := range []string{"Client", "ListCommentParams", "CreateStatusCallParams", "UploadFileForm", "ErrorCodeInvalidParams", "ErrorCodeTooManyRequests"} {
+		suite.NotNil(pkg.Scope().Lookup(name), name)
+	}
+	client := types.NewPointer(pkg.Scope().Lookup("Client").Type())
+	methods := types.NewMethodSet(client)
+	for _, name := range []string{"ListComment", "ListCommentEach", "PageWebsite", "PageWebsiteEach", "CreateStatus", "DeleteStatus", "UploadFile"} {
+		suite.NotNil(methods.Lookup(pkg, name), name)
+	}
+	suite.Nil(methods.Lookup(pkg, "ExportStatus"), "an export does not answer the envelope")
+	suite.Nil(methods.Lookup(pkg, "CreateStatusEach"))
+}
+
+func (suite *SdkgenSuite) TestTypeScriptClient() {
+	ts := string(suite.files[sdkgen.TypeScriptFile])
+	suite.Contains(ts, "  InvalidParams = 400000,")
+	suite.Contains(ts, "export interface CommentResp {")
+	suite.Contains(ts, "  created_at?: string;")
+	suite.Contains(ts, "  listComment(params: ListCommentParams): Promise<Envelope<CommentResp[]>> {")
+	suite.Contains(ts, "return this.request(\"GET\", `/api/v1/user/${pathValue(params.uid)}/comment`, true, omit(params, \"uid\"));")
+	suite.Contains(ts, "  async *listCommentAll(params: ListCommentParams): AsyncGenerator<CommentResp> {")
+	suite.Contains(ts, "  async *pageWebsiteAll(params: PageWebsiteParams = {}): AsyncGenerator<WebsiteResp> {")
+	suite.Contains(ts, "  createStatus(body: CreateStatusParams, params: CreateStatusCallParams = {}): Promise<Envelope<StatusResp>> {")
+	suite.Contains(ts, "  uploadFile(body: FormData, params: UploadFileParams = {}): Promise<Envelope<UploadFileData>> {")
+	suite.Contains(ts, "Not generated as they do not answer json: ExportStatus (GET /api/v1/status/export).")
+	suite.NotContains(ts, "export interface Error ")
+}
+
+func (suite *SdkgenSuite) TestCheck() {
+	dir := suite.T().TempDir()
+	suite.Error(sdkgen.Check(dir, suite.files))
+	suite.Require().NoError(sdkgen.Write(dir, suite.files))
+	suite.NoError(sdkgen.Check(dir, suite.files))
+
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "typescript", "index.ts"), []byte("// edited"), 0o644))
+	err := sdkgen.Check(dir, suite.files)
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "typescript/index.ts out of date")
+	suite.NotContains(err.Error(), "go/client.go")
+}
+
+func TestSdkgenSuite(t *testing.T) {
+	suite.Run(t, &SdkgenSuite{})
+}
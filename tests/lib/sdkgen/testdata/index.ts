@@ -0,0 +1,346 @@
+// Code generated by mises sdk. DO NOT EDIT.
+//
+// Client of the Mises API gateway.
+// Not generated as they do not answer json: ExportStatus (GET /api/v1/status/export).
+
+/** Codes of the errors of the gateway, the code of an ApiError */
+export enum ErrorCode {
+  /** invalid params, http 400 */
+  InvalidParams = 400000,
+  /** invalid auth params, http 400 */
+  InvalidAuthParams = 400001,
+  /** invalid auth method, http 400 */
+  InvalidAuthMethod = 400002,
+  /** invalid auth token, http 400 */
+  InvalidAuthToken = 400003,
+  /** unauthorized, http 401 */
+  Unauthorized = 401000,
+  /** authorize failed, http 401 */
+  AuthorizeFailed = 401001,
+  /** forbidden, http 403 */
+  Forbidden = 403000,
+  /** authorization expired, http 403 */
+  AuthorizationExpired = 403002,
+  /** not found, http 404 */
+  NotFound = 404000,
+  /** request timed out, http 408 */
+  RequestTimedOut = 408000,
+  /** request timeout, http 408 */
+  RequestTimeout = 408001,
+  /** request body too large, http 413 */
+  RequestBodyTooLarge = 413000,
+  /** unsupported media type, http 415 */
+  UnsupportedMediaType = 415000,
+  /** unprocessable entity, http 422 */
+  UnprocessableEntity = 422000,
+  /** username had existed, http 422 */
+  UsernameHadExisted = 403001,
+  /** username duplicate, http 422 */
+  UsernameDuplicate = 422001,
+  /** too many requests, http 429 */
+  TooManyRequests = 429001,
+  /** Unknown error, http 500 */
+  UnknownError = 500000,
+}
+
+/** Request field that failed validation, in is where it was sent when the gateway did not expect it there */
+export interface FieldError {
+  field: string;
+  rule: string;
+  in?: "query" | "body" | "path";
+  message: string;
+}
+
+/** Call answered with an error code */
+export class ApiError extends Error {
+  constructor(
+    /** http status of the response */
+    readonly status: number,
+    readonly code: ErrorCode | number,
+    message: string,
+    /** request fields that failed validation */
+    readonly fields: FieldError[] = [],
+    readonly requestId?: string,
+  ) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+export interface ClientOptions {
+  /** scheme and host of the gateway, e.g. https://api.mises.site */
+  baseURL: string;
+  /** session token of the sign in, or a function returning it, no token is sent when it is empty */
+  token?: string | (() => string | undefined | Promise<string | undefined>);
+  /**
+   * Retries of a failed call, 2 by default. GET, HEAD, PUT and DELETE are retried on network errors,
+   * 429, 502, 503 and 504, the other methods only on 429.
+   */
+  retries?: number;
+  /** delay of the first retry, doubled after each unless the response has a Retry-After, 200 by default */
+  retryDelayMs?: number;
+  /** headers sent with every request */
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+
+/** Pagination of the paged responses, the last_id of the quick pages or the page_num of the numbered ones */
+export interface Pagination {
+  last_id?: string;
+  limit?: number;
+  page_num?: number;
+  page_size?: number;
+  total_page?: number;
+}
+
+/** Envelope of a successful call */
+export interface Envelope<T> {
+  code: number;
+  data: T;
+  request_id?: string;
+  pagination?: Pagination;
+}
+
+export interface CommentResp {
+  comments?: CommentResp[];
+  content?: string;
+  created_at?: string;
+  id?: string;
+  user?: UserResp;
+}
+
+export interface CreateStatusParams {
+  content?: string;
+  images?: string[];
+  is_public?: boolean;
+  meta?: Record<string, string>;
+}
+
+export interface PageParams {
+  page_num?: number;
+  page_size?: number;
+  total_page?: number;
+}
+
+export interface PageQuickParams {
+  last_id?: string;
+  limit?: number;
+}
+
+export interface StatusResp {
+  id?: string;
+  user?: UserResp;
+}
+
+export interface UserResp {
+  uid?: number;
+  username?: string;
+}
+
+export interface WebsiteResp {
+  id?: string;
+  title?: string;
+  url?: string;
+}
+
+/** Path and query params of createStatus */
+export interface CreateStatusCallParams {
+  /** comma separated fields of data to return */
+  fields?: string;
+  /** comma separated nested objects of data to return */
+  expand?: string;
+}
+
+/** Path and query params of deleteStatus */
+export interface DeleteStatusParams {
+  id: string;
+  /** comma separated fields of data to return */
+  fields?: string;
+  /** comma separated nested objects of data to return */
+  expand?: string;
+}
+
+/** Path and query params of listComment */
+export interface ListCommentParams {
+  uid: number;
+  limit?: number;
+  last_id?: string;
+  status_id?: string;
+  /** comma separated fields of data to return */
+  fields?: string;
+  /** comma separated nested objects of data to return */
+  expand?: string;
+}
+
+/** Path and query params of pageWebsite */
+export interface PageWebsiteParams {
+  page_num?: number;
+  page_size?: number;
+  keywords?: string;
+  /** comma separated fields of data to return */
+  fields?: string;
+  /** comma separated nested objects of data to return */
+  expand?: string;
+}
+
+/** Path and query params of uploadFile */
+export interface UploadFileParams {
+  /** comma separated fields of data to return */
+  fields?: string;
+  /** comma separated nested objects of data to return */
+  expand?: string;
+}
+
+/** Data of uploadFile */
+export interface UploadFileData {
+  path?: string;
+  url?: string;
+}
+
+type Query = Record<string, unknown>;
+
+const idempotent = ["GET", "HEAD", "PUT", "DELETE"];
+const retriedStatuses = [502, 503, 504];
+
+function sleep(ms: number): Promise<void> {
+  return new Promise((resolve) => setTimeout(resolve, ms));
+}
+
+function pathValue(value: unknown): string {
+  return encodeURIComponent(String(value));
+}
+
+function omit(params: object, ...keys: string[]): Query {
+  const query: Query = { ...params };
+  for (const key of keys) delete query[key];
+  return query;
+}
+
+export class Client {
+  private readonly options: ClientOptions;
+  private readonly fetcher: typeof fetch;
+
+  constructor(options: ClientOptions) {
+    this.options = { ...options, baseURL: options.baseURL.replace(/\/+$/, "") };
+    this.fetcher = options.fetch ?? ((input, init) => fetch(input, init));
+  }
+
+  private async token(): Promise<string | undefined> {
+    const token = this.options.token;
+    return typeof token === "function" ? await token() : token;
+  }
+
+  /** delay before the next attempt, undefined when the call is not retried */
+  private retryDelay(method: string, attempt: number, response?: Response): number | undefined {
+    if (attempt >= (this.options.retries ?? 2)) return undefined;
+    const retried = response
+      ? response.status === 429 || (idempotent.includes(method) && retriedStatuses.includes(response.status))
+      : idempotent.includes(method);
+    if (!retried) return undefined;
+    const retryAfter = Number(response?.headers.get("Retry-After") ?? NaN);
+    if (retryAfter >= 0) return retryAfter * 1000;
+    return (this.options.retryDelayMs ?? 200) * 2 ** attempt;
+  }
+
+  private async request<T>(method: string, path: string, auth: boolean, query: Query, body?: unknown): Promise<Envelope<T>> {
+    const url = new URL(this.options.baseURL + path);
+    for (const [key, value] of Object.entries(query)) {
+      if (value === undefined || value === null || value === "") continue;
+      if (Array.isArray(value)) value.forEach((item) => url.searchParams.append(key, String(item)));
+      else url.searchParams.set(key, String(value));
+    }
+    const headers: Record<string, string> = { ...this.options.headers, Accept: "application/json" };
+    if (auth) {
+      const token = await this.token();
+      if (token) headers.Authorization = `Bearer ${token}`;
+    }
+    let payload: FormData | string | undefined;
+    if (body instanceof FormData) {
+      payload = body;
+    } else if (body !== undefined) {
+      payload = JSON.stringify(body);
+      headers["Content-Type"] = "application/json";
+    }
+
+    for (let attempt = 0; ; attempt++) {
+      let response: Response;
+      try {
+        response = await this.fetcher(url.toString(), { method, headers, body: payload });
+      } catch (err) {
+        const delay = this.retryDelay(method, attempt);
+        if (delay === undefined) throw err;
+        await sleep(delay);
+        continue;
+      }
+      const delay = this.retryDelay(method, attempt, response);
+      if (delay !== undefined) {
+        await sleep(delay);
+        continue;
+      }
+      const text = await response.text();
+      let envelope: any;
+      try {
+        envelope = text ? JSON.parse(text) : undefined;
+      } catch {
+        envelope = undefined;
+      }
+      if (!response.ok || !envelope || envelope.code !== 0) {
+        throw new ApiError(
+          response.status,
+          envelope?.code ?? 0,
+          envelope?.message ?? response.statusText,
+          envelope?.fields ?? [],
+          response.headers.get("X-Request-Id") ?? undefined,
+        );
+      }
+      return envelope as Envelope<T>;
+    }
+  }
+
+  /** POST /api/v1/status, needs the token */
+  createStatus(body: CreateStatusParams, params: CreateStatusCallParams = {}): Promise<Envelope<StatusResp>> {
+    return this.request("POST", `/api/v1/status`, true, params, body);
+  }
+
+  /** DELETE /api/v1/status/{id}, needs the token */
+  deleteStatus(params: DeleteStatusParams): Promise<Envelope<null>> {
+    return this.request("DELETE", `/api/v1/status/${pathValue(params.id)}`, true, omit(params, "id"));
+  }
+
+  /** GET /api/v1/user/{uid}/comment, Comments of a user, sends the token when there is one */
+  listComment(params: ListCommentParams): Promise<Envelope<CommentResp[]>> {
+    return this.request("GET", `/api/v1/user/${pathValue(params.uid)}/comment`, true, omit(params, "uid"));
+  }
+
+  /** Every item of listComment, page after page from the page of params */
+  async *listCommentAll(params: ListCommentParams): AsyncGenerator<CommentResp> {
+    for (let page = { ...params }; ; ) {
+      const response = await this.listComment(page);
+      const items = response.data ?? [];
+      yield* items;
+      const next = response.pagination?.last_id;
+      if (!items.length || !next || next === page.last_id) return;
+      page = { ...page, last_id: next };
+    }
+  }
+
+  /** GET /api/v1/website/page */
+  pageWebsite(params: PageWebsiteParams = {}): Promise<Envelope<WebsiteResp[]>> {
+    return this.request("GET", `/api/v1/website/page`, false, params);
+  }
+
+  /** Every item of pageWebsite, page after page from the page of params */
+  async *pageWebsiteAll(params: PageWebsiteParams = {}): AsyncGenerator<WebsiteResp> {
+    for (let page = { ...params, page_num: params.page_num || 1 }; ; page = { ...page, page_num: page.page_num + 1 }) {
+      const response = await this.pageWebsite(page);
+      const items = response.data ?? [];
+      yield* items;
+      if (!items.length || page.page_num >= (response.pagination?.total_page ?? 0)) return;
+    }
+  }
+
+  /** POST /api/v1/upload, needs the token */
+  uploadFile(body: FormData, params: UploadFileParams = {}): Promise<Envelope<UploadFileData>> {
+    return this.request("POST", `/api/v1/upload`, true, params, body);
+  }
+}
@@ -0,0 +1,131 @@
+//go:build tests
+// +build tests
+
+package slowlog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/slowlog"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+)
+
+type SlowlogSuite struct {
+	suite.Suite
+	tracker *slowlog.Tracker
+	e       *echo.Echo
+	sleep   time.Duration
+}
+
+func (suite *SlowlogSuite) SetupTest() {
+	suite.tracker = slowlog.NewTracker(128, 10)
+	suite.e = echo.New()
+	suite.e.Binder = slowlog.Binder{Binder: suite.e.Binder}
+	suite.e.Use(suite.tracker.Middleware())
+	interceptor := slowlog.UnaryClientInterceptor("swapsvc")
+	suite.e.GET("/api/v1/swap/quote", func(c echo.Context) error {
+		params := &struct {
+			ChainID uint64 `query:"chain_id"`
+		}{}
+		if err := c.Bind(params); err != nil {
+			return err
+		}
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			time.Sleep(suite.sleep)
+			return nil
+		}
+		if err := interceptor(c.Request().Context(), "/swapsvc.Swapsvc/SwapQuote", nil, nil, nil, invoker); err != nil {
+			return err
+		}
+		return wire.Render(c, http.StatusOK, echo.Map{"code": 0, "data": params.ChainID})
+	})
+}
+
+func (suite *SlowlogSuite) get(sleep time.Duration) {
+	suite.sleep = sleep
+	suite.e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/swap/quote?chain_id=1&sig=secret", nil))
+}
+
+func (suite *SlowlogSuite) TestThreshold() {
+	suite.tracker.SetConfig(slowlog.Config{Threshold: 20 * time.Millisecond})
+	suite.get(time.Millisecond)
+	suite.Empty(suite.tracker.Events())
+
+	suite.get(30 * time.Millisecond)
+	events := suite.tracker.Events()
+	suite.Require().Len(events, 1)
+	event := events[0]
+	suite.Equal(slowlog.ReasonThreshold, event.Reason)
+	suite.Equal("/api/v1/swap/quote", event.Route)
+	suite.Equal("/api/v1/swap/quote?chain_id=1&sig=[redacted]", event.URI)
+	names := []string{}
+	for _, phase := range event.Phases {
+		names = append(names, phase.Name)
+	}
+	suite.Equal([]string{"bind", "swapsvc/swapsvc.Swapsvc/SwapQuote", "serialize"}, names)
+	suite.GreaterOrEqual(event.Phases[1].Millis, float64(30))
+	suite.Less(event.OtherMillis, event.Millis)
+}
+
+func (suite *SlowlogSuite) TestP95Multiple() {
+	suite.tracker.SetConfig(slowlog.Config{P95Multiple: 3, MinLatency: 20 * time.Millisecond})
+	for i := 0; i < 60; i++ {
+		suite.get(0)
+	}
+	suite.Empty(suite.tracker.Events())
+
+	suite.get(30 * time.Millisecond)
+	events := suite.tracker.Events()
+	suite.Require().Len(events, 1)
+	suite.Equal(slowlog.ReasonP95, events[0].Reason)
+	suite.Greater(events[0].P95Millis, float64(0))
+
+	stats := suite.tracker.Stats()
+	suite.Require().Len(stats, 1)
+	suite.Equal(61, stats[0].Samples)
+	suite.True(strings.HasPrefix(stats[0].Route, "/api/v1/swap"))
+}
+
+func (suite *SlowlogSuite) TestEventsKept() {
+	suite.tracker.SetConfig(slowlog.Config{Threshold: time.Nanosecond})
+	for i := 0; i < 12; i++ {
+		suite.get(0)
+	}
+	suite.Len(suite.tracker.Events(), 10)
+}
+
+func (suite *SlowlogSuite) TestUnmatchedNotTracked() {
+	suite.tracker.SetConfig(slowlog.Config{Threshold: time.Nanosecond})
+	for _, path := range []string{"/random1", "/random2", "/api/v1/swap/unknown"} {
+		suite.e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	suite.Empty(suite.tracker.Stats())
+	suite.Empty(suite.tracker.Events())
+	suite.get(0)
+	suite.Len(suite.tracker.Stats(), 1)
+}
+
+func (suite *SlowlogSuite) TestGroupFallbackTrackedOnce() {
+	suite.e.Group("/api/v1/user", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return next
+	})
+	suite.tracker.SetConfig(slowlog.Config{Threshold: time.Nanosecond})
+	for _, path := range []string{"/api/v1/user/random1", "/api/v1/user/random2"} {
+		suite.e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	stats := suite.tracker.Stats()
+	suite.Require().Len(stats, 1)
+	suite.Equal("/api/v1/user/*", stats[0].Route)
+}
+
+func TestSlowlogSuite(t *testing.T) {
+	suite.Run(t, &SlowlogSuite{})
+}
@@ -0,0 +1,106 @@
+//go:build tests
+// +build tests
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/tracing"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type TracingSuite struct {
+	suite.Suite
+	exporter *tracetest.InMemoryExporter
+	e        *echo.Echo
+	outgoing metadata.MD
+}
+
+func (suite *TracingSuite) SetupTest() {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	suite.Require().NoError(err)
+	suite.exporter = tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(tracing.NewProvider(tracing.Config{SampleRatio: 1, ServiceName: "test"}, sdktrace.WithSyncer(suite.exporter)))
+
+	interceptor := tracing.UnaryClientInterceptor("swapsvc")
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		suite.outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	suite.e = echo.New()
+	suite.e.Use(tracing.Middleware())
+	suite.e.GET("/api/v1/swap/:chain_id/quote", func(c echo.Context) error {
+		c.Set("CurrentUID", uint64(1001))
+		if err := interceptor(c.Request().Context(), "/swapsvc.Swapsvc/SwapQuote", nil, nil, nil, invoker); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	suite.e.GET("/api/v1/fail", func(c echo.Context) error {
+		return codes.ErrInternal
+	}, mw.ErrorResponseMiddleware)
+}
+
+func (suite *TracingSuite) spans() map[trace.SpanKind]tracetest.SpanStub {
+	spans := map[trace.SpanKind]tracetest.SpanStub{}
+	for _, span := range suite.exporter.GetSpans() {
+		spans[span.SpanKind] = span
+	}
+	return spans
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func (suite *TracingSuite) TestServerAndBackendSpans() {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/swap/56/quote", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	suite.e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := suite.spans()
+	server, client := spans[trace.SpanKindServer], spans[trace.SpanKindClient]
+	suite.Equal("GET /api/v1/swap/:chain_id/quote", server.Name)
+	suite.Equal("4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	suite.Equal("00f067aa0ba902b7", server.Parent.SpanID().String())
+
+	serverAttributes := attributes(server)
+	suite.Equal("/api/v1/swap/:chain_id/quote", serverAttributes["http.route"].AsString())
+	suite.Equal("56", serverAttributes["chain_id"].AsString())
+	suite.Equal(tracing.HashUID(1001), serverAttributes["enduser.id_hash"].AsString())
+	suite.Equal(int64(http.StatusOK), serverAttributes["http.status_code"].AsInt64())
+
+	suite.Equal("/swapsvc.Swapsvc/SwapQuote", client.Name)
+	suite.Equal(server.SpanContext.SpanID(), client.Parent.SpanID())
+	suite.Equal("swapsvc", attributes(client)["backend"].AsString())
+	suite.Equal([]string{"00-4bf92f3577b34da6a3ce929d0e0e4736-" + client.SpanContext.SpanID().String() + "-01"}, suite.outgoing.Get("traceparent"))
+}
+
+func (suite *TracingSuite) TestErrorStatus() {
+	suite.e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/fail", nil))
+	server := suite.spans()[trace.SpanKindServer]
+	suite.Equal(int64(http.StatusInternalServerError), attributes(server)["http.status_code"].AsInt64())
+	suite.Equal("Error", server.Status.Code.String())
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, &TracingSuite{})
+}
@@ -0,0 +1,174 @@
+//go:build tests
+// +build tests
+
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/codes"
+	mw "github.com/mises-id/sns-apigateway/lib/middleware"
+	"github.com/mises-id/sns-apigateway/lib/validation"
+	"github.com/stretchr/testify/suite"
+)
+
+type pageParams struct {
+	PageNum  int64 `json:"page_num" query:"page_num" validate:"gte=0"`
+	PageSize int64 `json:"page_size" query:"page_size" validate:"page_size"`
+}
+
+type publicRequest struct {
+	ChainID uint64 `json:"chain_id" query:"chain_id" validate:"omitempty,chain_id"`
+}
+
+type tradeRequest struct {
+	publicRequest
+	Amount       string   `json:"amount" query:"amount" validate:"required,decimal"`
+	FromAddress  string   `json:"from_address" query:"from_address" validate:"required,evm_address"`
+	DestReceiver string   `json:"dest_receiver" query:"dest_receiver" validate:"omitempty,evm_address"`
+	Slippage     float32  `json:"slippage" query:"slippage" validate:"gte=0,lte=50"`
+	Wallets      []string `validate:"max=2,dive,evm_address"`
+}
+
+type channelRequest struct {
+	pageParams
+	Misesid string `json:"misesid" query:"misesid" validate:"omitempty,misesid"`
+}
+
+type profile struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type updateRequest struct {
+	By      string   `json:"by" validate:"required,oneof=profile avatar"`
+	Profile *profile `json:"profile"`
+}
+
+const address = "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"
+
+type ValidationSuite struct {
+	suite.Suite
+	v *validation.Validator
+}
+
+func (suite *ValidationSuite) SetupTest() {
+	suite.v = validation.New()
+	suite.v.RegisterStructValidation(func(sl validator.StructLevel) {
+		params := sl.Current().Interface().(updateRequest)
+		if params.By == "profile" && params.Profile == nil {
+			sl.ReportError(params.Profile, "profile", "Profile", "required_if", "by is profile")
+		}
+	}, updateRequest{})
+}
+
+func (suite *ValidationSuite) fields(i interface{}) map[string]codes.FieldError {
+	err := suite.v.Validate(i)
+	if err == nil {
+		return nil
+	}
+	fieldsErr, ok := err.(codes.FieldsError)
+	suite.Require().True(ok, err.Error())
+	suite.Equal(codes.InvalidArgumentCode, fieldsErr.Code.Code)
+	fields := map[string]codes.FieldError{}
+	for _, field := range fieldsErr.Fields {
+		fields[field.Field] = field
+	}
+	return fields
+}
+
+func (suite *ValidationSuite) TestValid() {
+	suite.NoError(suite.v.Validate(&tradeRequest{
+		publicRequest: publicRequest{ChainID: 56},
+		Amount:        "1000000000000000000",
+		FromAddress:   address,
+		Slippage:      0.5,
+		Wallets:       []string{address},
+	}))
+	suite.NoError(suite.v.Validate(&channelRequest{Misesid: "did:mises:mises1y53kz80x5gm2w0ype8x7a3w6sstztxxg7qkl5n"}))
+	suite.NoError(suite.v.Validate(&channelRequest{pageParams: pageParams{PageSize: validation.MaxPageSize}}))
+}
+
+func (suite *ValidationSuite) TestRules() {
+	fields := suite.fields(&tradeRequest{
+		publicRequest: publicRequest{ChainID: validation.MaxChainID + 1},
+		Amount:        "-1",
+		FromAddress:   "0x7a250d",
+		DestReceiver:  "mises1y53kz80x5gm2w0ype8x7a3w6sstztxxg7qkl5n",
+		Slippage:      -1,
+		Wallets:       []string{address, "0x01"},
+	})
+	suite.Equal("chain_id", fields["chain_id"].Rule, "the embedded struct is not part of the path")
+	suite.Equal("decimal", fields["amount"].Rule)
+	suite.Equal("evm_address", fields["from_address"].Rule)
+	suite.Equal("evm_address", fields["dest_receiver"].Rule)
+	suite.Equal("gte", fields["slippage"].Rule)
+	suite.Equal("slippage must be at least 0", fields["slippage"].Message)
+	suite.Equal("evm_address", fields["Wallets[1]"].Rule)
+	suite.Len(fields, 6)
+
+	fields = suite.fields(&tradeRequest{})
+	suite.Equal("amount is required", fields["amount"].Message)
+	suite.Equal("required", fields["from_address"].Rule)
+	suite.Len(fields, 2, "the optional rules skip the zero values")
+
+	fields = suite.fields(&tradeRequest{Amount: "1.5", FromAddress: address, Wallets: []string{address, address, address}})
+	suite.Equal("Wallets must be at most 2 items", fields["Wallets"].Message)
+
+	fields = suite.fields(&channelRequest{pageParams: pageParams{PageNum: -1, PageSize: 10000000}, Misesid: "mises1"})
+	suite.Equal("page_size must be at most 100", fields["page_size"].Message)
+	suite.Equal("gte", fields["page_num"].Rule)
+	suite.Equal("misesid", fields["misesid"].Rule)
+}
+
+func (suite *ValidationSuite) TestStructLevel() {
+	fields := suite.fields(&updateRequest{By: "profile"})
+	suite.Equal("profile is required when by is profile", fields["profile"].Message)
+
+	fields = suite.fields(&updateRequest{By: "profile", Profile: &profile{Email: "mises"}})
+	suite.Equal("profile.email must be a valid email", fields["profile.email"].Message)
+
+	fields = suite.fields(&updateRequest{By: "name"})
+	suite.Equal("by must be one of profile, avatar", fields["by"].Message)
+	suite.NoError(suite.v.Validate(&updateRequest{By: "avatar"}))
+}
+
+func (suite *ValidationSuite) TestErrorResponse() {
+	e := echo.New()
+	e.Validator = suite.v
+	e.GET("/api/v1/swap/trade", func(c echo.Context) error {
+		params := &tradeRequest{}
+		if err := c.Bind(params); err != nil {
+			return codes.ErrInvalidArgument.New("invalid query params")
+		}
+		if err := c.Validate(params); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	}, mw.ErrorResponseMiddleware)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/swap/trade?amount=1&from_address="+address+"&slippage=80", nil))
+	suite.Require().Equal(http.StatusBadRequest, rec.Code)
+	body := struct {
+		Code    int                `json:"code"`
+		Message string             `json:"message"`
+		Fields  []codes.FieldError `json:"fields"`
+	}{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	suite.Equal(codes.InvalidArgumentCode, body.Code)
+	suite.Equal("slippage must be at most 50", body.Message)
+	suite.Equal([]codes.FieldError{{Field: "slippage", Rule: "lte", Message: "slippage must be at most 50"}}, body.Fields)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/swap/trade?amount=1&from_address="+address, nil))
+	suite.Equal(http.StatusOK, rec.Code)
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, &ValidationSuite{})
+}
@@ -0,0 +1,198 @@
+//go:build tests
+// +build tests
+
+package wire
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mises-id/sns-apigateway/lib/wire"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type userResp struct {
+	UID      uint64 `json:"uid"`
+	Username string `json:"username"`
+}
+
+type statusResp struct {
+	ID        string      `json:"id"`
+	User      *userResp   `json:"user"`
+	Images    []string    `json:"images"`
+	Likes     uint64      `json:"likes_count"`
+	CreatedAt time.Time   `json:"created_at"`
+	Meta      interface{} `json:"meta"`
+	Secret    string      `json:"-"`
+}
+
+type WireSuite struct {
+	suite.Suite
+	status *statusResp
+}
+
+func (suite *WireSuite) SetupTest() {
+	suite.status = &statusResp{
+		ID:        "s1",
+		User:      &userResp{UID: 1001, Username: "alice"},
+		Images:    []string{"a.jpg", "b.jpg"},
+		Likes:     3,
+		CreatedAt: time.Unix(1600000000, 0),
+		Secret:    "hidden",
+	}
+}
+
+func (suite *WireSuite) TestMsgpack() {
+	body, err := wire.MarshalMsgpack(echo.Map{"code": 0, "data": []int{-1, 300}, "ok": true})
+	suite.NoError(err)
+	// fixmap(3) "code" 0 "data" [ -1, uint16 300 ] "ok" true
+	expected := []byte{0x83, 0xa4, 'c', 'o', 'd', 'e', 0x00, 0xa4, 'd', 'a', 't', 'a', 0x92, 0xff, 0xcd, 0x01, 0x2c, 0xa2, 'o', 'k', 0xc3}
+	suite.Equal(expected, body)
+}
+
+func (suite *WireSuite) TestMarshalProto() {
+	body, err := wire.MarshalProto(suite.status)
+	suite.NoError(err)
+
+	fields := map[protowire.Number][][]byte{}
+	for len(body) > 0 {
+		number, typ, n := protowire.ConsumeTag(body)
+		suite.Require().True(n > 0)
+		body = body[n:]
+		n = protowire.ConsumeFieldValue(number, typ, body)
+		suite.Require().True(n > 0)
+		fields[number] = append(fields[number], body[:n])
+		body = body[n:]
+	}
+	suite.Len(fields, 5)
+	suite.Equal("\x02s1", string(fields[1][0]))
+	suite.Len(fields[3], 2)
+	likes, _ := protowire.ConsumeVarint(fields[4][0])
+	suite.Equal(uint64(3), likes)
+	suite.NotContains(fields, protowire.Number(6))
+}
+
+func (suite *WireSuite) TestUnmarshalProto() {
+	suite.status.Meta = map[string]interface{}{"pinned": true}
+	body, err := wire.MarshalProto(suite.status)
+	suite.Require().NoError(err)
+	status := &statusResp{}
+	suite.Require().NoError(wire.UnmarshalProto(body, status))
+	suite.Equal(suite.status.ID, status.ID)
+	suite.Equal(suite.status.User, status.User)
+	suite.Equal(suite.status.Images, status.Images)
+	suite.Equal(suite.status.Likes, status.Likes)
+	suite.True(suite.status.CreatedAt.Equal(status.CreatedAt))
+	suite.Equal(suite.status.Meta, status.Meta)
+	suite.Empty(status.Secret)
+
+	type page struct {
+		IDs    []uint64         `json:"ids"`
+		Counts map[string]int32 `json:"counts"`
+		Next   *string          `json:"next"`
+		Users  []*userResp      `json:"users"`
+	}
+	next := "n1"
+	expected := page{IDs: []uint64{1, 300, 70000}, Counts: map[string]int32{"a": 1, "b": -2}, Next: &next, Users: []*userResp{{UID: 1}, {UID: 2}}}
+	body, err = wire.MarshalProto(expected)
+	suite.Require().NoError(err)
+	actual := page{}
+	suite.Require().NoError(wire.UnmarshalProto(body, &actual))
+	suite.Equal(expected, actual)
+
+	// the unknown fields are skipped, a truncated message fails
+	suite.NoError(wire.UnmarshalProto(protowire.AppendVarint(protowire.AppendTag(nil, 9, protowire.VarintType), 1), &actual))
+	suite.Error(wire.UnmarshalProto(body[:len(body)-1], &actual))
+	suite.Error(wire.UnmarshalProto(body, actual))
+}
+
+func (suite *WireSuite) TestWriteProto() {
+	builder := &strings.Builder{}
+	suite.NoError(wire.WriteProto(builder, reflect.TypeOf([]*statusResp{})))
+	proto := builder.String()
+	suite.Contains(proto, "message statusRespList {\n  repeated statusResp items = 1;\n}")
+	suite.Contains(proto, "  userResp user = 2;\n  repeated string images = 3;\n  uint64 likes_count = 4;\n")
+	suite.Contains(proto, "  google.protobuf.Timestamp created_at = 5;\n  google.protobuf.Value meta = 6;\n}")
+	suite.NotContains(proto, "Secret")
+}
+
+func (suite *WireSuite) TestProtoTag() {
+	// the content field was removed, likes keeps its number
+	type taggedResp struct {
+		ID     string   `json:"id" proto:"1"`
+		Likes  uint64   `json:"likes_count" proto:"3"`
+		Images []string `json:"images"`
+	}
+	builder := &strings.Builder{}
+	suite.NoError(wire.WriteProto(builder, reflect.TypeOf(taggedResp{})))
+	suite.Contains(builder.String(), "  string id = 1;\n  uint64 likes_count = 3;\n  repeated string images = 4;\n")
+
+	body, err := wire.MarshalProto(&taggedResp{ID: "s1", Likes: 3})
+	suite.Require().NoError(err)
+	actual := taggedResp{}
+	suite.Require().NoError(wire.UnmarshalProto(body, &actual))
+	suite.Equal(uint64(3), actual.Likes)
+	number, _, _ := protowire.ConsumeTag(body[protowire.SizeTag(1)+protowire.SizeBytes(len("s1")):])
+	suite.Equal(protowire.Number(3), number)
+
+	type duplicateResp struct {
+		ID    string `json:"id"`
+		Other string `json:"other" proto:"1"`
+	}
+	suite.Error(wire.WriteProto(&strings.Builder{}, reflect.TypeOf(duplicateResp{})))
+}
+
+func (suite *WireSuite) TestUnmarshalProtoJSON() {
+	actual := statusResp{}
+	suite.Require().NoError(wire.UnmarshalProtoJSON([]byte(`{"id": "s1", "user": {"uid": "1001"}, "likesCount": "3", "created_at": "2020-09-13T12:26:40Z", "unknown": 1}`), &actual))
+	suite.Equal("s1", actual.ID)
+	suite.Equal(uint64(1001), actual.User.UID)
+	suite.Equal(uint64(3), actual.Likes)
+	suite.Equal(int64(1600000000), actual.CreatedAt.Unix())
+
+	suite.Error(wire.UnmarshalProtoJSON([]byte(`{"likes_count": "3,\"id\":\"s2\""}`), &actual))
+	suite.Error(wire.UnmarshalProtoJSON([]byte(`{"likes_count": -1}`), &actual))
+	suite.Error(wire.UnmarshalProtoJSON([]byte(`[]`), &actual))
+}
+
+func (suite *WireSuite) TestWriteServiceProto() {
+	type statusRequest struct {
+		ID string `json:"id"`
+	}
+	builder := &strings.Builder{}
+	suite.NoError(wire.WriteServiceProto(builder, []wire.Service{{Name: "Statuses", Methods: []wire.ServiceMethod{
+		{Name: "GetStatus", Request: reflect.TypeOf(statusRequest{}), Response: reflect.TypeOf(statusResp{})},
+	}}}))
+	proto := builder.String()
+	suite.Contains(proto, "message statusRequest {\n  string id = 1;\n}")
+	suite.Contains(proto, "message userResp {")
+	suite.True(strings.HasSuffix(proto, "\nservice Statuses {\n  rpc GetStatus(statusRequest) returns (statusResp);\n}\n"), proto)
+}
+
+func (suite *WireSuite) TestNegotiate() {
+	e := echo.New()
+	for accept, expected := range map[string]string{
+		"":                                      echo.MIMEApplicationJSON,
+		"*/*":                                   echo.MIMEApplicationJSON,
+		"application/msgpack, application/json": wire.MIMEApplicationMsgpack,
+		"application/x-protobuf;q=0.9, text/html": wire.MIMEApplicationProtobuf,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		suite.NoError(wire.Render(c, http.StatusOK, echo.Map{"code": 0, "data": suite.status}))
+		suite.Equal(expected, strings.Split(rec.Header().Get(echo.HeaderContentType), ";")[0])
+		suite.Equal(echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+	}
+}
+
+func TestWireSuite(t *testing.T) {
+	suite.Run(t, &WireSuite{})
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/mises-id/sns-apigateway/config/route"
+	"github.com/mises-id/sns-apigateway/lib/binding"
+	"github.com/mises-id/sns-apigateway/lib/validation"
 
 	"github.com/mises-id/sns-apigateway/tests"
 	misesMock "github.com/mises-id/sns-apigateway/tests/mocks/lib/mises"
@@ -39,6 +41,8 @@ func (suite *RestBaseTestSuite) TearDownSuite() {
 
 func (suite *RestBaseTestSuite) SetupEchoHandler() {
 	e := echo.New()
+	e.Binder = binding.Binder{Binder: e.Binder}
+	e.Validator = validation.Default
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.DefaultCORSConfig))